@@ -0,0 +1,429 @@
+// Package api serves tasks as JSON over HTTP, for scripts and dashboards
+// that would otherwise parse the output of list. Tasks are represented as
+// in the JSON export (exchange.Record) and can be referred to by ID or UID,
+// as on the command line:
+//
+//	GET    /api/tasks                     list tasks, filtered by query parameters
+//	POST   /api/tasks                     create a task
+//	GET    /api/tasks/{ref}               get a task
+//	PATCH  /api/tasks/{ref}               change some of a task's fields
+//	DELETE /api/tasks/{ref}               delete a task
+//	POST   /api/tasks/{ref}/complete      and incomplete, skip and unskip
+//	GET    /api/openapi.json              the OpenAPI document describing all this
+//
+// Completing or skipping a recurring task creates its next occurrence, as
+// the complete and skip commands do. Behind auth.Authenticator, requests
+// only see the tasks of the authenticated user.
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/auth"
+	"github.com/johnmirolha/facienda/internal/exchange"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// maxBodySize bounds request bodies; a single task is a few hundred bytes.
+const maxBodySize = 1 << 20
+
+const dateLayout = "2006-01-02"
+
+//go:embed openapi.json
+var openAPI []byte
+
+// Handler is the API server. It is safe for concurrent use as long as its
+// storage is.
+type Handler struct {
+	store storage.Storage
+	mux   *http.ServeMux
+	// all is the whole of store, before it is restricted to the request's
+	// user. The next occurrence of a recurring task is created in it: it
+	// belongs to the task's owner, who needn't be the user completing it.
+	all storage.Storage
+}
+
+// NewHandler serves the tasks in store under prefix, such as "/api/".
+func NewHandler(store storage.Storage, prefix string) *Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	h := &Handler{store: store, mux: http.NewServeMux()}
+
+	h.mux.HandleFunc("GET "+prefix+"/tasks", h.handle((*Handler).list))
+	h.mux.HandleFunc("POST "+prefix+"/tasks", h.handle((*Handler).create))
+	h.mux.HandleFunc("GET "+prefix+"/tasks/{ref}", h.handle((*Handler).get))
+	h.mux.HandleFunc("PATCH "+prefix+"/tasks/{ref}", h.handle((*Handler).patch))
+	h.mux.HandleFunc("DELETE "+prefix+"/tasks/{ref}", h.handle((*Handler).delete))
+	h.mux.HandleFunc("POST "+prefix+"/tasks/{ref}/{action}", h.handle((*Handler).act))
+	h.mux.HandleFunc("GET "+prefix+"/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPI)
+	})
+	h.mux.HandleFunc(prefix+"/", h.handle(func(_ *Handler, w http.ResponseWriter, r *http.Request) error {
+		return httpError{http.StatusNotFound, "no such endpoint: " + r.Method + " " + r.URL.Path}
+	}))
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handle adapts a handler that returns an error, writing the error as JSON.
+// The handler runs on the tasks the request's user has access to.
+func (h *Handler) handle(fn func(h *Handler, w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scoped := &Handler{store: auth.Scope(r.Context(), h.store), all: h.store}
+		if err := fn(scoped, w, r); err != nil {
+			writeError(w, err)
+		}
+	}
+}
+
+// TaskInput is the body of a create or patch request. Fields left out of a
+// patch keep their value; the completed and skipped states are changed
+// through the actions instead.
+type TaskInput struct {
+	Title   *string `json:"title"`
+	Details *string `json:"details"`
+	// Date is YYYY-MM-DD. It defaults to today, or for a recurring task to
+	// its first occurrence from today.
+	Date *string `json:"date"`
+	// Time is HH:MM, or empty for a whole-day task.
+	Time *string `json:"time"`
+	Zone *string `json:"zone"`
+	// Recurrence takes a stored pattern ("weekly:monday") or a phrase as
+	// the add command's --recur flag does ("every monday"), or empty for
+	// a one-off task.
+	Recurrence *string `json:"recurrence"`
+	UID        *string `json:"uid"`
+	// Owner and Assignee are user names. Only the owner of a task can give
+	// it to someone else.
+	Owner    *string `json:"owner"`
+	Assignee *string `json:"assignee"`
+}
+
+// apply sets the fields given in the input on record.
+func (in TaskInput) apply(record *exchange.Record) {
+	for _, field := range []struct {
+		value *string
+		dest  *string
+	}{
+		{in.Title, &record.Title},
+		{in.Details, &record.Details},
+		{in.Date, &record.Date},
+		{in.Time, &record.Time},
+		{in.Zone, &record.Zone},
+		{in.Recurrence, &record.Recurrence},
+		{in.UID, &record.UID},
+		{in.Owner, &record.Owner},
+		{in.Assignee, &record.Assignee},
+	} {
+		if field.value != nil {
+			*field.dest = *field.value
+		}
+	}
+}
+
+// ActionResult is the response to an action: the task and, if completing
+// or skipping it created one, its next occurrence.
+type ActionResult struct {
+	Task exchange.Record  `json:"task"`
+	Next *exchange.Record `json:"next,omitempty"`
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) error {
+	match, err := parseQuery(r.URL.Query(), time.Now())
+	if err != nil {
+		return err
+	}
+	tasks, err := h.store.All()
+	if err != nil {
+		return err
+	}
+
+	records := []exchange.Record{}
+	for _, task := range tasks {
+		if match(task) {
+			records = append(records, exchange.NewRecord(task))
+		}
+	}
+	return writeJSON(w, http.StatusOK, records)
+}
+
+// parseQuery returns the filter the query parameters of a listing ask for:
+//
+//	when=past|today|future  tasks scheduled before, on or after today
+//	from=, to=              tasks scheduled between two dates, inclusive
+//	status=                 open, completed, skipped or all; all but skipped
+//	                        tasks by default, as list shows
+//	recurring=true|false    only recurring or one-off tasks
+//	q=                      tasks whose title or details contain the text
+func parseQuery(query map[string][]string, now time.Time) (func(*todo.Task) bool, error) {
+	get := func(name string) string {
+		if values := query[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	var checks []func(task *todo.Task, day string) bool
+
+	today := now.Format(dateLayout)
+	switch when := get("when"); when {
+	case "", "all":
+	case "past":
+		checks = append(checks, func(_ *todo.Task, day string) bool { return day < today })
+	case "today":
+		checks = append(checks, func(_ *todo.Task, day string) bool { return day == today })
+	case "future":
+		checks = append(checks, func(_ *todo.Task, day string) bool { return day > today })
+	default:
+		return nil, badRequest("invalid when %q (use past, today, future or all)", when)
+	}
+
+	for _, bound := range []string{"from", "to"} {
+		value := get(bound)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, value); err != nil {
+			return nil, badRequest("invalid %s date %q (use YYYY-MM-DD)", bound, value)
+		}
+		if bound == "from" {
+			checks = append(checks, func(_ *todo.Task, day string) bool { return day >= value })
+		} else {
+			checks = append(checks, func(_ *todo.Task, day string) bool { return day <= value })
+		}
+	}
+
+	switch status := get("status"); status {
+	case "":
+		checks = append(checks, func(task *todo.Task, _ string) bool { return !task.Skipped })
+	case "open":
+		checks = append(checks, func(task *todo.Task, _ string) bool { return !task.Skipped && !task.Completed })
+	case "completed":
+		checks = append(checks, func(task *todo.Task, _ string) bool { return task.Completed })
+	case "skipped":
+		checks = append(checks, func(task *todo.Task, _ string) bool { return task.Skipped })
+	case "all":
+	default:
+		return nil, badRequest("invalid status %q (use open, completed, skipped or all)", status)
+	}
+
+	if value := get("recurring"); value != "" {
+		recurring, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, badRequest("invalid recurring %q (use true or false)", value)
+		}
+		checks = append(checks, func(task *todo.Task, _ string) bool { return task.IsRecurring() == recurring })
+	}
+
+	if text := strings.ToLower(get("q")); text != "" {
+		checks = append(checks, func(task *todo.Task, _ string) bool {
+			return strings.Contains(strings.ToLower(task.Title), text) || strings.Contains(strings.ToLower(task.Details), text)
+		})
+	}
+
+	return func(task *todo.Task) bool {
+		day, _, _ := storage.SplitSchedule(task.Date)
+		for _, check := range checks {
+			if !check(task, day) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) error {
+	var in TaskInput
+	if err := readJSON(r, &in); err != nil {
+		return err
+	}
+	if in.Title == nil {
+		return badRequest("title is required")
+	}
+
+	record := exchange.Record{Date: time.Now().Format(dateLayout)}
+	in.apply(&record)
+	task, err := record.Task()
+	if err != nil {
+		return badRequest("%v", err)
+	}
+	if user, ok := auth.UserFrom(r.Context()); ok && task.Owner == "" {
+		task.Owner = user.Name
+	}
+	if in.Date == nil && task.IsRecurring() {
+		now := time.Now()
+		if task.Date, err = task.RecurrencePattern.NextOccurrence(now.AddDate(0, 0, -1)); err != nil {
+			return badRequest("%v", err)
+		}
+	}
+
+	if err := h.store.Create(task); err != nil {
+		return err
+	}
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+strconv.FormatInt(task.ID, 10))
+	return writeJSON(w, http.StatusCreated, exchange.NewRecord(task))
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) error {
+	task, err := h.find(r)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, exchange.NewRecord(task))
+}
+
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request) error {
+	task, err := h.find(r)
+	if err != nil {
+		return err
+	}
+	var in TaskInput
+	if err := readJSON(r, &in); err != nil {
+		return err
+	}
+
+	record := exchange.NewRecord(task)
+	in.apply(&record)
+	changed, err := record.Task()
+	if err != nil {
+		return badRequest("%v", err)
+	}
+	if err := task.Update(changed.Title, changed.Details); err != nil {
+		return err
+	}
+	task.Date = changed.Date
+	task.RecurrencePattern = changed.RecurrencePattern
+	task.UID = changed.UID
+	task.Owner = changed.Owner
+	task.Assignee = changed.Assignee
+
+	if err := h.store.Update(task); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, exchange.NewRecord(task))
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) error {
+	task, err := h.find(r)
+	if err != nil {
+		return err
+	}
+	if err := h.store.Delete(task.ID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// act runs the complete, incomplete, skip or unskip action on a task.
+func (h *Handler) act(w http.ResponseWriter, r *http.Request) error {
+	task, err := h.find(r)
+	if err != nil {
+		return err
+	}
+
+	isRecurring := task.IsRecurring()
+	createsNext := false
+	switch action := r.PathValue("action"); action {
+	case "complete":
+		task.Complete()
+		createsNext = isRecurring
+	case "incomplete":
+		task.Incomplete()
+	case "skip":
+		task.Skip()
+		createsNext = isRecurring
+	case "unskip":
+		task.Unskip()
+	default:
+		return httpError{http.StatusNotFound, fmt.Sprintf("unknown action %q (use complete, incomplete, skip or unskip)", action)}
+	}
+	if err := h.store.Update(task); err != nil {
+		return err
+	}
+
+	result := ActionResult{Task: exchange.NewRecord(task)}
+	if createsNext {
+		next, err := task.GenerateNextInstance()
+		if err != nil {
+			return fmt.Errorf("failed to generate next instance: %w", err)
+		}
+		if next != nil {
+			if err := h.all.Create(next); err != nil {
+				return fmt.Errorf("failed to create next instance: %w", err)
+			}
+			record := exchange.NewRecord(next)
+			result.Next = &record
+		}
+	}
+	return writeJSON(w, http.StatusOK, result)
+}
+
+// find returns the task the request's {ref} refers to.
+func (h *Handler) find(r *http.Request) (*todo.Task, error) {
+	return storage.FindTask(h.store, r.PathValue("ref"))
+}
+
+func readJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return badRequest("missing request body")
+		}
+		return badRequest("invalid request body: %v", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// httpError is an error with the status code to respond with.
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e httpError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...any) error {
+	return httpError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+// writeError responds with {"error": "..."} and the status that fits err.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var herr httpError
+	switch {
+	case errors.As(err, &herr):
+		status = herr.status
+	case errors.Is(err, todo.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, todo.ErrEmptyTitle), errors.Is(err, todo.ErrAmbiguousUID):
+		status = http.StatusBadRequest
+	case errors.Is(err, todo.ErrDuplicateUID), errors.Is(err, todo.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, todo.ErrForbidden):
+		status = http.StatusForbidden
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,360 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/auth"
+	"github.com/johnmirolha/facienda/internal/exchange"
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+func newServer(t *testing.T) (*httptest.Server, *storage.MemoryStorage) {
+	t.Helper()
+
+	store := storage.NewMemoryStorage()
+	server := httptest.NewServer(NewHandler(store, "/api/"))
+	t.Cleanup(server.Close)
+	return server, store
+}
+
+// call sends a request and decodes the JSON response into out, if given.
+func call(t *testing.T, server *httptest.Server, method, path, body string, out any) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s: invalid JSON %q: %v", method, path, data, err)
+		}
+	}
+	return resp
+}
+
+func createTask(t *testing.T, store storage.Storage, task *todo.Task) *todo.Task {
+	t.Helper()
+
+	if err := store.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	return task
+}
+
+func TestCRUD(t *testing.T) {
+	server, store := newServer(t)
+
+	var created exchange.Record
+	resp := call(t, server, http.MethodPost, "/api/tasks",
+		`{"title": "Dentist", "details": "Bring the card", "date": "2025-11-21", "time": "14:30"}`, &created)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	if created.ID == 0 || created.UID == "" || created.Date != "2025-11-21" || created.Time != "14:30" {
+		t.Errorf("unexpected task %+v", created)
+	}
+	if want := "/api/tasks/" + strconv.FormatInt(created.ID, 10); resp.Header.Get("Location") != want {
+		t.Errorf("expected Location %s, got %s", want, resp.Header.Get("Location"))
+	}
+
+	// Tasks are found by ID or by UID.
+	var got exchange.Record
+	for _, ref := range []string{strconv.FormatInt(created.ID, 10), created.UID, strings.ToLower(created.UID[:20])} {
+		if resp := call(t, server, http.MethodGet, "/api/tasks/"+ref, "", &got); resp.StatusCode != http.StatusOK || got.ID != created.ID {
+			t.Errorf("GET %s: got %d %+v", ref, resp.StatusCode, got)
+		}
+	}
+
+	var patched exchange.Record
+	resp = call(t, server, http.MethodPatch, "/api/tasks/"+created.UID, `{"title": "Dentist appointment", "time": ""}`, &patched)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if patched.Title != "Dentist appointment" || patched.Details != "Bring the card" || patched.Date != "2025-11-21" || patched.Time != "" {
+		t.Errorf("expected only the title and time to change, got %+v", patched)
+	}
+	stored, _ := store.GetByID(created.ID)
+	if stored.Title != "Dentist appointment" || stored.HasTime() {
+		t.Errorf("patch not stored: %+v", stored)
+	}
+
+	if resp := call(t, server, http.MethodDelete, "/api/tasks/"+created.UID, "", nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	var apiErr struct{ Error string }
+	if resp := call(t, server, http.MethodGet, "/api/tasks/"+created.UID, "", &apiErr); resp.StatusCode != http.StatusNotFound || apiErr.Error == "" {
+		t.Errorf("expected a 404 error, got %d %+v", resp.StatusCode, apiErr)
+	}
+}
+
+func TestCreate_Rejects(t *testing.T) {
+	server, store := newServer(t)
+	existing := createTask(t, store, &todo.Task{Title: "Existing", Date: time.Now(), UID: "event-1"})
+
+	for body, status := range map[string]int{
+		``:                                       http.StatusBadRequest,
+		`{"details": "No title"}`:                http.StatusBadRequest,
+		`{"title": ""}`:                          http.StatusBadRequest,
+		`{"title": "Typo", "dat": "2025-11-21"}`: http.StatusBadRequest,
+		`{"title": "Bad date", "date": "21.11.2025"}`:      http.StatusBadRequest,
+		`{"title": "Bad recurrence", "recurrence": "x"}`:   http.StatusBadRequest,
+		`{"title": "Copy", "uid": "` + existing.UID + `"}`: http.StatusConflict,
+	} {
+		var apiErr struct{ Error string }
+		if resp := call(t, server, http.MethodPost, "/api/tasks", body, &apiErr); resp.StatusCode != status || apiErr.Error == "" {
+			t.Errorf("%s: expected %d with an error, got %d %+v", body, status, resp.StatusCode, apiErr)
+		}
+	}
+}
+
+func TestCreate_Recurring(t *testing.T) {
+	server, _ := newServer(t)
+
+	var created exchange.Record
+	call(t, server, http.MethodPost, "/api/tasks", `{"title": "Weekly report", "recurrence": "every monday"}`, &created)
+	if created.Recurrence != "weekly:monday" {
+		t.Errorf("expected the stored pattern, got %q", created.Recurrence)
+	}
+	date, err := time.ParseInLocation(dateLayout, created.Date, time.Local)
+	if err != nil || date.Weekday() != time.Monday || date.Before(storage.StartOfDay(time.Now())) {
+		t.Errorf("expected the next Monday from today, got %s", created.Date)
+	}
+}
+
+func TestActions(t *testing.T) {
+	server, store := newServer(t)
+	task := createTask(t, store, &todo.Task{
+		Title:             "Weekly report",
+		Date:              time.Date(2025, 11, 24, 0, 0, 0, 0, time.Local),
+		RecurrencePattern: recurrence.Pattern("weekly:monday"),
+	})
+	ref := strconv.FormatInt(task.ID, 10)
+
+	var result ActionResult
+	if resp := call(t, server, http.MethodPost, "/api/tasks/"+ref+"/complete", "", &result); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if !result.Task.Completed || result.Next == nil || result.Next.Date != "2025-12-01" || result.Next.Completed {
+		t.Fatalf("expected the completed task and its next occurrence, got %+v", result)
+	}
+	if tasks, _ := store.All(); len(tasks) != 2 {
+		t.Errorf("expected 2 tasks, got %d", len(tasks))
+	}
+
+	result = ActionResult{}
+	call(t, server, http.MethodPost, "/api/tasks/"+ref+"/incomplete", "", &result)
+	if result.Task.Completed || result.Next != nil {
+		t.Errorf("expected the task to be incomplete with no next occurrence, got %+v", result)
+	}
+
+	next := strconv.FormatInt(result.Task.ID+1, 10)
+	result = ActionResult{}
+	call(t, server, http.MethodPost, "/api/tasks/"+next+"/skip", "", &result)
+	if !result.Task.Skipped || result.Next == nil || result.Next.Date != "2025-12-08" {
+		t.Errorf("expected skipping to create the next occurrence, got %+v", result)
+	}
+	result = ActionResult{}
+	call(t, server, http.MethodPost, "/api/tasks/"+next+"/unskip", "", &result)
+	if result.Task.Skipped {
+		t.Errorf("expected the task to be unskipped, got %+v", result)
+	}
+
+	if resp := call(t, server, http.MethodPost, "/api/tasks/"+ref+"/archive", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown action, got %d", resp.StatusCode)
+	}
+}
+
+func TestList(t *testing.T) {
+	server, store := newServer(t)
+	today := storage.StartOfDay(time.Now())
+
+	createTask(t, store, &todo.Task{Title: "Old report", Date: today.AddDate(0, 0, -3), Completed: true})
+	createTask(t, store, &todo.Task{Title: "Skipped standup", Date: today, Skipped: true})
+	createTask(t, store, &todo.Task{Title: "Standup", Details: "Daily sync", Date: today, RecurrencePattern: recurrence.Pattern("daily")})
+	createTask(t, store, &todo.Task{Title: "Dentist", Date: today.AddDate(0, 0, 2)})
+
+	for query, want := range map[string]string{
+		"":                      "Old report,Standup,Dentist",
+		"?status=all":           "Old report,Skipped standup,Standup,Dentist",
+		"?status=open":          "Standup,Dentist",
+		"?status=completed":     "Old report",
+		"?status=skipped":       "Skipped standup",
+		"?when=past":            "Old report",
+		"?when=today":           "Standup",
+		"?when=future":          "Dentist",
+		"?recurring=true":       "Standup",
+		"?q=SYNC":               "Standup",
+		"?q=standup&status=all": "Skipped standup,Standup",
+		"?from=" + today.Format(dateLayout) + "&to=" + today.AddDate(0, 0, 1).Format(dateLayout): "Standup",
+	} {
+		var records []exchange.Record
+		if resp := call(t, server, http.MethodGet, "/api/tasks"+query, "", &records); resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", query, resp.StatusCode)
+			continue
+		}
+		titles := make([]string, len(records))
+		for i, record := range records {
+			titles[i] = record.Title
+		}
+		if got := strings.Join(titles, ","); got != want {
+			t.Errorf("%s: got %q, want %q", query, got, want)
+		}
+	}
+
+	for _, query := range []string{"?when=yesterday", "?status=done", "?recurring=maybe", "?from=tomorrow"} {
+		if resp := call(t, server, http.MethodGet, "/api/tasks"+query, "", nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.StatusCode)
+		}
+	}
+}
+
+// newUsersServer serves the API with users, who are named in a header in
+// place of auth.Authenticator; alice is an admin. It returns a function
+// that sends a request as a user and decodes the response into out, if
+// given, returning the status.
+func newUsersServer(t *testing.T) (*storage.MemoryStorage, func(user, method, path, body string, out any) int) {
+	t.Helper()
+
+	store := storage.NewMemoryStorage()
+	handler := NewHandler(store, "/api/")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := auth.User{Name: r.Header.Get("X-User"), Admin: r.Header.Get("X-User") == "alice"}
+		handler.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+	}))
+	t.Cleanup(server.Close)
+
+	return store, func(user, method, path, body string, out any) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if out != nil {
+			json.NewDecoder(resp.Body).Decode(out)
+		}
+		return resp.StatusCode
+	}
+}
+
+func TestUsers(t *testing.T) {
+	store, as := newUsersServer(t)
+
+	var bobs, carols exchange.Record
+	if status := as("bob", http.MethodPost, "/api/tasks", `{"title": "Bob's task"}`, &bobs); status != http.StatusCreated || bobs.Owner != "bob" {
+		t.Fatalf("expected bob to own his new task, got %d %+v", status, bobs)
+	}
+	as("carol", http.MethodPost, "/api/tasks", `{"title": "Carol's task"}`, &carols)
+	createTask(t, store, &todo.Task{Title: "Nobody's task", Date: time.Now()})
+
+	count := func(user string) int {
+		var records []exchange.Record
+		as(user, http.MethodGet, "/api/tasks", "", &records)
+		return len(records)
+	}
+	if n := count("bob"); n != 1 {
+		t.Errorf("expected bob to see 1 task, got %d", n)
+	}
+	if n := count("alice"); n != 3 {
+		t.Errorf("expected the admin to see 3 tasks, got %d", n)
+	}
+
+	carolsPath := "/api/tasks/" + strconv.FormatInt(carols.ID, 10)
+	if status := as("bob", http.MethodGet, carolsPath, "", nil); status != http.StatusNotFound {
+		t.Errorf("expected bob not to find carol's task, got %d", status)
+	}
+	if status := as("bob", http.MethodPost, "/api/tasks", `{"title": "Forged", "owner": "carol"}`, nil); status != http.StatusForbidden {
+		t.Errorf("expected 403 creating a task for someone else, got %d", status)
+	}
+
+	// Carol assigns her task to bob, who can then complete it but not
+	// delete it or give it away.
+	if status := as("carol", http.MethodPatch, carolsPath, `{"assignee": "bob"}`, nil); status != http.StatusOK {
+		t.Fatalf("expected 200 assigning the task, got %d", status)
+	}
+	if status := as("bob", http.MethodPost, carolsPath+"/complete", "", nil); status != http.StatusOK {
+		t.Errorf("expected bob to complete an assigned task, got %d", status)
+	}
+	if status := as("bob", http.MethodPatch, carolsPath, `{"owner": "bob"}`, nil); status != http.StatusForbidden {
+		t.Errorf("expected 403 taking over a task, got %d", status)
+	}
+	if status := as("bob", http.MethodDelete, carolsPath, "", nil); status != http.StatusForbidden {
+		t.Errorf("expected 403 deleting someone else's task, got %d", status)
+	}
+	if status := as("carol", http.MethodDelete, carolsPath, "", nil); status != http.StatusNoContent {
+		t.Errorf("expected carol to delete her task, got %d", status)
+	}
+}
+
+func TestUsers_AssigneeCompletesRecurring(t *testing.T) {
+	store, as := newUsersServer(t)
+
+	var task exchange.Record
+	as("carol", http.MethodPost, "/api/tasks", `{"title": "Water plants", "recurrence": "every monday", "assignee": "bob"}`, &task)
+	path := "/api/tasks/" + strconv.FormatInt(task.ID, 10)
+
+	// The next occurrence is carol's, like the task bob completed.
+	var result ActionResult
+	if status := as("bob", http.MethodPost, path+"/complete", "", &result); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if result.Next == nil || result.Next.Owner != "carol" || result.Next.Assignee != "bob" {
+		t.Fatalf("expected the next occurrence owned by carol and assigned to bob, got %+v", result.Next)
+	}
+	all, _ := store.All()
+	if len(all) != 2 {
+		t.Errorf("expected 2 tasks, got %d", len(all))
+	}
+
+	if status := as("bob", http.MethodPost, "/api/tasks/"+strconv.FormatInt(result.Next.ID, 10)+"/skip", "", &result); status != http.StatusOK || result.Next == nil {
+		t.Errorf("expected skipping to create the next occurrence too, got %d %+v", status, result.Next)
+	}
+}
+
+// TestOpenAPI checks that the document is valid JSON and that every
+// operation it describes is served.
+func TestOpenAPI(t *testing.T) {
+	server, store := newServer(t)
+
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage
+	}
+	if resp := call(t, server, http.MethodGet, "/api/openapi.json", "", &doc); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if len(doc.Paths) == 0 {
+		t.Fatal("expected paths in the document")
+	}
+
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			task := createTask(t, store, &todo.Task{Title: "Task", Date: time.Now()})
+			url := "/api" + strings.ReplaceAll(path, "{ref}", strconv.FormatInt(task.ID, 10))
+			resp := call(t, server, strings.ToUpper(method), url, `{"title": "Task"}`, nil)
+			if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+				t.Errorf("%s %s is documented but not served", method, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Package auth keeps the users of the server mode and their API tokens,
+// and authenticates requests to the server with them.
+//
+// Users and tokens live in a JSON file next to the task database. Tokens
+// are stored as SHA-256 hashes: the token itself is only shown when it is
+// created. Admins see every task; other users see the tasks they own or
+// are assigned to (see storage.Scope).
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownUser  = errors.New("unknown user")
+	ErrUnknownToken = errors.New("unknown token")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// tokenPrefix starts every token, so that tokens are recognizable in
+// configuration files and by secret scanners.
+const tokenPrefix = "fct_"
+
+// userNameRegex restricts names to ones that are easy to type and can't be
+// mistaken for anything else in a task file or a URL.
+var userNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// User is a user of the server mode.
+type User struct {
+	Name string `json:"name"`
+	// Admin users see and change every task, including those nobody owns.
+	Admin     bool      `json:"admin,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Token is an API token of a user.
+type Token struct {
+	// ID identifies the token in listings and when revoking it. It is the
+	// start of Hash, so it gives nothing away about the token.
+	ID   string `json:"id"`
+	User string `json:"user"`
+	// Name says what the token is for, such as "phone".
+	Name      string    `json:"name,omitempty"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Accounts is the contents of the users file.
+type Accounts struct {
+	Users  []User  `json:"users,omitempty"`
+	Tokens []Token `json:"tokens,omitempty"`
+}
+
+// DefaultPath returns the users file of the task database at dbPath: the
+// database path followed by -users.json.
+func DefaultPath(dbPath string) string {
+	return filepath.Clean(dbPath) + "-users.json"
+}
+
+// Load reads the users file at path. A missing file has no users.
+func Load(path string) (*Accounts, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Accounts{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users: %w", err)
+	}
+
+	var accounts Accounts
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to parse users file %s: %w", path, err)
+	}
+	return &accounts, nil
+}
+
+// Save writes the users file to path, replacing it atomically. Only its
+// owner can read it.
+func (a *Accounts) Save(path string) error {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	// CreateTemp creates the file readable by its owner only.
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to save users: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save users: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save users: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save users: %w", err)
+	}
+	return nil
+}
+
+// Get returns the user called name.
+func (a *Accounts) Get(name string) (User, error) {
+	for _, user := range a.Users {
+		if user.Name == name {
+			return user, nil
+		}
+	}
+	return User{}, fmt.Errorf("%w %q", ErrUnknownUser, name)
+}
+
+// AddUser adds a user.
+func (a *Accounts) AddUser(name string, admin bool) error {
+	if !userNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid user name %q (use lowercase letters, digits, '.', '-' and '_')", name)
+	}
+	if _, err := a.Get(name); err == nil {
+		return fmt.Errorf("user %q already exists", name)
+	}
+	a.Users = append(a.Users, User{Name: name, Admin: admin, CreatedAt: time.Now().UTC()})
+	sort.Slice(a.Users, func(i, j int) bool { return a.Users[i].Name < a.Users[j].Name })
+	return nil
+}
+
+// RemoveUser removes a user and revokes their tokens. Their tasks are kept.
+func (a *Accounts) RemoveUser(name string) error {
+	if _, err := a.Get(name); err != nil {
+		return err
+	}
+	users := a.Users[:0]
+	for _, user := range a.Users {
+		if user.Name != name {
+			users = append(users, user)
+		}
+	}
+	a.Users = users
+
+	tokens := a.Tokens[:0]
+	for _, token := range a.Tokens {
+		if token.User != name {
+			tokens = append(tokens, token)
+		}
+	}
+	a.Tokens = tokens
+	return nil
+}
+
+// CreateToken creates a token for user and returns it. The token can't be
+// read back later, only revoked.
+func (a *Accounts) CreateToken(user, name string) (string, Token, error) {
+	if _, err := a.Get(user); err != nil {
+		return "", Token{}, err
+	}
+
+	var secret [20]byte
+	if _, err := rand.Read(secret[:]); err != nil {
+		return "", Token{}, fmt.Errorf("failed to create token: %w", err)
+	}
+	value := tokenPrefix + hex.EncodeToString(secret[:])
+	hash := hashToken(value)
+	token := Token{ID: hash[:8], User: user, Name: name, Hash: hash, CreatedAt: time.Now().UTC()}
+	a.Tokens = append(a.Tokens, token)
+	return value, token, nil
+}
+
+// RevokeToken revokes the token with the given ID.
+func (a *Accounts) RevokeToken(id string) error {
+	for i, token := range a.Tokens {
+		if token.ID == id {
+			a.Tokens = append(a.Tokens[:i], a.Tokens[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q", ErrUnknownToken, id)
+}
+
+// Authenticate returns the user a token belongs to.
+func (a *Accounts) Authenticate(value string) (User, error) {
+	if !strings.HasPrefix(value, tokenPrefix) {
+		return User{}, ErrInvalidToken
+	}
+	hash := hashToken(value)
+	for _, token := range a.Tokens {
+		if token.Hash == hash {
+			return a.Get(token.User)
+		}
+	}
+	return User{}, ErrInvalidToken
+}
+
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAccounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db-users.json")
+	accounts, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load of a missing file failed: %v", err)
+	}
+
+	if err := accounts.AddUser("alice", true); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	if err := accounts.AddUser("bob", false); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	if err := accounts.AddUser("bob", false); err == nil {
+		t.Error("expected an error adding a user twice")
+	}
+	if err := accounts.AddUser("Bob Smith", false); err == nil {
+		t.Error("expected an error for an invalid user name")
+	}
+
+	value, token, err := accounts.CreateToken("bob", "phone")
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	if _, _, err := accounts.CreateToken("carol", ""); !errors.Is(err, ErrUnknownUser) {
+		t.Errorf("expected ErrUnknownUser, got %v", err)
+	}
+	if err := accounts.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("expected the users file to be private, got %v", mode)
+	}
+
+	accounts, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	user, err := accounts.Authenticate(value)
+	if err != nil || user.Name != "bob" || user.Admin {
+		t.Errorf("expected bob, got %+v, %v", user, err)
+	}
+	if _, err := accounts.Authenticate(value + "0"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+	if _, err := accounts.Authenticate(token.Hash); !errors.Is(err, ErrInvalidToken) {
+		t.Error("the stored hash must not work as a token")
+	}
+
+	if err := accounts.RevokeToken(token.ID); err != nil {
+		t.Fatalf("RevokeToken failed: %v", err)
+	}
+	if _, err := accounts.Authenticate(value); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected a revoked token to fail, got %v", err)
+	}
+	if err := accounts.RevokeToken(token.ID); !errors.Is(err, ErrUnknownToken) {
+		t.Errorf("expected ErrUnknownToken, got %v", err)
+	}
+
+	value, _, _ = accounts.CreateToken("bob", "")
+	if err := accounts.RemoveUser("bob"); err != nil {
+		t.Fatalf("RemoveUser failed: %v", err)
+	}
+	if _, err := accounts.Authenticate(value); err == nil {
+		t.Error("expected the tokens of a removed user to fail")
+	}
+	if len(accounts.Users) != 1 || len(accounts.Tokens) != 0 {
+		t.Errorf("expected alice and no tokens, got %+v", accounts)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	accounts := &Accounts{}
+	accounts.AddUser("bob", false)
+	value, _, _ := accounts.CreateToken("bob", "")
+	if err := accounts.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator, err := NewAuthenticator(path)
+	if err != nil {
+		t.Fatalf("NewAuthenticator failed: %v", err)
+	}
+	handler := authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := UserFrom(r.Context())
+		w.Write([]byte(user.Name))
+	}))
+
+	request := func(setup func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/tasks", nil)
+		setup(req)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name   string
+		setup  func(*http.Request)
+		status int
+	}{
+		{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+value) }, http.StatusOK},
+		{"basic", func(r *http.Request) { r.SetBasicAuth("bob", value) }, http.StatusOK},
+		{"basic with another user", func(r *http.Request) { r.SetBasicAuth("alice", value) }, http.StatusUnauthorized},
+		{"wrong token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer fct_nope") }, http.StatusUnauthorized},
+		{"no token", func(r *http.Request) {}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := request(tt.setup)
+			if rec.Code != tt.status {
+				t.Fatalf("expected %d, got %d", tt.status, rec.Code)
+			}
+			if tt.status == http.StatusOK && rec.Body.String() != "bob" {
+				t.Errorf("expected the request to be bob's, got %q", rec.Body.String())
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected a WWW-Authenticate header")
+			}
+		})
+	}
+
+	// Revoking the token takes effect without restarting the server.
+	accounts.Tokens = nil
+	if err := accounts.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	os.Chtimes(path, later, later)
+	if rec := request(tests[0].setup); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a revoked token to be refused, got %d", rec.Code)
+	}
+}
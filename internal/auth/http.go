@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+)
+
+// Authenticator checks the tokens of requests against a users file. It
+// rereads the file when it changes, so that users and tokens added while
+// the server runs work right away and revoked tokens stop working.
+type Authenticator struct {
+	path string
+
+	mu       sync.Mutex
+	accounts *Accounts
+	modTime  time.Time
+	size     int64
+}
+
+// NewAuthenticator authenticates requests with the users file at path.
+func NewAuthenticator(path string) (*Authenticator, error) {
+	a := &Authenticator{path: path}
+	if _, err := a.load(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// load returns the accounts, rereading the file if it changed.
+func (a *Authenticator) load() (*Accounts, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	info, err := os.Stat(a.path)
+	if err == nil && a.accounts != nil && info.ModTime().Equal(a.modTime) && info.Size() == a.size {
+		return a.accounts, nil
+	}
+	accounts, err := Load(a.path)
+	if err != nil {
+		return nil, err
+	}
+	a.accounts = accounts
+	if info != nil {
+		a.modTime, a.size = info.ModTime(), info.Size()
+	}
+	return accounts, nil
+}
+
+// Middleware lets requests through to next that carry a valid token,
+// either as a bearer token or, for CalDAV clients, as the password of
+// basic authentication with the token's user as the user name. The user
+// is available to next through UserFrom.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := a.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="facienda", charset="UTF-8"`)
+			w.Header().Add("WWW-Authenticate", `Bearer realm="facienda"`)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (User, error) {
+	accounts, err := a.load()
+	if err != nil {
+		return User{}, err
+	}
+
+	if name, password, ok := r.BasicAuth(); ok {
+		user, err := accounts.Authenticate(password)
+		if err != nil {
+			return User{}, err
+		}
+		if user.Name != name {
+			return User{}, ErrInvalidToken
+		}
+		return user, nil
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return User{}, ErrInvalidToken
+	}
+	return accounts.Authenticate(strings.TrimSpace(token))
+}
+
+type userKey struct{}
+
+// WithUser returns a context carrying the authenticated user.
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFrom returns the authenticated user of a request's context. There is
+// none when the server runs without users.
+func UserFrom(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userKey{}).(User)
+	return user, ok
+}
+
+// Scope returns the part of store the user of ctx has access to: all of it
+// for admins and when the server runs without users, and the user's tasks
+// for everyone else.
+func Scope(ctx context.Context, store storage.Storage) storage.Storage {
+	user, ok := UserFrom(ctx)
+	if !ok || user.Admin {
+		return store
+	}
+	return storage.Scope(store, user.Name)
+}
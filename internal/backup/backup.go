@@ -0,0 +1,194 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of snapshot. Regular snapshots are rotated by Prune; safety copies
+// taken before a restore or migration are kept until removed by hand.
+const (
+	KindRegular    = ""
+	KindPreRestore = "pre-restore"
+	KindPreMigrate = "pre-migrate"
+)
+
+const timestampFormat = "20060102T150405Z"
+
+// EncryptedSuffix is appended to the names of encrypted snapshots.
+const EncryptedSuffix = ".enc"
+
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot is a backup file in a backup directory.
+type Snapshot struct {
+	Path      string
+	Kind      string
+	Time      time.Time
+	Encrypted bool
+}
+
+// Name returns the snapshot's file name.
+func (s Snapshot) Name() string {
+	return filepath.Base(s.Path)
+}
+
+// Retention says how many regular snapshots Prune keeps: the newest snapshot
+// of each of the last Daily days and of each of the last Weekly ISO weeks
+// that have snapshots. The newest snapshot overall is always kept.
+type Retention struct {
+	Daily  int
+	Weekly int
+}
+
+// DefaultDir returns the backup directory used for the database at dbPath
+// when none is configured: a .facienda-backups directory next to it.
+func DefaultDir(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), ".facienda-backups")
+}
+
+// stem returns the part of the database file name that snapshot names are
+// built from, e.g. "facienda" for ~/.facienda.db.
+func stem(dbPath string) string {
+	base := filepath.Base(dbPath)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	base = strings.TrimLeft(base, ".")
+	if base == "" {
+		return "facienda"
+	}
+	return base
+}
+
+// NewPath returns the path of a new snapshot of the database at dbPath,
+// taken at t, in dir.
+func NewPath(dir, dbPath, kind string, t time.Time) string {
+	name := stem(dbPath)
+	if kind != KindRegular {
+		name += "-" + kind
+	}
+	name += "-" + t.UTC().Format(timestampFormat) + ".db"
+	return filepath.Join(dir, name)
+}
+
+// List returns the snapshots of the database at dbPath found in dir, newest
+// first. A missing directory holds no snapshots.
+func List(dir, dbPath string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	nameRegex := regexp.MustCompile(`^` + regexp.QuoteMeta(stem(dbPath)) +
+		`(?:-(` + KindPreRestore + `|` + KindPreMigrate + `))?-(\d{8}T\d{6}Z)\.db(` + regexp.QuoteMeta(EncryptedSuffix) + `)?$`)
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := nameRegex.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		t, err := time.Parse(timestampFormat, matches[2])
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{
+			Path:      filepath.Join(dir, entry.Name()),
+			Kind:      matches[1],
+			Time:      t,
+			Encrypted: matches[3] != "",
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.After(snapshots[j].Time)
+	})
+	return snapshots, nil
+}
+
+// Expired returns the regular snapshots that keep does not retain. Days and
+// weeks are counted in loc.
+func Expired(snapshots []Snapshot, keep Retention, loc *time.Location) []Snapshot {
+	var regular []Snapshot
+	for _, s := range snapshots {
+		if s.Kind == KindRegular {
+			regular = append(regular, s)
+		}
+	}
+	sort.Slice(regular, func(i, j int) bool {
+		return regular[i].Time.After(regular[j].Time)
+	})
+
+	keepPath := map[string]bool{}
+	days := map[string]bool{}
+	weeks := map[string]bool{}
+	for i, s := range regular {
+		t := s.Time.In(loc)
+		day := t.Format("2006-01-02")
+		year, week := t.ISOWeek()
+		weekKey := fmt.Sprintf("%d-W%02d", year, week)
+
+		if i == 0 {
+			keepPath[s.Path] = true
+		}
+		if !days[day] && len(days) < keep.Daily {
+			days[day] = true
+			keepPath[s.Path] = true
+		}
+		if !weeks[weekKey] && len(weeks) < keep.Weekly {
+			weeks[weekKey] = true
+			keepPath[s.Path] = true
+		}
+	}
+
+	var expired []Snapshot
+	for _, s := range regular {
+		if !keepPath[s.Path] {
+			expired = append(expired, s)
+		}
+	}
+	return expired
+}
+
+// Prune deletes the regular snapshots of the database at dbPath in dir that
+// keep does not retain, and returns them.
+func Prune(dir, dbPath string, keep Retention) ([]Snapshot, error) {
+	snapshots, err := List(dir, dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := Expired(snapshots, keep, time.Local)
+	for _, s := range expired {
+		if err := os.Remove(s.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove old snapshot: %w", err)
+		}
+	}
+	return expired, nil
+}
+
+// Resolve finds a snapshot given either a path to it or its file name in
+// dir.
+func Resolve(dir, name string) (string, error) {
+	if info, err := os.Stat(name); err == nil && !info.IsDir() {
+		return name, nil
+	}
+
+	path := filepath.Join(dir, name)
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return path, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+}
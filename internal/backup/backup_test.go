@@ -0,0 +1,154 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewPath(t *testing.T) {
+	at := time.Date(2025, 11, 20, 9, 30, 0, 0, time.FixedZone("", 3600))
+
+	tests := []struct {
+		dbPath string
+		kind   string
+		want   string
+	}{
+		{"/home/me/.facienda.db", KindRegular, "facienda-20251120T083000Z.db"},
+		{"/data/work.db", KindRegular, "work-20251120T083000Z.db"},
+		{"/data/work.db", KindPreRestore, "work-pre-restore-20251120T083000Z.db"},
+		{"/data/tasks", KindPreMigrate, "tasks-pre-migrate-20251120T083000Z.db"},
+	}
+
+	for _, tt := range tests {
+		got := NewPath("/backups", tt.dbPath, tt.kind, at)
+		if want := filepath.Join("/backups", tt.want); got != want {
+			t.Errorf("NewPath(%q, %q) = %q, want %q", tt.dbPath, tt.kind, got, want)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := "/home/me/.facienda.db"
+
+	names := []string{
+		"facienda-20251120T083000Z.db",
+		"facienda-20251122T083000Z.db",
+		"facienda-pre-restore-20251121T083000Z.db",
+		"facienda-20251123T083000Z.db.enc",
+		"work-20251123T083000Z.db",
+		"facienda-latest.db",
+		"notes.txt",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshots, err := List(dir, dbPath)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	want := []struct {
+		name      string
+		kind      string
+		encrypted bool
+	}{
+		{"facienda-20251123T083000Z.db.enc", KindRegular, true},
+		{"facienda-20251122T083000Z.db", KindRegular, false},
+		{"facienda-pre-restore-20251121T083000Z.db", KindPreRestore, false},
+		{"facienda-20251120T083000Z.db", KindRegular, false},
+	}
+	if len(snapshots) != len(want) {
+		t.Fatalf("expected %d snapshots, got %d: %+v", len(want), len(snapshots), snapshots)
+	}
+	for i, w := range want {
+		if snapshots[i].Name() != w.name || snapshots[i].Kind != w.kind || snapshots[i].Encrypted != w.encrypted {
+			t.Errorf("snapshot %d = %s (%q), want %s (%q)", i, snapshots[i].Name(), snapshots[i].Kind, w.name, w.kind)
+		}
+	}
+
+	missing, err := List(filepath.Join(dir, "missing"), dbPath)
+	if err != nil || len(missing) != 0 {
+		t.Errorf("expected no snapshots in a missing directory, got %v, %v", missing, err)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	// Two snapshots a day for 30 days, starting on a Monday.
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	var snapshots []Snapshot
+	for day := 0; day < 30; day++ {
+		for _, hour := range []int{9, 18} {
+			at := start.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)
+			snapshots = append(snapshots, Snapshot{Path: at.Format(timestampFormat), Time: at})
+		}
+	}
+	safety := Snapshot{Path: "safety", Kind: KindPreRestore, Time: start}
+	snapshots = append(snapshots, safety)
+
+	expired := Expired(snapshots, Retention{Daily: 3, Weekly: 3}, time.UTC)
+
+	kept := map[string]bool{}
+	for _, s := range snapshots {
+		kept[s.Path] = true
+	}
+	for _, s := range expired {
+		delete(kept, s.Path)
+	}
+
+	want := []string{
+		// The newest snapshot of each of the last three days...
+		"20250930T180000Z",
+		"20250929T180000Z",
+		"20250928T180000Z",
+		// ...which already cover the two newest weeks (Sep 29-30 and
+		// Sep 22-28), so only the third week adds a snapshot.
+		"20250921T180000Z",
+		// Safety copies are never expired.
+		"safety",
+	}
+	for _, path := range want {
+		if !kept[path] {
+			t.Errorf("expected %s to be kept", path)
+		}
+	}
+	if len(kept) != len(want) {
+		t.Errorf("expected %d snapshots kept, got %d: %v", len(want), len(kept), kept)
+	}
+}
+
+func TestExpired_KeepsNewest(t *testing.T) {
+	at := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+	snapshots := []Snapshot{
+		{Path: "older", Time: at.Add(-time.Hour)},
+		{Path: "newest", Time: at},
+	}
+
+	expired := Expired(snapshots, Retention{}, time.UTC)
+	if len(expired) != 1 || expired[0].Path != "older" {
+		t.Errorf("expected only the older snapshot to expire, got %+v", expired)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "facienda-20251120T083000Z.db")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := Resolve(dir, path); err != nil || got != path {
+		t.Errorf("Resolve(path) = %q, %v", got, err)
+	}
+	if got, err := Resolve(dir, "facienda-20251120T083000Z.db"); err != nil || got != path {
+		t.Errorf("Resolve(name) = %q, %v", got, err)
+	}
+	if _, err := Resolve(dir, "missing.db"); err == nil {
+		t.Error("expected error for missing snapshot")
+	}
+}
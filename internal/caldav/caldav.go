@@ -0,0 +1,397 @@
+// Package caldav serves tasks to calendar apps over CalDAV (RFC 4791), as a
+// single collection of VTODOs backed by a storage.Storage.
+//
+// The server has one principal, at the handler's prefix, whose calendar
+// home is the prefix as well; it holds one task collection:
+//
+//	/caldav/                 principal and calendar home
+//	/caldav/tasks/           the VTODO collection
+//	/caldav/tasks/<uid>.ics  one task, named after its UID
+//
+// Resources are iCalendar files in the format of the ics export, so that
+// recurring tasks are VTODOs with an RRULE. A client marking a task
+// completed or cancelled completes or skips it as the complete and skip
+// commands would, creating the next occurrence of a recurring task. Behind
+// auth.Authenticator, the collection holds the authenticated user's tasks.
+package caldav
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/johnmirolha/facienda/internal/auth"
+	"github.com/johnmirolha/facienda/internal/exchange"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+const (
+	collectionName = "tasks"
+	// maxBodySize bounds request bodies; a single task is a few hundred
+	// bytes.
+	maxBodySize = 1 << 20
+)
+
+// Handler is the CalDAV server. It is safe for concurrent use as long as
+// its storage is.
+type Handler struct {
+	store  storage.Storage
+	prefix string
+	// all is the whole of store, before it is restricted to the request's
+	// user. The next occurrence of a recurring task is created in it: it
+	// belongs to the task's owner, who needn't be the user completing it.
+	all storage.Storage
+}
+
+// NewHandler serves the tasks in store under prefix, such as "/caldav/".
+// Requests for /.well-known/caldav are redirected to the prefix, so that
+// clients given only the server's address find it.
+func NewHandler(store storage.Storage, prefix string) *Handler {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &Handler{store: store, prefix: prefix}
+}
+
+// resource is what a request path names: the principal, the collection or
+// one task. uid is set for tasks, whether or not the task exists.
+type resource struct {
+	kind resourceKind
+	uid  string
+}
+
+type resourceKind int
+
+const (
+	principalResource resourceKind = iota
+	collectionResource
+	taskResource
+)
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/.well-known/caldav" {
+		http.Redirect(w, r, h.prefix, http.StatusMovedPermanently)
+		return
+	}
+
+	res, ok := h.resolve(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	h = &Handler{store: auth.Scope(r.Context(), h.store), prefix: h.prefix, all: h.store}
+	w.Header().Set("DAV", "1, 3, calendar-access")
+
+	var err error
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+		w.WriteHeader(http.StatusOK)
+	case "PROPFIND":
+		err = h.propfind(w, r, res)
+	case "REPORT":
+		err = h.report(w, r, res)
+	case http.MethodGet, http.MethodHead:
+		err = h.get(w, r, res)
+	case http.MethodPut:
+		err = h.put(w, r, res)
+	case http.MethodDelete:
+		err = h.delete(w, r, res)
+	default:
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// resolve maps a request path to the resource it names.
+func (h *Handler) resolve(p string) (resource, bool) {
+	rest, ok := strings.CutPrefix(p, h.prefix)
+	if !ok {
+		if p+"/" == h.prefix {
+			return resource{kind: principalResource}, true
+		}
+		return resource{}, false
+	}
+
+	switch rest = strings.TrimSuffix(rest, "/"); {
+	case rest == "":
+		return resource{kind: principalResource}, true
+	case rest == collectionName:
+		return resource{kind: collectionResource}, true
+	}
+	name, ok := strings.CutPrefix(rest, collectionName+"/")
+	if !ok || strings.Contains(name, "/") {
+		return resource{}, false
+	}
+	uid, ok := strings.CutSuffix(name, ".ics")
+	if !ok || uid == "" {
+		return resource{}, false
+	}
+	return resource{kind: taskResource, uid: uid}, true
+}
+
+func (h *Handler) collectionHref() string {
+	return h.prefix + collectionName + "/"
+}
+
+func (h *Handler) taskHref(task *todo.Task) string {
+	return h.collectionHref() + url.PathEscape(task.StableUID()) + ".ics"
+}
+
+// findTask returns the task with exactly the given UID.
+func (h *Handler) findTask(uid string) (*todo.Task, error) {
+	task, err := storage.FindByUID(h.store, uid)
+	if err != nil && !errors.Is(err, todo.ErrAmbiguousUID) {
+		return nil, err
+	}
+	if task == nil || !strings.EqualFold(task.StableUID(), uid) {
+		return nil, todo.ErrNotFound
+	}
+	return task, nil
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, res resource) error {
+	if res.kind != taskResource {
+		return httpError{http.StatusMethodNotAllowed, "only tasks can be downloaded"}
+	}
+	task, err := h.findTask(res.uid)
+	if err != nil {
+		return err
+	}
+
+	data, err := calendarData(task)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("ETag", etag(task))
+	w.Header().Set("Last-Modified", task.UpdatedAt.UTC().Format(http.TimeFormat))
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	_, err = io.WriteString(w, data)
+	return err
+}
+
+// put creates or replaces a task. A task the client marks completed or
+// cancelled is completed or skipped, with the next occurrence of a
+// recurring task created as the complete and skip commands would.
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, res resource) error {
+	if res.kind != taskResource {
+		return httpError{http.StatusMethodNotAllowed, "only tasks can be uploaded"}
+	}
+
+	rows, err := exchange.ImportICS(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return httpError{http.StatusBadRequest, err.Error()}
+	}
+	if len(rows) != 1 {
+		return httpError{http.StatusUnsupportedMediaType, "expected a calendar with exactly one VTODO"}
+	}
+	if rows[0].Err != nil {
+		return httpError{http.StatusBadRequest, rows[0].Err.Error()}
+	}
+	incoming := rows[0].Task
+	if incoming.UID == "" {
+		incoming.UID = res.uid
+	}
+
+	current, err := h.findTask(res.uid)
+	if errors.Is(err, todo.ErrNotFound) && incoming.UID != res.uid {
+		current, err = h.findTask(incoming.UID)
+	}
+	if err != nil && !errors.Is(err, todo.ErrNotFound) {
+		return err
+	}
+	if err := checkPreconditions(r, current); err != nil {
+		return err
+	}
+
+	if current == nil {
+		if user, ok := auth.UserFrom(r.Context()); ok {
+			incoming.Owner = user.Name
+		}
+		if err := h.store.Create(incoming); err != nil {
+			return err
+		}
+		return h.written(w, incoming.ID, http.StatusCreated)
+	}
+
+	next, err := h.replace(current, incoming)
+	if err != nil {
+		return err
+	}
+	if next != nil {
+		if err := h.all.Create(next); err != nil {
+			return fmt.Errorf("failed to create next instance: %w", err)
+		}
+	}
+	return h.written(w, current.ID, http.StatusNoContent)
+}
+
+// written responds to an upload with the ETag of the task as it was stored,
+// which may differ from the uploaded version in ways that don't matter,
+// such as the precision of timestamps.
+func (h *Handler) written(w http.ResponseWriter, id int64, status int) error {
+	task, err := h.store.GetByID(id)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag(task))
+	w.Header().Set("Location", h.taskHref(task))
+	w.WriteHeader(status)
+	return nil
+}
+
+// replace updates task to incoming, the version a client uploaded, and
+// returns the next occurrence to create if the client completed or skipped
+// a recurring task.
+func (h *Handler) replace(task, incoming *todo.Task) (*todo.Task, error) {
+	completed := incoming.Completed && !task.Completed
+	skipped := incoming.Skipped && !task.Skipped
+
+	if err := task.Update(incoming.Title, incoming.Details); err != nil {
+		return nil, err
+	}
+	task.Date = incoming.Date
+	task.RecurrencePattern = incoming.RecurrencePattern
+	switch {
+	case completed:
+		task.Complete()
+	case !incoming.Completed && task.Completed:
+		task.Incomplete()
+	}
+	switch {
+	case skipped:
+		task.Skip()
+	case !incoming.Skipped && task.Skipped:
+		task.Unskip()
+	}
+	if err := h.store.Update(task); err != nil {
+		return nil, err
+	}
+
+	if !(completed || skipped) || !task.IsRecurring() {
+		return nil, nil
+	}
+	next, err := task.GenerateNextInstance()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate next instance: %w", err)
+	}
+	return next, nil
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, res resource) error {
+	if res.kind != taskResource {
+		return httpError{http.StatusForbidden, "the task collection can't be deleted"}
+	}
+	task, err := h.findTask(res.uid)
+	if err != nil {
+		return err
+	}
+	if err := checkPreconditions(r, task); err != nil {
+		return err
+	}
+	if err := h.store.Delete(task.ID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// checkPreconditions applies the If-Match and If-None-Match headers clients
+// send to avoid overwriting each other's changes. current is nil if the
+// task doesn't exist.
+func checkPreconditions(r *http.Request, current *todo.Task) error {
+	failed := httpError{http.StatusPreconditionFailed, "the task was changed by someone else"}
+	if match := r.Header.Get("If-Match"); match != "" {
+		if current == nil || (match != "*" && !containsETag(match, etag(current))) {
+			return failed
+		}
+	}
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && current != nil {
+		if noneMatch == "*" || containsETag(noneMatch, etag(current)) {
+			return failed
+		}
+	}
+	return nil
+}
+
+func containsETag(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// etag identifies a version of a task. It is derived from the task's fields
+// rather than its iCalendar file, which has a DTSTAMP that changes on every
+// download.
+func etag(task *todo.Task) string {
+	hash := fnv.New64a()
+	hash.Write(storage.MarshalTask(task))
+	return fmt.Sprintf(`"%x"`, hash.Sum64())
+}
+
+// ctag identifies the state of the whole collection, so that clients can
+// tell without listing it whether anything changed.
+func ctag(tasks []*todo.Task) string {
+	tags := make([]string, len(tasks))
+	for i, task := range tasks {
+		tags[i] = etag(task)
+	}
+	sort.Strings(tags)
+
+	hash := fnv.New64a()
+	io.WriteString(hash, strings.Join(tags, ","))
+	return fmt.Sprintf(`"%x"`, hash.Sum64())
+}
+
+func calendarData(task *todo.Task) (string, error) {
+	var b strings.Builder
+	if err := exchange.ExportICS(&b, []*todo.Task{task}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// httpError is an error with the status code to respond with.
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e httpError) Error() string {
+	return e.message
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var herr httpError
+	switch {
+	case errors.As(err, &herr):
+		http.Error(w, herr.message, herr.status)
+	case errors.Is(err, todo.ErrNotFound):
+		http.Error(w, "task not found", http.StatusNotFound)
+	case errors.Is(err, todo.ErrEmptyTitle):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, todo.ErrDuplicateUID):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, todo.ErrConflict):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, todo.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
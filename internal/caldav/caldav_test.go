@@ -0,0 +1,256 @@
+package caldav
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/auth"
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+func newServer(t *testing.T) (*httptest.Server, *storage.MemoryStorage) {
+	t.Helper()
+
+	store := storage.NewMemoryStorage()
+	server := httptest.NewServer(NewHandler(store, "/caldav/"))
+	t.Cleanup(server.Close)
+	return server, store
+}
+
+func request(t *testing.T, server *httptest.Server, method, path, body string, headers ...string) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func createTask(t *testing.T, store storage.Storage, task *todo.Task) *todo.Task {
+	t.Helper()
+
+	if err := store.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	return task
+}
+
+func TestDiscovery(t *testing.T) {
+	server, store := newServer(t)
+	task, _ := todo.NewTask("Plan sprint", "", time.Date(2025, 11, 20, 0, 0, 0, 0, time.Local))
+	createTask(t, store, task)
+
+	client := *server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(server.URL + "/.well-known/caldav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != "/caldav/" {
+		t.Errorf("expected a redirect to /caldav/, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	resp, body := request(t, server, "PROPFIND", "/caldav/", `<?xml version="1.0"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:current-user-principal/><c:calendar-home-set/><d:quota-used-bytes/></d:prop>
+</d:propfind>`, "Depth", "0")
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", resp.StatusCode, body)
+	}
+	for _, want := range []string{
+		"<D:current-user-principal><D:href>/caldav/</D:href></D:current-user-principal>",
+		"<C:calendar-home-set><D:href>/caldav/</D:href></C:calendar-home-set>",
+		"<D:quota-used-bytes/></D:prop><D:status>HTTP/1.1 404 Not Found",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in:\n%s", want, body)
+		}
+	}
+
+	_, body = request(t, server, "PROPFIND", "/caldav/tasks/", "", "Depth", "1")
+	for _, want := range []string{
+		"<D:href>/caldav/tasks/</D:href>",
+		"<D:collection/><C:calendar/>",
+		`<C:comp name="VTODO"/>`,
+		"<CS:getctag>",
+		"<D:href>/caldav/tasks/" + task.UID + ".ics</D:href>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "calendar-data") {
+		t.Errorf("expected no calendar data unless asked for:\n%s", body)
+	}
+}
+
+func TestReports(t *testing.T) {
+	server, store := newServer(t)
+	task, _ := todo.NewTask("Weekly report", "", time.Date(2025, 11, 24, 0, 0, 0, 0, time.Local))
+	task.RecurrencePattern = recurrence.Pattern("weekly:monday")
+	createTask(t, store, task)
+
+	_, body := request(t, server, "REPORT", "/caldav/tasks/", `<?xml version="1.0"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO"/></c:comp-filter></c:filter>
+</c:calendar-query>`, "Depth", "1")
+	for _, want := range []string{"<D:getetag>", "SUMMARY:Weekly report", "RRULE:FREQ=WEEKLY;BYDAY=MO"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in:\n%s", want, body)
+		}
+	}
+
+	_, body = request(t, server, "REPORT", "/caldav/tasks/", `<?xml version="1.0"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT"/></c:comp-filter></c:filter>
+</c:calendar-query>`)
+	if strings.Contains(body, "<D:response>") {
+		t.Errorf("expected no events, got:\n%s", body)
+	}
+
+	_, body = request(t, server, "REPORT", "/caldav/tasks/", `<?xml version="1.0"?>
+<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><c:calendar-data/></d:prop>
+  <d:href>/caldav/tasks/`+task.UID+`.ics</d:href>
+  <d:href>/caldav/tasks/missing.ics</d:href>
+</c:calendar-multiget>`)
+	if !strings.Contains(body, "UID:"+task.UID) || !strings.Contains(body, "<D:href>/caldav/tasks/missing.ics</D:href><D:status>HTTP/1.1 404 Not Found") {
+		t.Errorf("unexpected multiget response:\n%s", body)
+	}
+}
+
+const clientTask = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Example//Tasks//EN\r\n" +
+	"BEGIN:VTODO\r\nUID:phone-1\r\nSUMMARY:Water the plants\r\nDTSTART;VALUE=DATE:20251124\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO\r\nSTATUS:%s\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+
+func TestPutGetDelete(t *testing.T) {
+	server, store := newServer(t)
+
+	resp, body := request(t, server, http.MethodPut, "/caldav/tasks/phone-1.ics",
+		strings.Replace(clientTask, "%s", "NEEDS-ACTION", 1), "If-None-Match", "*")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	created := resp.Header.Get("ETag")
+
+	resp, body = request(t, server, http.MethodGet, "/caldav/tasks/phone-1.ics", "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != created {
+		t.Fatalf("expected the task with ETag %s, got %d %s", created, resp.StatusCode, resp.Header.Get("ETag"))
+	}
+	if !strings.Contains(body, "SUMMARY:Water the plants") || !strings.Contains(body, "RRULE:FREQ=WEEKLY;BYDAY=MO") {
+		t.Errorf("unexpected task:\n%s", body)
+	}
+
+	// Creating it again, or changing an outdated version, fails.
+	resp, _ = request(t, server, http.MethodPut, "/caldav/tasks/phone-1.ics",
+		strings.Replace(clientTask, "%s", "NEEDS-ACTION", 1), "If-None-Match", "*")
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for an existing task, got %d", resp.StatusCode)
+	}
+	resp, _ = request(t, server, http.MethodPut, "/caldav/tasks/phone-1.ics",
+		strings.Replace(clientTask, "%s", "COMPLETED", 1), "If-Match", `"outdated"`)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for an outdated ETag, got %d", resp.StatusCode)
+	}
+
+	// Ticking off a recurring task completes it and schedules the next one.
+	resp, body = request(t, server, http.MethodPut, "/caldav/tasks/phone-1.ics",
+		strings.Replace(clientTask, "%s", "COMPLETED", 1), "If-Match", created)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", resp.StatusCode, body)
+	}
+	tasks, _ := store.All()
+	if len(tasks) != 2 || !tasks[0].Completed || tasks[1].Completed {
+		t.Fatalf("expected the completed task and its next occurrence, got %+v", tasks)
+	}
+	if got := tasks[1].Date.Format("2006-01-02"); got != "2025-12-01" {
+		t.Errorf("expected the next occurrence on 2025-12-01, got %s", got)
+	}
+
+	resp, _ = request(t, server, http.MethodDelete, "/caldav/tasks/phone-1.ics", "")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	if resp, _ = request(t, server, http.MethodGet, "/caldav/tasks/phone-1.ics", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected the task to be gone, got %d", resp.StatusCode)
+	}
+}
+
+func TestPut_AssigneeCompletesRecurring(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	handler := NewHandler(store, "/caldav/")
+	// bob is signed in, as auth.Authenticator would do.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), auth.User{Name: "bob"})))
+	}))
+	t.Cleanup(server.Close)
+
+	createTask(t, store, &todo.Task{
+		Title:             "Water the plants",
+		Date:              time.Date(2025, 11, 24, 0, 0, 0, 0, time.Local),
+		RecurrencePattern: recurrence.Pattern("weekly:monday"),
+		UID:               "phone-1",
+		Owner:             "carol",
+		Assignee:          "bob",
+	})
+
+	resp, body := request(t, server, http.MethodPut, "/caldav/tasks/phone-1.ics", strings.Replace(clientTask, "%s", "COMPLETED", 1))
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", resp.StatusCode, body)
+	}
+	tasks, _ := store.All()
+	if len(tasks) != 2 || !tasks[0].Completed {
+		t.Fatalf("expected the completed task and its next occurrence, got %+v", tasks)
+	}
+	if next := tasks[1]; next.Owner != "carol" || next.Assignee != "bob" || next.Completed {
+		t.Errorf("expected the next occurrence owned by carol and assigned to bob, got %+v", next)
+	}
+}
+
+func TestPut_UnmappedRule(t *testing.T) {
+	server, store := newServer(t)
+
+	body := strings.Replace(strings.Replace(clientTask, "%s", "NEEDS-ACTION", 1), "BYDAY=MO", "INTERVAL=2", 1)
+	if resp, body := request(t, server, http.MethodPut, "/caldav/tasks/phone-1.ics", body); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	tasks, _ := store.All()
+	if len(tasks) != 1 || tasks[0].IsRecurring() {
+		t.Errorf("expected a one-off task, got %+v", tasks)
+	}
+}
+
+func TestPut_Rejects(t *testing.T) {
+	server, _ := newServer(t)
+
+	for name, body := range map[string]string{
+		"event":       "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:e\r\nSUMMARY:Party\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		"empty title": strings.Replace(strings.Replace(clientTask, "%s", "NEEDS-ACTION", 1), "Water the plants", "", 1),
+	} {
+		if resp, _ := request(t, server, http.MethodPut, "/caldav/tasks/phone-1.ics", body); resp.StatusCode < 400 {
+			t.Errorf("%s: expected an error, got %d", name, resp.StatusCode)
+		}
+	}
+	if resp, _ := request(t, server, http.MethodPut, "/caldav/tasks/", clientTask); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for the collection, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,304 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// XML namespaces of WebDAV, CalDAV and the calendarserver.org extensions
+// that clients use to poll collections cheaply.
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	nsCS     = "http://calendarserver.org/ns/"
+)
+
+// prefixes are the namespace prefixes declared on every multistatus
+// response.
+var prefixes = map[string]string{nsDAV: "D", nsCalDAV: "C", nsCS: "CS"}
+
+var calendarDataName = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+
+// propfindBody is the body of a PROPFIND request. An empty body asks for
+// every property, like allprop.
+type propfindBody struct {
+	XMLName xml.Name     `xml:"DAV: propfind"`
+	Prop    *propElement `xml:"DAV: prop"`
+}
+
+// reportBody is the body of a calendar-query or calendar-multiget REPORT.
+type reportBody struct {
+	XMLName xml.Name
+	Prop    *propElement `xml:"DAV: prop"`
+	Hrefs   []string     `xml:"DAV: href"`
+	Filter  struct {
+		Calendar struct {
+			Components []struct {
+				Name string `xml:"name,attr"`
+			} `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+		} `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+// propElement is a DAV:prop element listing the properties asked for.
+type propElement struct {
+	Names []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+// names returns the properties asked for, or nil for all of them.
+func (p *propElement) names() []xml.Name {
+	if p == nil {
+		return nil
+	}
+	names := make([]xml.Name, len(p.Names))
+	for i, n := range p.Names {
+		names[i] = n.XMLName
+	}
+	return names
+}
+
+func readXML(r *http.Request, v any) error {
+	err := xml.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return err
+	}
+	if err != nil {
+		return httpError{http.StatusBadRequest, "invalid XML body: " + err.Error()}
+	}
+	return nil
+}
+
+func (h *Handler) propfind(w http.ResponseWriter, r *http.Request, res resource) error {
+	var body propfindBody
+	if err := readXML(r, &body); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	names := body.Prop.names()
+	children := r.Header.Get("Depth") != "0"
+
+	ms := &multistatus{}
+	switch res.kind {
+	case principalResource:
+		ms.add(h.prefix, h.principalProps(), names)
+		if children {
+			tasks, err := h.store.All()
+			if err != nil {
+				return err
+			}
+			ms.add(h.collectionHref(), h.collectionProps(tasks), names)
+		}
+	case collectionResource:
+		tasks, err := h.store.All()
+		if err != nil {
+			return err
+		}
+		ms.add(h.collectionHref(), h.collectionProps(tasks), names)
+		if children {
+			for _, task := range tasks {
+				ms.add(h.taskHref(task), h.taskProps(task, names), names)
+			}
+		}
+	case taskResource:
+		task, err := h.findTask(res.uid)
+		if err != nil {
+			return err
+		}
+		ms.add(h.taskHref(task), h.taskProps(task, names), names)
+	}
+	return ms.writeTo(w)
+}
+
+// report answers the calendar-query and calendar-multiget reports clients
+// use to download the collection. Queries return every task: the only
+// filter applied is the component, since the collection only has VTODOs.
+func (h *Handler) report(w http.ResponseWriter, r *http.Request, res resource) error {
+	if res.kind != collectionResource {
+		return httpError{http.StatusForbidden, "reports are only supported on the task collection"}
+	}
+	var body reportBody
+	if err := readXML(r, &body); err != nil {
+		if errors.Is(err, io.EOF) {
+			return httpError{http.StatusBadRequest, "missing report"}
+		}
+		return err
+	}
+	names := body.Prop.names()
+
+	ms := &multistatus{}
+	switch body.XMLName {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		for _, comp := range body.Filter.Calendar.Components {
+			if !strings.EqualFold(comp.Name, "VTODO") {
+				return ms.writeTo(w)
+			}
+		}
+		tasks, err := h.store.All()
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			ms.add(h.taskHref(task), h.taskProps(task, names), names)
+		}
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		for _, href := range body.Hrefs {
+			task, err := h.taskAt(href)
+			if errors.Is(err, todo.ErrNotFound) {
+				ms.missing(href)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			ms.add(href, h.taskProps(task, names), names)
+		}
+	default:
+		return httpError{http.StatusForbidden, "unsupported report " + body.XMLName.Local}
+	}
+	return ms.writeTo(w)
+}
+
+// taskAt returns the task an href names.
+func (h *Handler) taskAt(href string) (*todo.Task, error) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return nil, todo.ErrNotFound
+	}
+	res, ok := h.resolve(u.Path)
+	if !ok || res.kind != taskResource {
+		return nil, todo.ErrNotFound
+	}
+	return h.findTask(res.uid)
+}
+
+// privileges lets clients know they may change the collection, rather than
+// showing it read-only.
+const privileges = "<D:privilege><D:read/></D:privilege><D:privilege><D:write/></D:privilege>" +
+	"<D:privilege><D:write-content/></D:privilege><D:privilege><D:bind/></D:privilege>" +
+	"<D:privilege><D:unbind/></D:privilege>"
+
+func (h *Handler) principalProps() map[xml.Name]string {
+	href := "<D:href>" + escape(h.prefix) + "</D:href>"
+	return map[xml.Name]string{
+		{Space: nsDAV, Local: "resourcetype"}:           "<D:collection/><D:principal/>",
+		{Space: nsDAV, Local: "displayname"}:            "facienda",
+		{Space: nsDAV, Local: "current-user-principal"}: href,
+		{Space: nsDAV, Local: "principal-URL"}:          href,
+		{Space: nsCalDAV, Local: "calendar-home-set"}:   href,
+	}
+}
+
+func (h *Handler) collectionProps(tasks []*todo.Task) map[xml.Name]string {
+	tag := escape(ctag(tasks))
+	return map[xml.Name]string{
+		{Space: nsDAV, Local: "resourcetype"}:                        "<D:collection/><C:calendar/>",
+		{Space: nsDAV, Local: "displayname"}:                         "facienda",
+		{Space: nsDAV, Local: "current-user-principal"}:              "<D:href>" + escape(h.prefix) + "</D:href>",
+		{Space: nsDAV, Local: "current-user-privilege-set"}:          privileges,
+		{Space: nsDAV, Local: "getetag"}:                             tag,
+		{Space: nsCS, Local: "getctag"}:                              tag,
+		{Space: nsCalDAV, Local: "supported-calendar-component-set"}: `<C:comp name="VTODO"/>`,
+		{Space: nsDAV, Local: "supported-report-set"}: "<D:supported-report><D:report><C:calendar-query/></D:report></D:supported-report>" +
+			"<D:supported-report><D:report><C:calendar-multiget/></D:report></D:supported-report>",
+	}
+}
+
+// taskProps returns the properties of a task. Its calendar data is only
+// included when asked for.
+func (h *Handler) taskProps(task *todo.Task, names []xml.Name) map[xml.Name]string {
+	props := map[xml.Name]string{
+		{Space: nsDAV, Local: "resourcetype"}:               "",
+		{Space: nsDAV, Local: "getetag"}:                    escape(etag(task)),
+		{Space: nsDAV, Local: "getcontenttype"}:             "text/calendar; charset=utf-8; component=VTODO",
+		{Space: nsDAV, Local: "getlastmodified"}:            task.UpdatedAt.UTC().Format(http.TimeFormat),
+		{Space: nsDAV, Local: "current-user-privilege-set"}: privileges,
+	}
+	for _, name := range names {
+		if name == calendarDataName {
+			if data, err := calendarData(task); err == nil {
+				props[name] = escape(data)
+			}
+		}
+	}
+	return props
+}
+
+// multistatus builds a 207 Multi-Status response.
+type multistatus struct {
+	b strings.Builder
+}
+
+// add writes the response for the resource at href: the properties asked
+// for, or all of them if names is nil, with the ones it doesn't have listed
+// as not found.
+func (m *multistatus) add(href string, props map[xml.Name]string, names []xml.Name) {
+	var found, missing strings.Builder
+	if names == nil {
+		for name := range props {
+			if name != calendarDataName {
+				names = append(names, name)
+			}
+		}
+		sort.Slice(names, func(i, j int) bool { return element(names[i], "") < element(names[j], "") })
+	}
+	for _, name := range names {
+		if value, ok := props[name]; ok {
+			found.WriteString(element(name, value))
+		} else {
+			missing.WriteString(element(name, ""))
+		}
+	}
+
+	m.b.WriteString("<D:response><D:href>" + escape(href) + "</D:href>")
+	if found.Len() > 0 {
+		m.b.WriteString("<D:propstat><D:prop>" + found.String() + "</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat>")
+	}
+	if missing.Len() > 0 {
+		m.b.WriteString("<D:propstat><D:prop>" + missing.String() + "</D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>")
+	}
+	m.b.WriteString("</D:response>")
+}
+
+// missing writes the response for an href that names nothing.
+func (m *multistatus) missing(href string) {
+	m.b.WriteString("<D:response><D:href>" + escape(href) + "</D:href><D:status>HTTP/1.1 404 Not Found</D:status></D:response>")
+}
+
+func (m *multistatus) writeTo(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, err := io.WriteString(w, xml.Header+`<D:multistatus xmlns:D="DAV:" xmlns:C="`+nsCalDAV+`" xmlns:CS="`+nsCS+`">`+
+		m.b.String()+"</D:multistatus>\n")
+	return err
+}
+
+// element renders a property with the given inner XML, declaring its
+// namespace if it isn't one of the prefixes.
+func element(name xml.Name, inner string) string {
+	tag, open := name.Local, name.Local
+	if prefix, ok := prefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+		open = tag
+	} else if name.Space != "" {
+		tag = "X:" + name.Local
+		open = tag + ` xmlns:X="` + escape(name.Space) + `"`
+	}
+	if inner == "" {
+		return "<" + open + "/>"
+	}
+	return "<" + open + ">" + inner + "</" + tag + ">"
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
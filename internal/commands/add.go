@@ -5,14 +5,18 @@ import (
 	"time"
 
 	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/storage"
 	"github.com/johnmirolha/facienda/internal/todo"
 	"github.com/spf13/cobra"
 )
 
 var (
-	addDate    string
-	addDetails string
-	addRecur   string
+	addDate     string
+	addTime     string
+	addDetails  string
+	addRecur    string
+	addOwner    string
+	addAssignee string
 )
 
 var addCmd = &cobra.Command{
@@ -25,61 +29,76 @@ You can optionally specify a date or make the task recurring.
 Examples:
   facienda add "Buy groceries"
   facienda add "Team meeting" --date 2025-11-20
+  facienda add "Dentist" --date 2025-11-21 --time 14:30
   facienda add "Weekly report" --recur "every monday"
   facienda add "Pay rent" --recur "1st of each month"`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		title := args[0]
-
-		// Handle recurring tasks
-		if addRecur != "" {
-			pattern, err := recurrence.ParsePattern(addRecur)
-			if err != nil {
-				return fmt.Errorf("invalid recurrence pattern: %w\nExamples: 'every monday', '3rd of each month'", err)
-			}
-
-			task, err := todo.NewRecurringTask(title, addDetails, pattern)
-			if err != nil {
-				return err
-			}
+		task, err := newTask(args[0], addDetails, addDate, addTime, addRecur)
+		if err != nil {
+			return err
+		}
+		task.Owner, task.Assignee = addOwner, addAssignee
 
-			if err := store.Create(task); err != nil {
-				return err
-			}
+		if err := store.Create(task); err != nil {
+			return err
+		}
 
+		if task.IsRecurring() {
 			fmt.Printf("✓ Recurring task added (ID: %d)\n", task.ID)
-			fmt.Printf("  Pattern: %s\n", pattern.String())
+			fmt.Printf("  Pattern: %s\n", task.RecurrencePattern.String())
 			fmt.Printf("  Next occurrence: %s\n", task.Date.Format("Mon, Jan 2, 2006"))
 			return nil
 		}
+		fmt.Printf("✓ Task added (ID: %d)\n", task.ID)
+		return nil
+	},
+}
 
-		// Handle regular tasks
-		date := time.Now()
-		if addDate != "" {
-			parsedDate, err := time.Parse("2006-01-02", addDate)
-			if err != nil {
-				return fmt.Errorf("invalid date format (use YYYY-MM-DD): %w", err)
-			}
-			date = parsedDate
-		}
+// newTask builds a task from the values of the add command's flags: a date
+// (YYYY-MM-DD, default today) and time of day (HH:MM) for a one-off task,
+// or a recurrence phrase, in which case the task is scheduled for its first
+// occurrence from today.
+func newTask(title, details, date, at, recur string) (*todo.Task, error) {
+	if at != "" && recur != "" {
+		return nil, fmt.Errorf("--time can't be used with --recur")
+	}
 
-		task, err := todo.NewTask(title, addDetails, date)
+	// Handle recurring tasks
+	if recur != "" {
+		pattern, err := recurrence.ParsePattern(recur)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("invalid recurrence pattern: %w\nExamples: 'every monday', '3rd of each month'", err)
 		}
+		return todo.NewRecurringTask(title, details, pattern)
+	}
 
-		if err := store.Create(task); err != nil {
-			return err
+	// Handle regular tasks
+	day := storage.StartOfDay(time.Now())
+	if date != "" {
+		parsedDate, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date format (use YYYY-MM-DD): %w", err)
 		}
-
-		fmt.Printf("✓ Task added (ID: %d)\n", task.ID)
-		return nil
-	},
+		day = parsedDate
+	}
+	if at != "" {
+		clock, err := time.Parse("15:04", at)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time format (use HH:MM): %w", err)
+		}
+		year, month, d := day.Date()
+		day = time.Date(year, month, d, clock.Hour(), clock.Minute(), 0, 0, time.Local)
+	}
+	return todo.NewTask(title, details, day)
 }
 
 func init() {
 	addCmd.Flags().StringVarP(&addDate, "date", "d", "", "task date (YYYY-MM-DD, default: today)")
+	addCmd.Flags().StringVarP(&addTime, "time", "t", "", "time of day (HH:MM, default: the whole day)")
 	addCmd.Flags().StringVarP(&addDetails, "details", "m", "", "task details")
 	addCmd.Flags().StringVarP(&addRecur, "recur", "r", "", "recurrence pattern (e.g., 'every monday', '3rd of each month')")
+	addCmd.Flags().StringVar(&addOwner, "owner", "", "user who owns the task on the server")
+	addCmd.Flags().StringVar(&addAssignee, "assignee", "", "user the task is assigned to on the server")
 	rootCmd.AddCommand(addCmd)
 }
@@ -0,0 +1,243 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/backup"
+	"github.com/johnmirolha/facienda/internal/encryption"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupKeepDaily  int
+	backupKeepWeekly int
+	backupEncrypt    bool
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up the task database",
+	Long: `Write a timestamped snapshot of the task database to the backup directory.
+
+The snapshot is taken with SQLite's online backup API, so it is consistent
+even while other facienda processes are using the database. Old snapshots
+are rotated: the newest snapshot of each of the last --keep-daily days and
+of each of the last --keep-weekly weeks is kept.
+
+With --encrypt the snapshot is encrypted with a passphrase (AES-256-GCM,
+key derived with PBKDF2), read from the FACIENDA_PASSPHRASE environment
+variable or asked for. The unencrypted copy never touches the backup
+directory.
+
+Examples:
+  facienda backup
+  facienda backup --encrypt
+  facienda backup --keep-daily 14 --keep-weekly 8
+  facienda backup --backup-dir /mnt/shared/facienda`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshotter, err := currentSnapshotter()
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(backupDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create backup directory: %w", err)
+		}
+
+		passphrase := ""
+		path := backup.NewPath(backupDir, dbPath, backup.KindRegular, time.Now())
+		if backupEncrypt {
+			if passphrase, err = readPassphrase(cmd, true); err != nil {
+				return err
+			}
+			path += backup.EncryptedSuffix
+		}
+
+		if err := writeSnapshot(snapshotter, path, passphrase); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Backup written to %s\n", path)
+
+		removed, err := backup.Prune(backupDir, dbPath, backup.Retention{
+			Daily:  backupKeepDaily,
+			Weekly: backupKeepWeekly,
+		})
+		if err != nil {
+			return err
+		}
+		for _, snapshot := range removed {
+			fmt.Printf("  Removed old backup %s\n", snapshot.Name())
+		}
+
+		return nil
+	},
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List database backups",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshots, err := backup.List(backupDir, dbPath)
+		if err != nil {
+			return err
+		}
+
+		if len(snapshots) == 0 {
+			fmt.Printf("No backups in %s.\n", backupDir)
+			return nil
+		}
+
+		fmt.Printf("Backups in %s:\n\n", backupDir)
+		for _, snapshot := range snapshots {
+			var labels []string
+			if snapshot.Kind != backup.KindRegular {
+				labels = append(labels, snapshot.Kind)
+			}
+			if snapshot.Encrypted {
+				labels = append(labels, "encrypted")
+			}
+			suffix := ""
+			if len(labels) > 0 {
+				suffix = fmt.Sprintf(" (%s)", strings.Join(labels, ", "))
+			}
+			fmt.Printf("%s  %s%s\n", snapshot.Time.Local().Format("2006-01-02 15:04:05"), snapshot.Name(), suffix)
+		}
+
+		return nil
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [snapshot]",
+	Short: "Restore the task database from a backup",
+	Long: `Replace the task database with a snapshot taken by "facienda backup".
+
+The snapshot can be given as a path or as a file name in the backup directory.
+Before restoring, the current database is saved as a pre-restore snapshot in
+the backup directory, so a restore can itself be undone.
+
+Encrypted snapshots are decrypted with the passphrase from the
+FACIENDA_PASSPHRASE environment variable or a prompt; the pre-restore copy is
+then encrypted with the same passphrase.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshotter, err := currentSnapshotter()
+		if err != nil {
+			return err
+		}
+
+		path, err := backup.Resolve(backupDir, args[0])
+		if err != nil {
+			return err
+		}
+		if sameFile(path, dbPath) {
+			return fmt.Errorf("cannot restore the database from itself")
+		}
+
+		if err := os.MkdirAll(backupDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create backup directory: %w", err)
+		}
+
+		// Decrypt first, so a wrong passphrase fails before anything is
+		// written.
+		src, passphrase := path, ""
+		encrypted, err := encryption.IsEncryptedFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		if encrypted {
+			if passphrase, err = readPassphrase(cmd, false); err != nil {
+				return err
+			}
+
+			tmpDir, err := os.MkdirTemp("", "facienda-restore-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(tmpDir)
+
+			src = filepath.Join(tmpDir, "snapshot.db")
+			if err := encryption.DecryptFile(path, src, passphrase); err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", filepath.Base(path), err)
+			}
+		}
+
+		safety := backup.NewPath(backupDir, dbPath, backup.KindPreRestore, time.Now())
+		if encrypted {
+			safety += backup.EncryptedSuffix
+		}
+		if err := writeSnapshot(snapshotter, safety, passphrase); err != nil {
+			return fmt.Errorf("failed to save pre-restore copy: %w", err)
+		}
+		fmt.Printf("✓ Current database saved to %s\n", safety)
+
+		if err := snapshotter.Restore(src); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Database restored from %s\n", filepath.Base(path))
+		return nil
+	},
+}
+
+// writeSnapshot backs the database up to path, encrypted with passphrase
+// unless it is empty. The unencrypted copy of an encrypted snapshot is
+// staged in a private temporary directory rather than next to path.
+func writeSnapshot(snapshotter storage.Snapshotter, path, passphrase string) error {
+	if passphrase == "" {
+		return snapshotter.Backup(path)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "facienda-backup-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	plain := filepath.Join(tmpDir, "snapshot.db")
+	if err := snapshotter.Backup(plain); err != nil {
+		return err
+	}
+	if err := encryption.EncryptFile(plain, path, passphrase); err != nil {
+		return fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+	return nil
+}
+
+// currentSnapshotter returns the open store as a Snapshotter, or an error if
+// the configured backend can't take snapshots.
+func currentSnapshotter() (storage.Snapshotter, error) {
+	snapshotter, ok := storage.As[storage.Snapshotter](store)
+	if !ok {
+		return nil, fmt.Errorf("backups are only supported by the %s backend", storage.BackendSQLite)
+	}
+	return snapshotter, nil
+}
+
+func sameFile(a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+func init() {
+	backupCmd.Flags().IntVar(&backupKeepDaily, "keep-daily", 7, "number of days to keep a daily backup for")
+	backupCmd.Flags().IntVar(&backupKeepWeekly, "keep-weekly", 4, "number of weeks to keep a weekly backup for")
+	backupCmd.Flags().BoolVar(&backupEncrypt, "encrypt", false, "encrypt the backup with a passphrase")
+	backupCmd.AddCommand(backupListCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+}
@@ -2,72 +2,41 @@ package commands
 
 import (
 	"fmt"
-	"strconv"
 
+	"github.com/johnmirolha/facienda/internal/todo"
 	"github.com/spf13/cobra"
 )
 
 var completeCmd = &cobra.Command{
-	Use:   "complete [task-id]",
+	Use:   "complete [task-id|uid]",
 	Short: "Mark a task as completed",
 	Long: `Mark a task as completed.
 
 If the task is recurring, this will automatically create the next occurrence.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		id, err := strconv.ParseInt(args[0], 10, 64)
-		if err != nil {
-			return fmt.Errorf("invalid task ID: %w", err)
-		}
-
-		task, err := store.GetByID(id)
+		task, err := findTask(args[0])
 		if err != nil {
 			return err
 		}
 
-		// Check if this is a recurring task
-		isRecurring := task.IsRecurring()
-
-		task.Complete()
-		if err := store.Update(task); err != nil {
+		next, err := completeTask(task)
+		if err != nil {
 			return err
 		}
 
-		fmt.Printf("✓ Task %d marked as completed\n", id)
-
-		// If recurring, generate the next instance
-		if isRecurring {
-			nextTask, err := task.GenerateNextInstance()
-			if err != nil {
-				return fmt.Errorf("failed to generate next instance: %w", err)
-			}
-
-			if nextTask != nil {
-				if err := store.Create(nextTask); err != nil {
-					return fmt.Errorf("failed to create next instance: %w", err)
-				}
-
-				fmt.Printf("✓ Next occurrence created (ID: %d) for %s\n",
-					nextTask.ID,
-					nextTask.Date.Format("Mon, Jan 2, 2006"))
-			}
-		}
-
+		fmt.Printf("✓ Task %d marked as completed\n", task.ID)
+		printNextInstance(next)
 		return nil
 	},
 }
 
 var incompleteCmd = &cobra.Command{
-	Use:   "incomplete [task-id]",
+	Use:   "incomplete [task-id|uid]",
 	Short: "Mark a task as incomplete",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		id, err := strconv.ParseInt(args[0], 10, 64)
-		if err != nil {
-			return fmt.Errorf("invalid task ID: %w", err)
-		}
-
-		task, err := store.GetByID(id)
+		task, err := findTask(args[0])
 		if err != nil {
 			return err
 		}
@@ -77,11 +46,49 @@ var incompleteCmd = &cobra.Command{
 			return err
 		}
 
-		fmt.Printf("✓ Task %d marked as incomplete\n", id)
+		fmt.Printf("✓ Task %d marked as incomplete\n", task.ID)
 		return nil
 	},
 }
 
+// completeTask marks task as completed and, if it is recurring, creates its
+// next occurrence, which it returns.
+func completeTask(task *todo.Task) (*todo.Task, error) {
+	task.Complete()
+	if err := store.Update(task); err != nil {
+		return nil, err
+	}
+	return createNextInstance(task)
+}
+
+// createNextInstance creates the occurrence of a recurring task that follows
+// task. It returns nil for one-off tasks.
+func createNextInstance(task *todo.Task) (*todo.Task, error) {
+	if !task.IsRecurring() {
+		return nil, nil
+	}
+
+	next, err := task.GenerateNextInstance()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate next instance: %w", err)
+	}
+	if next == nil {
+		return nil, nil
+	}
+	if err := store.Create(next); err != nil {
+		return nil, fmt.Errorf("failed to create next instance: %w", err)
+	}
+	return next, nil
+}
+
+func printNextInstance(next *todo.Task) {
+	if next != nil {
+		fmt.Printf("✓ Next occurrence created (ID: %d) for %s\n",
+			next.ID,
+			next.Date.Format("Mon, Jan 2, 2006"))
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(completeCmd)
 	rootCmd.AddCommand(incompleteCmd)
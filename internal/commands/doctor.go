@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/johnmirolha/facienda/internal/doctor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorFix         bool
+	doctorInteractive bool
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the task database for problems",
+	Long: `Check the task database for problems and optionally repair them.
+
+The checks cover database corruption and unreadable dates (SQLite integrity
+check), tasks with an empty title, recurrence patterns that don't parse, and
+recurring tasks with more than one open instance.
+
+By default problems are only reported. Use --fix to repair everything that
+can be repaired, or --interactive to choose fixes one by one.
+
+Examples:
+  facienda doctor
+  facienda doctor --interactive
+  facienda doctor --fix`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if doctorFix && doctorInteractive {
+			return fmt.Errorf("--fix and --interactive can't be used together")
+		}
+
+		issues, err := doctor.Check(store)
+		if err != nil {
+			return err
+		}
+
+		if len(issues) == 0 {
+			fmt.Println("✓ No problems found.")
+			return nil
+		}
+
+		fmt.Printf("Found %d problem(s):\n", len(issues))
+
+		input := bufio.NewReader(cmd.InOrStdin())
+		remaining := 0
+		for _, issue := range issues {
+			fmt.Printf("\n✗ %s\n", issue.Message)
+
+			if !issue.Fixable() {
+				if issue.Kind == doctor.KindIntegrity {
+					fmt.Println("  Can't be fixed automatically; restore a backup with 'facienda restore'.")
+				} else {
+					fmt.Println("  Can't be fixed automatically.")
+				}
+				remaining++
+				continue
+			}
+
+			fmt.Printf("  Fix: %s\n", issue.Repair)
+
+			apply := doctorFix
+			if doctorInteractive {
+				apply, err = confirm(input, "  Apply this fix? [y/N] ")
+				if err != nil {
+					return err
+				}
+			}
+			if !apply {
+				remaining++
+				continue
+			}
+
+			if err := issue.Fix(store); err != nil {
+				return fmt.Errorf("failed to fix task %d: %w", issue.TaskID, err)
+			}
+			fmt.Println("  ✓ Fixed")
+		}
+
+		if remaining == 0 {
+			fmt.Println("\n✓ All problems fixed.")
+			return nil
+		}
+
+		if !doctorFix && !doctorInteractive {
+			fmt.Println("\nRun 'facienda doctor --fix' to repair them, or 'facienda doctor --interactive' to choose.")
+		}
+
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d problem(s) remaining", remaining)
+	},
+}
+
+// confirm asks a yes/no question on stdout and reads the answer from input.
+// Anything but "y" or "yes" counts as no, including end of input.
+func confirm(input *bufio.Reader, prompt string) (bool, error) {
+	fmt.Print(prompt)
+
+	answer, err := input.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	if err == io.EOF {
+		fmt.Println()
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "repair every problem that can be repaired")
+	doctorCmd.Flags().BoolVarP(&doctorInteractive, "interactive", "i", false, "ask before repairing each problem")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -2,27 +2,23 @@ package commands
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	editTitle   string
-	editDetails string
+	editTitle    string
+	editDetails  string
+	editOwner    string
+	editAssignee string
 )
 
 var editCmd = &cobra.Command{
-	Use:   "edit [task-id]",
+	Use:   "edit [task-id|uid]",
 	Short: "Edit task details",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		id, err := strconv.ParseInt(args[0], 10, 64)
-		if err != nil {
-			return fmt.Errorf("invalid task ID: %w", err)
-		}
-
-		task, err := store.GetByID(id)
+		task, err := findTask(args[0])
 		if err != nil {
 			return err
 		}
@@ -40,12 +36,18 @@ var editCmd = &cobra.Command{
 		if err := task.Update(title, details); err != nil {
 			return err
 		}
+		if cmd.Flags().Changed("owner") {
+			task.Owner = editOwner
+		}
+		if cmd.Flags().Changed("assignee") {
+			task.Assignee = editAssignee
+		}
 
 		if err := store.Update(task); err != nil {
 			return err
 		}
 
-		fmt.Printf("✓ Task %d updated\n", id)
+		fmt.Printf("✓ Task %d updated\n", task.ID)
 		return nil
 	},
 }
@@ -53,5 +55,7 @@ var editCmd = &cobra.Command{
 func init() {
 	editCmd.Flags().StringVarP(&editTitle, "title", "t", "", "new task title")
 	editCmd.Flags().StringVarP(&editDetails, "details", "m", "", "new task details")
+	editCmd.Flags().StringVar(&editOwner, "owner", "", "user who owns the task on the server (empty for nobody)")
+	editCmd.Flags().StringVar(&editAssignee, "assignee", "", "user the task is assigned to on the server (empty for nobody)")
 	rootCmd.AddCommand(editCmd)
 }
@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/encryption"
+	"github.com/johnmirolha/facienda/internal/exchange"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat    string
+	exportOutput    string
+	exportFrom      string
+	exportTo        string
+	exportCompleted bool
+	exportRecurring bool
+	exportEncrypt   bool
+	exportView      string
+)
+
+// exportViews are the listing views --view selects tasks like.
+var exportViews = map[string]storage.TimeFilter{
+	"past":   storage.FilterPast,
+	"today":  storage.FilterCurrent,
+	"future": storage.FilterFuture,
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export tasks to a file",
+	Long: `Export tasks to a file.
+
+JSON and CSV keep every task field. The JSON format is a versioned document
+({"schema_version": 1, "tasks": [...]}); the CSV format has one row per task
+with the same field names as columns. The todotxt format writes todo.txt
+lines, with the task date as due: and recurrences as rec:. The ics format
+writes iCalendar VTODOs with RRULEs for calendar apps; each task gets a UID,
+so importing the file again updates the tasks instead of duplicating them.
+The markdown format writes a checklist grouped by date for notes apps such
+as Obsidian, with 📅 dates and 🔁 recurrences.
+The org format writes Org-mode headlines for Emacs, scheduled on the task
+date, with weekly and monthly recurrences as +1w and +1m repeaters.
+Skipped tasks are included and marked as skipped, unless --view selects
+the tasks the past, list (today) or future command shows.
+
+--completed and --recurring only filter when given: --completed exports only
+completed tasks and --completed=false only open ones, and likewise for
+--recurring. With --encrypt the export is encrypted with a passphrase, like
+encrypted backups.
+
+Examples:
+  facienda export > tasks.json
+  facienda export --format csv --output tasks.csv
+  facienda export --format todotxt --completed=false > todo.txt
+  facienda export --format ics --output tasks.ics
+  facienda export --format markdown --view future > upcoming.md
+  facienda export --format org --completed=false > ~/org/facienda.org
+  facienda export --from 2025-01-01 --to 2025-12-31 --completed
+  facienda export --recurring=false --encrypt --output tasks.json.enc`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := exchange.Lookup(exportFormat)
+		if err != nil {
+			return err
+		}
+		if format.Export == nil {
+			return fmt.Errorf("format %q can only be imported; use one of %s", format.Name, strings.Join(exchange.ExportNames(), ", "))
+		}
+
+		var filter exchange.Filter
+		if filter.From, err = parseFilterDate("--from", exportFrom); err != nil {
+			return err
+		}
+		if filter.To, err = parseFilterDate("--to", exportTo); err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("completed") {
+			filter.Completed = &exportCompleted
+		}
+		if cmd.Flags().Changed("recurring") {
+			filter.Recurring = &exportRecurring
+		}
+
+		if exportEncrypt && exportOutput == "" {
+			return fmt.Errorf("--encrypt needs --output")
+		}
+
+		var all []*todo.Task
+		if exportView == "" {
+			all, err = store.All()
+		} else {
+			view, ok := exportViews[exportView]
+			if !ok {
+				return fmt.Errorf("unknown view %q (use past, today or future)", exportView)
+			}
+			all, err = store.List(view)
+		}
+		if err != nil {
+			return err
+		}
+		tasks := filter.Apply(all)
+
+		var buf bytes.Buffer
+		if err := format.Export(&buf, tasks); err != nil {
+			return fmt.Errorf("failed to export tasks: %w", err)
+		}
+
+		if exportOutput == "" {
+			_, err := os.Stdout.Write(buf.Bytes())
+			return err
+		}
+
+		data := buf.Bytes()
+		if exportEncrypt {
+			passphrase, err := readPassphrase(cmd, true)
+			if err != nil {
+				return err
+			}
+			if data, err = encryption.Encrypt(data, passphrase); err != nil {
+				return err
+			}
+		}
+		if err := os.WriteFile(exportOutput, data, 0o600); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+
+		fmt.Printf("✓ Exported %d tasks to %s\n", len(tasks), exportOutput)
+		return nil
+	},
+}
+
+// parseFilterDate parses a YYYY-MM-DD flag value; an empty value is the zero
+// time, which doesn't filter.
+func parseFilterDate(flag, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s date (use YYYY-MM-DD): %w", flag, err)
+	}
+	return date, nil
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "json", "export format ("+strings.Join(exchange.ExportNames(), ", ")+")")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "file to write (default: standard output)")
+	exportCmd.Flags().StringVar(&exportFrom, "from", "", "only tasks on or after this date (YYYY-MM-DD)")
+	exportCmd.Flags().StringVar(&exportTo, "to", "", "only tasks on or before this date (YYYY-MM-DD)")
+	exportCmd.Flags().BoolVar(&exportCompleted, "completed", false, "only completed tasks (--completed=false: only open tasks)")
+	exportCmd.Flags().BoolVar(&exportRecurring, "recurring", false, "only recurring tasks (--recurring=false: only one-off tasks)")
+	exportCmd.Flags().StringVar(&exportView, "view", "", "only the tasks a view shows: past, today or future")
+	exportCmd.Flags().BoolVar(&exportEncrypt, "encrypt", false, "encrypt the export with a passphrase")
+	rootCmd.AddCommand(exportCmd)
+}
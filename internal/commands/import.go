@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/johnmirolha/facienda/internal/encryption"
+	"github.com/johnmirolha/facienda/internal/exchange"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importFormat string
+	importDryRun bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import tasks from a file",
+	Long: `Import tasks from a JSON, CSV, todo.txt, iCalendar (.ics), Markdown
+checklist or Org-mode file, such as one written by export.
+Use - to read from standard input.
+
+The format is taken from the file extension unless --format is given. Each
+task is validated like the add command would: rows with an empty title, an
+invalid date or time, or a recurrence that can't be parsed are rejected and
+reported, and the rest are imported. Recurrences may be given as exported
+("weekly:monday") or as the add command takes them ("every monday").
+
+Tasks with a UID, such as calendar entries, update the existing task with
+that UID. Otherwise a task with the same title on the same date as an
+existing task is treated as a duplicate and skipped, so importing the same
+file twice doesn't double its tasks. If the imported task is completed and
+the existing one isn't, the existing task is completed, so ticking items off
+in a notes app and importing the note again marks them done. Use --dry-run
+to see what would be imported.
+
+Encrypted exports are recognised and decrypted with the passphrase from
+FACIENDA_PASSPHRASE, or asked for.
+
+CSV files need a header row with at least the title and date columns.
+todo.txt tasks are scheduled on their due: date; rec:1w and rec:1m become
+weekly and monthly recurrences on that date's weekday or day. iCalendar
+files are read for their VTODOs, with RRULEs mapped to recurrences where
+facienda has an equivalent. Markdown checklist items ("- [ ] ...") are
+scheduled on their 📅 date or the date heading they are under, with 🔁
+recurrences written as for --recur. Org-mode headlines with a TODO keyword
+are scheduled on their SCHEDULED (or DEADLINE) date, with +1w and +1m
+repeaters read as weekly and monthly recurrences.
+
+Taskwarrior exports (task export > tasks.json) and Todoist project CSVs can
+be imported with --format taskwarrior or --format todoist. Priorities become
+a pri:A to pri:C title prefix, Taskwarrior projects and tags are added to the
+title, and annotations and Todoist comments become details. Recurrences with
+no facienda equivalent, such as daily ones, are imported as one-off tasks
+and reported as warnings.
+
+Examples:
+  facienda import tasks.json
+  facienda import --dry-run plan.csv
+  facienda import todo.txt
+  facienda import calendar.ics
+  facienda import ~/notes/2025-11-20.md
+  facienda import ~/org/agenda.org
+  facienda import --format csv - < plan.txt
+  task export | facienda import --format taskwarrior -
+  facienda import --format todoist Groceries.csv
+  facienda import tasks.json.enc`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+
+		format, err := importFormatFor(path)
+		if err != nil {
+			return err
+		}
+
+		data, err := readImportFile(cmd, path)
+		if err != nil {
+			return err
+		}
+
+		rows, err := format.Import(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		existing, err := store.All()
+		if err != nil {
+			return err
+		}
+		plan := exchange.NewPlan(rows, existing)
+
+		if !importDryRun {
+			for i, task := range plan.New {
+				if err := store.Create(task); err != nil {
+					return fmt.Errorf("failed to import %q after importing %d tasks: %w", task.Title, i, err)
+				}
+			}
+			for _, task := range plan.Updates {
+				if err := store.Update(task); err != nil {
+					return fmt.Errorf("failed to update task %d: %w", task.ID, err)
+				}
+			}
+		}
+
+		verb := "✓ Imported"
+		if importDryRun {
+			verb = "Would import"
+		}
+		fmt.Printf("%s %d tasks\n", verb, len(plan.New))
+		if len(plan.Updates) > 0 {
+			if importDryRun {
+				fmt.Printf("  Would update %d tasks\n", len(plan.Updates))
+			} else {
+				fmt.Printf("  Updated %d tasks\n", len(plan.Updates))
+			}
+		}
+		if len(plan.Duplicates) > 0 {
+			fmt.Printf("  Skipped %d duplicates\n", len(plan.Duplicates))
+		}
+		if len(plan.Rejected) > 0 {
+			fmt.Printf("  Rejected %d rows:\n", len(plan.Rejected))
+			for _, row := range plan.Rejected {
+				fmt.Printf("    row %d: %v\n", row.Line, row.Err)
+			}
+		}
+		if len(plan.Warnings) > 0 {
+			fmt.Printf("  %d warnings:\n", len(plan.Warnings))
+			for _, row := range plan.Warnings {
+				fmt.Printf("    row %d: %s\n", row.Line, row.Warning)
+			}
+		}
+		return nil
+	},
+}
+
+// importFormatFor returns the --format format, or the one path's extension
+// suggests.
+func importFormatFor(path string) (exchange.Format, error) {
+	if importFormat != "" {
+		return exchange.Lookup(importFormat)
+	}
+	if format, ok := exchange.ForFile(path); ok {
+		return format, nil
+	}
+	return exchange.Format{}, fmt.Errorf("can't tell the format of %s; use --format (%s)", path, strings.Join(exchange.Names(), ", "))
+}
+
+// readImportFile reads path, or standard input for -, decrypting it if it
+// is an encrypted export.
+func readImportFile(cmd *cobra.Command, path string) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+
+	if !encryption.IsEncrypted(data) {
+		return data, nil
+	}
+	passphrase, err := readPassphrase(cmd, false)
+	if err != nil {
+		return nil, err
+	}
+	return encryption.Decrypt(data, passphrase)
+}
+
+func init() {
+	importCmd.Flags().StringVarP(&importFormat, "format", "f", "", "import format ("+strings.Join(exchange.Names(), ", ")+"; default: from the file extension)")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "show what would be imported without changing anything")
+	rootCmd.AddCommand(importCmd)
+}
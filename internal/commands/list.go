@@ -2,47 +2,42 @@ package commands
 
 import (
 	"fmt"
+	"iter"
+	"sort"
 	"time"
 
 	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
 	"github.com/spf13/cobra"
 )
 
+var (
+	listProfiles    []string
+	listAllProfiles bool
+	listUIDs        bool
+)
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List current tasks",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		tasks, err := store.List(storage.FilterCurrent)
-		if err != nil {
-			return err
-		}
-
-		if len(tasks) == 0 {
-			fmt.Println("No tasks for today.")
-			return nil
-		}
-
-		fmt.Printf("Tasks for %s:\n\n", time.Now().Format("2006-01-02"))
-		for _, task := range tasks {
-			status := "[ ]"
-			if task.Completed {
-				status = "[✓]"
+		count := 0
+		for task, err := range listedTasks(storage.FilterCurrent) {
+			if err != nil {
+				return err
 			}
 
-			title := task.Title
-			if task.IsRecurring() {
-				title = fmt.Sprintf("%s ↻", task.Title)
+			if count == 0 {
+				fmt.Printf("Tasks for %s:\n\n", time.Now().Format("2006-01-02"))
 			}
+			count++
 
-			fmt.Printf("%s %d. %s\n", status, task.ID, title)
-			if task.Details != "" {
-				fmt.Printf("   %s\n", task.Details)
-			}
-			if task.IsRecurring() {
-				fmt.Printf("   Recurs: %s\n", task.RecurrencePattern.String())
-			}
+			printTask(task)
 		}
 
+		if count == 0 {
+			fmt.Println("No tasks for today.")
+		}
 		return nil
 	},
 }
@@ -51,95 +46,185 @@ var pastCmd = &cobra.Command{
 	Use:   "past",
 	Short: "View past tasks (timeline)",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		tasks, err := store.List(storage.FilterPast)
+		return printTimeline(storage.FilterPast, "Past tasks:", "No past tasks.")
+	},
+}
+
+var futureCmd = &cobra.Command{
+	Use:   "future",
+	Short: "View future tasks",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return printTimeline(storage.FilterFuture, "Future tasks:", "No future tasks.")
+	},
+}
+
+// printTimeline prints the tasks for filter grouped by date. The past
+// timeline grows every day, so tasks are streamed rather than loaded into
+// memory at once.
+func printTimeline(filter storage.TimeFilter, header, empty string) error {
+	count := 0
+	currentDate := ""
+	for task, err := range listedTasks(filter) {
 		if err != nil {
 			return err
 		}
 
-		if len(tasks) == 0 {
-			fmt.Println("No past tasks.")
-			return nil
+		if count == 0 {
+			fmt.Println(header)
 		}
+		count++
 
-		fmt.Println("Past tasks:")
-		currentDate := ""
-		for _, task := range tasks {
-			taskDate := task.Date.Format("2006-01-02")
-			if taskDate != currentDate {
-				currentDate = taskDate
-				fmt.Printf("\n%s:\n", currentDate)
-			}
+		taskDate := task.Date.Format("2006-01-02")
+		if taskDate != currentDate {
+			currentDate = taskDate
+			fmt.Printf("\n%s:\n", currentDate)
+		}
 
-			status := "[ ]"
-			if task.Completed {
-				status = "[✓]"
-			}
+		printTask(task)
+	}
 
-			title := task.Title
-			if task.IsRecurring() {
-				title = fmt.Sprintf("%s ↻", task.Title)
-			}
+	if count == 0 {
+		fmt.Println(empty)
+	}
+	return nil
+}
 
-			fmt.Printf("%s %d. %s\n", status, task.ID, title)
-			if task.Details != "" {
-				fmt.Printf("   %s\n", task.Details)
-			}
-			if task.IsRecurring() {
-				fmt.Printf("   Recurs: %s\n", task.RecurrencePattern.String())
+// profileTask is a task together with the profile it was read from. The
+// profile is empty unless several profiles are listed at once.
+type profileTask struct {
+	*todo.Task
+	profile string
+}
+
+// listedTasks returns the tasks for filter from the open store or, with
+// --profiles or --all-profiles, from each of those profiles merged into one
+// list in date order.
+func listedTasks(filter storage.TimeFilter) iter.Seq2[profileTask, error] {
+	names := listProfiles
+	if listAllProfiles {
+		names = cfg.Names()
+	}
+
+	if len(names) == 0 {
+		return func(yield func(profileTask, error) bool) {
+			for task, err := range storage.Iterate(store, filter) {
+				if !yield(profileTask{Task: task}, err) {
+					return
+				}
 			}
 		}
+	}
 
-		return nil
-	},
+	return func(yield func(profileTask, error) bool) {
+		tasks, err := listProfileTasks(names, filter)
+		if err != nil {
+			yield(profileTask{}, err)
+			return
+		}
+		for _, task := range tasks {
+			if !yield(task, nil) {
+				return
+			}
+		}
+	}
 }
 
-var futureCmd = &cobra.Command{
-	Use:   "future",
-	Short: "View future tasks",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		tasks, err := store.List(storage.FilterFuture)
+// listProfileTasks opens each named profile in turn and merges their tasks
+// for filter by date, time of day and creation time.
+func listProfileTasks(names []string, filter storage.TimeFilter) ([]profileTask, error) {
+	var tasks []profileTask
+	for _, name := range names {
+		profile, err := cfg.Get(name)
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		s, err := storage.Open(profile.Backend, profile.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open profile %q: %w", name, err)
+		}
+		listed, err := s.List(filter)
+		s.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list profile %q: %w", name, err)
 		}
 
-		if len(tasks) == 0 {
-			fmt.Println("No future tasks.")
-			return nil
+		for _, task := range listed {
+			tasks = append(tasks, profileTask{Task: task, profile: name})
 		}
+	}
 
-		fmt.Println("Future tasks:")
-		currentDate := ""
-		for _, task := range tasks {
-			taskDate := task.Date.Format("2006-01-02")
-			if taskDate != currentDate {
-				currentDate = taskDate
-				fmt.Printf("\n%s:\n", currentDate)
-			}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if ka, kb := scheduleKey(a.Task), scheduleKey(b.Task); ka != kb {
+			return ka < kb
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return tasks, nil
+}
 
-			status := "[ ]"
-			if task.Completed {
-				status = "[✓]"
-			}
+// scheduleKey sorts tasks by calendar date, with whole-day tasks before
+// tasks at a time of day.
+func scheduleKey(task *todo.Task) string {
+	key := task.Date.Format("2006-01-02")
+	if task.HasTime() {
+		key += " " + task.Date.Format("15:04")
+	}
+	return key
+}
 
-			title := task.Title
-			if task.IsRecurring() {
-				title = fmt.Sprintf("%s ↻", task.Title)
-			}
+func printTask(task profileTask) {
+	status := "[ ]"
+	if task.Completed {
+		status = "[✓]"
+	}
+
+	title := displayTitle(task.Task)
+	if task.profile != "" {
+		title = fmt.Sprintf("[%s] %s", task.profile, title)
+	}
+
+	fmt.Printf("%s %d. %s\n", status, task.ID, title)
+	if task.Details != "" {
+		fmt.Printf("   %s\n", task.Details)
+	}
+	if task.IsRecurring() {
+		fmt.Printf("   Recurs: %s\n", task.RecurrencePattern.String())
+	}
+	if task.Assignee != "" {
+		fmt.Printf("   Assigned to: %s\n", task.Assignee)
+	}
+	if listUIDs {
+		fmt.Printf("   UID: %s\n", task.StableUID())
+	}
+}
 
-			fmt.Printf("%s %d. %s\n", status, task.ID, title)
-			if task.Details != "" {
-				fmt.Printf("   %s\n", task.Details)
-			}
-			if task.IsRecurring() {
-				fmt.Printf("   Recurs: %s\n", task.RecurrencePattern.String())
-			}
+// displayTitle returns the task title with its time of day, if any, and a
+// marker for recurring tasks.
+func displayTitle(task *todo.Task) string {
+	title := task.Title
+	if task.HasTime() {
+		layout := "15:04"
+		if task.Date.Location() != time.Local {
+			layout = "15:04 MST"
 		}
-
-		return nil
-	},
+		title = fmt.Sprintf("%s (%s)", title, task.Date.Format(layout))
+	}
+	if task.IsRecurring() {
+		title = fmt.Sprintf("%s ↻", title)
+	}
+	return title
 }
 
 func init() {
+	for _, cmd := range []*cobra.Command{listCmd, pastCmd, futureCmd} {
+		cmd.Flags().StringSliceVar(&listProfiles, "profiles", nil, "list tasks from these profiles (comma-separated), showing the profile per task")
+		cmd.Flags().BoolVar(&listAllProfiles, "all-profiles", false, "list tasks from every profile")
+		cmd.MarkFlagsMutuallyExclusive("profiles", "all-profiles")
+		cmd.Flags().BoolVar(&listUIDs, "uids", false, "show each task's UID")
+	}
+
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(pastCmd)
 	rootCmd.AddCommand(futureCmd)
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// passphraseEnv names the environment variable that supplies the passphrase
+// for encrypted backups, so scheduled jobs can run without a prompt.
+const passphraseEnv = "FACIENDA_PASSPHRASE"
+
+// readPassphrase returns the passphrase from FACIENDA_PASSPHRASE, or asks
+// for it. On a terminal the input is not echoed, and when twice is set it
+// has to be typed a second time to catch typos. Otherwise it is read as a
+// line from the command's input.
+func readPassphrase(cmd *cobra.Command, twice bool) (string, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	stdin, ok := cmd.InOrStdin().(*os.File)
+	if !ok || !term.IsTerminal(int(stdin.Fd())) {
+		line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		passphrase := strings.TrimRight(line, "\r\n")
+		if passphrase == "" {
+			return "", fmt.Errorf("no passphrase given (set %s or type it on stdin)", passphraseEnv)
+		}
+		return passphrase, nil
+	}
+
+	prompt := func(label string) (string, error) {
+		fmt.Fprint(cmd.ErrOrStderr(), label)
+		input, err := term.ReadPassword(int(stdin.Fd()))
+		fmt.Fprintln(cmd.ErrOrStderr())
+		return string(input), err
+	}
+
+	passphrase, err := prompt("Passphrase: ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+	if twice {
+		again, err := prompt("Repeat passphrase: ")
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
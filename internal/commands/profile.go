@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/johnmirolha/facienda/internal/config"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	profileAddPath    string
+	profileAddBackend string
+	profileAddUse     bool
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named task databases",
+	Long: `Profiles give task databases a name, so you can switch between them
+instead of passing --db and --backend every time.
+
+The profile used by a command is, in order: the --profile flag, the
+FACIENDA_PROFILE environment variable, then the current profile set with
+"facienda profile use". Profiles are stored in ~/.facienda.json (see --config).
+
+Examples:
+  facienda profile add work --path ~/work.db
+  facienda profile add personal --path ~/notes/tasks --backend markdown
+  facienda profile use work
+  FACIENDA_PROFILE=personal facienda list
+  facienda list --profiles work,personal`,
+	// Profile commands only touch the configuration file, so they don't
+	// open a task database.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		cfg, err = config.Load(configPath)
+		return err
+	},
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names := cfg.Names()
+		if len(names) == 0 {
+			fmt.Println("No profiles defined. Add one with 'facienda profile add'.")
+			return nil
+		}
+
+		active := cfg.Active(profileName)
+		for _, name := range names {
+			profile, _ := cfg.Get(name)
+			marker := " "
+			if name == active {
+				marker = "*"
+			}
+			backendName := profile.Backend
+			if backendName == "" {
+				backendName = storage.BackendSQLite
+			}
+			fmt.Printf("%s %s  %s (%s)\n", marker, name, profile.Path, backendName)
+		}
+
+		if env := os.Getenv(config.ProfileEnv); env != "" && profileName == "" {
+			fmt.Printf("\n%s is set to %q.\n", config.ProfileEnv, env)
+		}
+		return nil
+	},
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add [name]",
+	Short: "Define a profile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		switch profileAddBackend {
+		case storage.BackendSQLite, storage.BackendMarkdown:
+		default:
+			return fmt.Errorf("unknown storage backend %q (use %q or %q)",
+				profileAddBackend, storage.BackendSQLite, storage.BackendMarkdown)
+		}
+
+		profile := config.Profile{Path: profileAddPath}
+		if profileAddBackend != storage.BackendSQLite {
+			profile.Backend = profileAddBackend
+		}
+		if err := cfg.Add(name, profile); err != nil {
+			return err
+		}
+		// The first profile becomes the current one, so that adding it is
+		// enough to start using it.
+		if profileAddUse || (cfg.Current == "" && len(cfg.Profiles) == 1) {
+			cfg.Use(name)
+		}
+		if err := cfg.Save(configPath); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Profile %q added\n", name)
+		if cfg.Current == name {
+			fmt.Printf("  Now using profile %q\n", name)
+		}
+		return nil
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use [name]",
+	Short: "Switch the current profile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cfg.Use(args[0]); err != nil {
+			return err
+		}
+		if err := cfg.Save(configPath); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Now using profile %q\n", args[0])
+		if env := os.Getenv(config.ProfileEnv); env != "" && env != args[0] {
+			fmt.Printf("  Note: %s=%s overrides it in this shell.\n", config.ProfileEnv, env)
+		}
+		return nil
+	},
+}
+
+func init() {
+	profileAddCmd.Flags().StringVar(&profileAddPath, "path", "", "database file, or task directory for the markdown backend")
+	profileAddCmd.Flags().StringVar(&profileAddBackend, "backend", storage.BackendSQLite, "storage backend (sqlite or markdown)")
+	profileAddCmd.Flags().BoolVar(&profileAddUse, "use", false, "make it the current profile")
+	profileAddCmd.MarkFlagRequired("path")
+
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileAddCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	rootCmd.AddCommand(profileCmd)
+}
@@ -4,24 +4,61 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/johnmirolha/facienda/internal/backup"
+	"github.com/johnmirolha/facienda/internal/config"
+	"github.com/johnmirolha/facienda/internal/events"
 	"github.com/johnmirolha/facienda/internal/storage"
 	"github.com/spf13/cobra"
 )
 
 var (
-	dbPath  string
-	store   storage.Storage
-	rootCmd = &cobra.Command{
+	dbPath              string
+	backend             string
+	backupDir           string
+	backupBeforeMigrate bool
+	hooksDir            string
+	configPath          string
+	profileName         string
+	store               storage.Storage
+	rootCmd             = &cobra.Command{
 		Use:   "facienda",
 		Short: "A console-based TODO application",
 		Long:  "Facienda is a simple and efficient console TODO app for managing your tasks.",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			var err error
-			store, err = storage.NewSQLiteStorage(dbPath)
+			if err := resolveDB(cmd); err != nil {
+				return err
+			}
+			if backupDir == "" {
+				backupDir = backup.DefaultDir(dbPath)
+			}
+
+			var opts []storage.SQLiteOption
+			if cmd != doctorCmd {
+				// doctor lists unreadable tasks itself.
+				opts = append(opts, storage.WithUnreadableTasks(func(err error) {
+					fmt.Fprintf(os.Stderr, "Warning: skipped unreadable %v (run \"facienda doctor\")\n", err)
+				}))
+			}
+			if backupBeforeMigrate {
+				opts = append(opts, storage.WithMigrationBackup(func() (string, error) {
+					if err := os.MkdirAll(backupDir, 0o755); err != nil {
+						return "", err
+					}
+					return backup.NewPath(backupDir, dbPath, backup.KindPreMigrate, time.Now()), nil
+				}))
+			}
+
+			opened, err := storage.Open(backend, dbPath, opts...)
 			if err != nil {
 				return fmt.Errorf("failed to initialize storage: %w", err)
 			}
+			store = events.NewStore(opened, &bus)
+
+			bus.Subscribe(events.ExecHooks(hooksDir, os.Stderr, func(err error) {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}))
 			return nil
 		},
 		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
@@ -33,14 +70,83 @@ var (
 	}
 )
 
+// bus receives an event for every change commands make through store.
+var bus events.Bus
+
+var (
+	// cfg is the configuration file, loaded before every command.
+	cfg *config.Config
+
+	// activeProfile is the profile the command runs against, or empty when
+	// --db and --backend are used directly.
+	activeProfile string
+)
+
+// resolveDB sets dbPath and backend to the task database the command works
+// on, from the flags and the active profile.
+func resolveDB(cmd *cobra.Command) error {
+	if err := applyProfile(cmd); err != nil {
+		return err
+	}
+	if backend == storage.BackendMarkdown && !cmd.Flags().Changed("db") && activeProfile == "" {
+		dbPath = defaultTaskDir
+	}
+	return nil
+}
+
+// applyProfile loads the configuration file and, if a profile is selected,
+// takes the database path and backend from it. Flags given explicitly on
+// the command line still win, and --db replaces the profile altogether.
+func applyProfile(cmd *cobra.Command) error {
+	var err error
+	if cfg, err = config.Load(configPath); err != nil {
+		return err
+	}
+
+	activeProfile = cfg.Active(profileName)
+	if activeProfile == "" {
+		return nil
+	}
+
+	profile, err := cfg.Get(activeProfile)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("db") {
+		// Another database, so the profile's backend needn't fit it.
+		return nil
+	}
+	dbPath = profile.Path
+	if !cmd.Flags().Changed("backend") {
+		backend = profile.Backend
+		if backend == "" {
+			backend = storage.BackendSQLite
+		}
+	}
+	return nil
+}
+
+var (
+	defaultTaskDir  string
+	defaultHooksDir string
+)
+
 func init() {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		home = "."
 	}
 	defaultDB := filepath.Join(home, ".facienda.db")
+	defaultTaskDir = filepath.Join(home, ".facienda-tasks")
+	defaultHooksDir = filepath.Join(home, ".facienda-hooks")
 
-	rootCmd.PersistentFlags().StringVar(&dbPath, "db", defaultDB, "path to SQLite database file")
+	rootCmd.PersistentFlags().StringVar(&dbPath, "db", defaultDB, "path to SQLite database file, or task directory for the markdown backend")
+	rootCmd.PersistentFlags().StringVar(&backend, "backend", storage.BackendSQLite, "storage backend (sqlite or markdown)")
+	rootCmd.PersistentFlags().StringVar(&backupDir, "backup-dir", "", "directory for database backups (default: .facienda-backups next to the database)")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", config.DefaultPath(), "configuration file defining profiles")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "profile to use (default: $FACIENDA_PROFILE or the current profile)")
+	rootCmd.PersistentFlags().StringVar(&hooksDir, "hooks-dir", defaultHooksDir, "directory of executables run when tasks change")
+	rootCmd.PersistentFlags().BoolVar(&backupBeforeMigrate, "backup-before-migrate", false, "back up the database before upgrading its schema")
 }
 
 func Execute() error {
@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/api"
+	"github.com/johnmirolha/facienda/internal/auth"
+	"github.com/johnmirolha/facienda/internal/caldav"
+	"github.com/johnmirolha/facienda/internal/web"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr   string
+	serveAPI    bool
+	serveCalDAV bool
+	serveWeb    bool
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve tasks over the network",
+	Long: `Serve the task database over HTTP until interrupted.
+
+With --web, a web interface at the server's address shows today's, past
+and future tasks, adds tasks and completes and skips them, for people who
+would rather not use the command line. It needs no internet connection,
+and serves the API (see --api) as well, which it works through.
+
+With --api, tasks can be listed, created, changed, completed and skipped
+through a JSON API under /api/, for scripts and dashboards. The API is
+described by the OpenAPI document at /api/openapi.json.
+
+With --caldav, tasks are served over CalDAV as one task list, for phones
+and calendar apps. Point the app at http://<address>/ and it finds the list
+at /caldav/tasks/. Recurring tasks are repeating to-dos, and ticking off a
+task in the app completes it as the complete command would, scheduling the
+next occurrence of a recurring task.
+
+Once users are defined with "facienda user add", every request needs an
+API token of one of them (see "facienda token"). Admins see every task;
+other users see the tasks they own or are assigned to. Without users there
+is no authentication.
+
+The server listens on localhost only, unless --addr says otherwise. Use
+--addr :5232 to reach it from other devices on the network, after defining
+users or on a network you trust. Tokens travel in the clear over plain
+HTTP, so put the server behind a TLS proxy when it is reachable from
+outside.
+
+Examples:
+  facienda serve --web
+  facienda serve --api
+  facienda serve --caldav
+  facienda serve --api --caldav --addr :5232`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !serveWeb && !serveAPI && !serveCalDAV {
+			return fmt.Errorf("nothing to serve: pass --web, --api, --caldav or several of them")
+		}
+
+		mux := http.NewServeMux()
+		if serveWeb {
+			ui, err := web.NewHandler("/", "/api/")
+			if err != nil {
+				return err
+			}
+			mux.Handle("/", ui)
+			serveAPI = true
+		}
+		if serveAPI {
+			mux.Handle("/api/", api.NewHandler(store, "/api/"))
+		}
+		if serveCalDAV {
+			dav := caldav.NewHandler(store, "/caldav/")
+			mux.Handle("/caldav/", dav)
+			mux.Handle("/.well-known/caldav", dav)
+			// CalDAV clients given the server's address look there first;
+			// browsers get the web interface if there is one.
+			if serveWeb {
+				mux.Handle("PROPFIND /{$}", http.RedirectHandler("/caldav/", http.StatusFound))
+			} else {
+				mux.Handle("/{$}", http.RedirectHandler("/caldav/", http.StatusFound))
+			}
+		}
+
+		// Whether the server authenticates is decided when it starts, so
+		// that removing the users file can't open it up. Changes to users
+		// and tokens are picked up while it runs.
+		var handler http.Handler = mux
+		usersFile := auth.DefaultPath(dbPath)
+		accounts, err := auth.Load(usersFile)
+		if err != nil {
+			return err
+		}
+		if len(accounts.Users) > 0 {
+			authenticator, err := auth.NewAuthenticator(usersFile)
+			if err != nil {
+				return err
+			}
+			handler = authenticator.Middleware(mux)
+		}
+
+		listener, err := net.Listen("tcp", serveAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", serveAddr, err)
+		}
+		server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdown)
+		}()
+
+		if serveWeb {
+			fmt.Printf("✓ Serving the web interface at http://%s/\n", listener.Addr())
+		}
+		if serveAPI {
+			fmt.Printf("✓ Serving the API at http://%s/api/\n", listener.Addr())
+		}
+		if serveCalDAV {
+			fmt.Printf("✓ Serving CalDAV at http://%s/caldav/\n", listener.Addr())
+		}
+		if len(accounts.Users) == 0 {
+			fmt.Println("  No users defined: anyone who can reach the server can change every task.")
+		}
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:5232", "address to listen on")
+	serveCmd.Flags().BoolVar(&serveWeb, "web", false, "serve the web interface (and the JSON API)")
+	serveCmd.Flags().BoolVar(&serveAPI, "api", false, "serve the JSON API")
+	serveCmd.Flags().BoolVar(&serveCalDAV, "caldav", false, "serve tasks over CalDAV")
+	rootCmd.AddCommand(serveCmd)
+}
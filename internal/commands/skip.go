@@ -2,13 +2,13 @@ package commands
 
 import (
 	"fmt"
-	"strconv"
 
+	"github.com/johnmirolha/facienda/internal/todo"
 	"github.com/spf13/cobra"
 )
 
 var skipCmd = &cobra.Command{
-	Use:   "skip [task-id]",
+	Use:   "skip [task-id|uid]",
 	Short: "Skip a task",
 	Long: `Skip a task without marking it as completed.
 
@@ -16,59 +16,28 @@ If the task is recurring, this will automatically create the next occurrence.
 Skipped tasks won't appear in the task list.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		id, err := strconv.ParseInt(args[0], 10, 64)
-		if err != nil {
-			return fmt.Errorf("invalid task ID: %w", err)
-		}
-
-		task, err := store.GetByID(id)
+		task, err := findTask(args[0])
 		if err != nil {
 			return err
 		}
 
-		// Check if this is a recurring task
-		isRecurring := task.IsRecurring()
-
-		task.Skip()
-		if err := store.Update(task); err != nil {
+		next, err := skipTask(task)
+		if err != nil {
 			return err
 		}
 
-		fmt.Printf("⊘ Task %d skipped\n", id)
-
-		// If recurring, generate the next instance
-		if isRecurring {
-			nextTask, err := task.GenerateNextInstance()
-			if err != nil {
-				return fmt.Errorf("failed to generate next instance: %w", err)
-			}
-
-			if nextTask != nil {
-				if err := store.Create(nextTask); err != nil {
-					return fmt.Errorf("failed to create next instance: %w", err)
-				}
-
-				fmt.Printf("✓ Next occurrence created (ID: %d) for %s\n",
-					nextTask.ID,
-					nextTask.Date.Format("Mon, Jan 2, 2006"))
-			}
-		}
-
+		fmt.Printf("⊘ Task %d skipped\n", task.ID)
+		printNextInstance(next)
 		return nil
 	},
 }
 
 var unskipCmd = &cobra.Command{
-	Use:   "unskip [task-id]",
+	Use:   "unskip [task-id|uid]",
 	Short: "Unskip a task",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		id, err := strconv.ParseInt(args[0], 10, 64)
-		if err != nil {
-			return fmt.Errorf("invalid task ID: %w", err)
-		}
-
-		task, err := store.GetByID(id)
+		task, err := findTask(args[0])
 		if err != nil {
 			return err
 		}
@@ -78,11 +47,21 @@ var unskipCmd = &cobra.Command{
 			return err
 		}
 
-		fmt.Printf("✓ Task %d unskipped\n", id)
+		fmt.Printf("✓ Task %d unskipped\n", task.ID)
 		return nil
 	},
 }
 
+// skipTask skips task and, if it is recurring, creates its next
+// occurrence, which it returns.
+func skipTask(task *todo.Task) (*todo.Task, error) {
+	task.Skip()
+	if err := store.Update(task); err != nil {
+		return nil, err
+	}
+	return createNextInstance(task)
+}
+
 func init() {
 	rootCmd.AddCommand(skipCmd)
 	rootCmd.AddCommand(unskipCmd)
@@ -0,0 +1,220 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/syncer"
+	"github.com/johnmirolha/facienda/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncDryRun      bool
+	syncInteractive bool
+	syncPrefer      string
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync <database|directory>",
+	Short: "Sync tasks with another task database",
+	Long: `Merge the tasks of another facienda database into this one and the other
+way round, so both end up with every change made on either.
+
+The other store is a SQLite database file, or a task directory of the
+markdown backend. Tasks only one side has are copied to the other, unless
+the other side deleted them, in which case they are deleted. A task changed
+on one side since the last sync is updated on the other.
+
+A task changed on both sides, or changed on one and deleted on the other, is
+a conflict. By default conflicts are only reported and left as they are; the
+rest of the sync still happens. Use --interactive to choose which version to
+keep for each conflict, or --prefer to keep the local, remote or newer
+version of every one. The first sync of two databases has no earlier sync to
+compare with, so every task that differs between them is a conflict.
+
+To sync through a shared git repository instead, see 'facienda sync git'.
+
+Examples:
+  facienda sync /mnt/usb/facienda.db
+  facienda sync --dry-run ~/Dropbox/facienda.db
+  facienda sync --interactive ~/Dropbox/facienda.db
+  facienda sync --prefer newer ~/Dropbox/facienda.db
+  facienda sync ~/Sync/facienda-tasks`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if syncInteractive && syncPrefer != "" {
+			return fmt.Errorf("--interactive and --prefer can't be used together")
+		}
+		switch syncPrefer {
+		case "", "local", "remote", "newer":
+		default:
+			return fmt.Errorf("invalid --prefer %q (use local, remote or newer)", syncPrefer)
+		}
+
+		remote, err := openSyncPeer(args[0])
+		if err != nil {
+			return err
+		}
+		defer remote.Close()
+
+		plan, err := syncer.NewPlan(store, remote)
+		if err != nil {
+			return err
+		}
+
+		input := bufio.NewReader(cmd.InOrStdin())
+		for _, c := range plan.Conflicts {
+			fmt.Printf("\n✗ Conflict: %q\n", conflictTitle(c))
+			fmt.Printf("  local:  %s\n", describeVersion(c.Local, c.DeletedAt))
+			fmt.Printf("  remote: %s\n", describeVersion(c.Remote, c.DeletedAt))
+
+			switch {
+			case syncPrefer == "local":
+				plan.Resolve(c, syncer.Local)
+			case syncPrefer == "remote":
+				plan.Resolve(c, syncer.Remote)
+			case syncPrefer == "newer":
+				plan.Resolve(c, c.Newer())
+			case syncInteractive:
+				side, ok, err := chooseSide(input, "  Keep [l]ocal, [r]emote or [s]kip? ")
+				if err != nil {
+					return err
+				}
+				if ok {
+					plan.Resolve(c, side)
+				}
+			}
+		}
+		if len(plan.Conflicts) > 0 {
+			fmt.Println()
+		}
+
+		if !syncDryRun {
+			if err := plan.Apply(); err != nil {
+				return err
+			}
+		}
+
+		if syncDryRun {
+			fmt.Printf("Would sync with %s\n", args[0])
+		} else {
+			fmt.Printf("✓ Synced with %s\n", args[0])
+		}
+		fmt.Printf("  Local:  %s\n", summarizeChanges(plan.Local))
+		fmt.Printf("  Remote: %s\n", summarizeChanges(plan.Remote))
+
+		unresolved := len(plan.Unresolved())
+		if unresolved == 0 {
+			return nil
+		}
+		if syncPrefer == "" && !syncInteractive {
+			fmt.Println("\nRun 'facienda sync --interactive' to choose which versions to keep, or use --prefer.")
+		}
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d conflict(s) unresolved", unresolved)
+	},
+}
+
+// openSyncPeer opens the store at path: a task directory with the markdown
+// backend, and a file with the SQLite backend. It has to exist already, so
+// that a mistyped path doesn't create an empty store.
+func openSyncPeer(path string) (storage.Storage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	own, _ := filepath.Abs(dbPath)
+	if abs, _ := filepath.Abs(path); abs == own {
+		return nil, fmt.Errorf("%s is the current database; give the path of the other one", path)
+	}
+
+	peerBackend := storage.BackendSQLite
+	if info.IsDir() {
+		peerBackend = storage.BackendMarkdown
+	}
+	peer, err := storage.Open(peerBackend, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return peer, nil
+}
+
+// chooseSide asks which version of a conflict to keep. Anything but local
+// or remote, including end of input, skips the conflict.
+func chooseSide(input *bufio.Reader, prompt string) (syncer.Side, bool, error) {
+	fmt.Print(prompt)
+
+	answer, err := input.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, false, err
+	}
+	if err == io.EOF {
+		fmt.Println()
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "l", "local":
+		return syncer.Local, true, nil
+	case "r", "remote":
+		return syncer.Remote, true, nil
+	default:
+		return 0, false, nil
+	}
+}
+
+func conflictTitle(c syncer.Conflict) string {
+	if c.Local != nil {
+		return c.Local.Title
+	}
+	return c.Remote.Title
+}
+
+// describeVersion summarises one side of a conflict: the task as that side
+// has it, or when it was deleted.
+func describeVersion(task *todo.Task, deletedAt time.Time) string {
+	if task == nil {
+		return "deleted " + deletedAt.Local().Format("2006-01-02 15:04")
+	}
+
+	date, clock, zone := storage.SplitSchedule(task.Date)
+	schedule := strings.Join(strings.Fields(date+" "+clock+" "+zone), " ")
+	status := "open"
+	switch {
+	case task.Skipped:
+		status = "skipped"
+	case task.Completed:
+		status = "completed"
+	}
+	description := fmt.Sprintf("changed %s: %q on %s, %s", task.UpdatedAt.Local().Format("2006-01-02 15:04"), task.Title, schedule, status)
+	if task.IsRecurring() {
+		description += ", " + task.RecurrencePattern.String()
+	}
+	if task.Details != "" {
+		description += fmt.Sprintf(", details %q", task.Details)
+	}
+	return description
+}
+
+func summarizeChanges(changes []syncer.Change) string {
+	var counts [3]int
+	for _, change := range changes {
+		counts[change.Action]++
+	}
+	return fmt.Sprintf("%d added, %d updated, %d deleted",
+		counts[syncer.Create], counts[syncer.Update], counts[syncer.Delete])
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "show what would change without changing either store")
+	syncCmd.Flags().BoolVarP(&syncInteractive, "interactive", "i", false, "ask which version to keep for each conflict")
+	syncCmd.Flags().StringVar(&syncPrefer, "prefer", "", "resolve every conflict by keeping the local, remote or newer version")
+	rootCmd.AddCommand(syncCmd)
+}
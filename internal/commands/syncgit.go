@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/johnmirolha/facienda/internal/gitsync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncGitClone  string
+	syncGitBranch string
+)
+
+var syncGitCmd = &cobra.Command{
+	Use:   "git <repository>",
+	Short: "Sync tasks through a git repository",
+	Long: `Share tasks with everyone who syncs with the same git repository.
+
+facienda keeps a clone of the repository next to the database, with one
+file per task under tasks/ in the format of the markdown backend. A sync
+writes the changes made to the database since the last sync into the clone
+and commits them, pulls and merges, applies the merged tasks to the
+database, and pushes.
+
+A task changed on two machines is merged field by field: a field only one
+machine changed keeps that change, and a field both changed keeps the later
+one. A task deleted on one machine and changed on another is kept if it was
+changed after it was deleted, and deleted otherwise.
+
+The repository is any URL or path git can push to, and is created on the
+first sync if it is empty. git has to be installed.
+
+Examples:
+  facienda sync git git@github.com:acme/tasks.git
+  facienda sync git /srv/git/tasks.git
+  facienda sync git --branch team-tasks ../shared.git`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to find the facienda executable: %w", err)
+		}
+		clone := syncGitClone
+		if clone == "" {
+			clone = dbPath + "-git"
+		}
+
+		repo, err := gitsync.Open(clone, args[0], syncGitBranch, shellQuote(exe)+" sync git merge-driver")
+		if err != nil {
+			return err
+		}
+		result, err := repo.Sync(store)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Synced with %s\n", args[0])
+		fmt.Printf("  Local:      %s\n", summarizeChanges(result.Imported))
+		fmt.Printf("  Repository: %s\n", summarizeChanges(result.Exported))
+		for _, c := range result.Conflicts {
+			fmt.Printf("  Kept the %s version of %q\n", c.Newer(), conflictTitle(c))
+		}
+		return nil
+	},
+}
+
+// syncGitMergeDriverCmd is the merge driver git runs for the task files in
+// a clone; see gitsync.RunMergeDriver.
+var syncGitMergeDriverCmd = &cobra.Command{
+	Use:    "merge-driver <base> <ours> <theirs> <path>",
+	Short:  "Merge two versions of a task file",
+	Hidden: true,
+	Args:   cobra.ExactArgs(4),
+	// The driver runs inside git and needs no database.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return gitsync.RunMergeDriver(args[0], args[1], args[2], args[3])
+	},
+}
+
+// shellQuote quotes s for the shell git runs merge drivers with.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func init() {
+	syncGitCmd.Flags().StringVar(&syncGitClone, "clone", "", "directory of the clone (default: the database path followed by -git)")
+	syncGitCmd.Flags().StringVar(&syncGitBranch, "branch", "main", "branch the tasks are kept on")
+	syncGitCmd.AddCommand(syncGitMergeDriverCmd)
+	syncCmd.AddCommand(syncGitCmd)
+}
@@ -0,0 +1,19 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// findTask returns the task ref refers to, a task ID or UID; see
+// storage.FindTask.
+func findTask(ref string) (*todo.Task, error) {
+	task, err := storage.FindTask(store, ref)
+	if errors.Is(err, todo.ErrNotFound) {
+		return nil, fmt.Errorf("no task ID or UID matches %q", ref)
+	}
+	return task, err
+}
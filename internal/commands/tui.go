@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"errors"
+	"os"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+	"github.com/johnmirolha/facienda/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Work through tasks in a full-screen interface",
+	Long: `Show today's, past and future tasks in a full-screen interface and
+work through them with the keyboard:
+
+  ↑ ↓ or j k        move the cursor
+  ← → or tab, 1-3   switch between the today, past and future panes
+  space or c        complete the task, or mark a completed one incomplete
+  s                 skip the task
+  e                 edit the task's title and details
+  a                 add a task, with a date and time or a recurrence such
+                    as "every monday" (tab moves between the fields)
+  r                 reload
+  q                 quit
+
+Completing and skipping a recurring task creates its next occurrence, as
+the complete and skip commands do. Changes made elsewhere, by another
+facienda or a sync, show up within a second.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return tui.Run(tui.Config{
+			Store:    store,
+			Add:      addFromForm,
+			Complete: completeTask,
+			Skip:     skipTask,
+		}, os.Stdin, os.Stdout)
+	},
+}
+
+// addFromForm adds a task from the fields of the interface's add form as
+// the add command would from its flags. A recurring task starts on its
+// next occurrence, whatever the date field says.
+func addFromForm(title, date, at, recur string) (*todo.Task, error) {
+	if at != "" && recur != "" {
+		return nil, errors.New("a recurring task can't have a time of day")
+	}
+	task, err := newTask(title, "", date, at, recur)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Create(task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+}
@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/johnmirolha/facienda/internal/auth"
+	"github.com/spf13/cobra"
+)
+
+var (
+	userAddAdmin  bool
+	tokenName     string
+	usersPath     string
+	usersAccounts *auth.Accounts
+)
+
+// loadUsers reads the users file of the task database. User and token
+// commands only touch that file, so they don't open the database.
+func loadUsers(cmd *cobra.Command, args []string) error {
+	if err := resolveDB(cmd); err != nil {
+		return err
+	}
+	usersPath = auth.DefaultPath(dbPath)
+	var err error
+	usersAccounts, err = auth.Load(usersPath)
+	return err
+}
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage the users of the server",
+	Long: `Users sign in to "facienda serve" with API tokens (see "facienda token").
+Once there is a user, the server turns away requests without a valid token.
+
+Admins see and change every task. Other users see the tasks they own or
+are assigned to, and tasks they create through the server are their own.
+Users are kept in a file next to the task database, ending in -users.json.
+
+Examples:
+  facienda user add alice --admin
+  facienda user add bob
+  facienda token create bob --name phone
+  facienda edit 3 --assignee bob`,
+	PersistentPreRunE: loadUsers,
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List users",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(usersAccounts.Users) == 0 {
+			fmt.Println("No users defined. Add one with 'facienda user add'.")
+			return nil
+		}
+		for _, user := range usersAccounts.Users {
+			tokens := 0
+			for _, token := range usersAccounts.Tokens {
+				if token.User == user.Name {
+					tokens++
+				}
+			}
+			role := "user"
+			if user.Admin {
+				role = "admin"
+			}
+			fmt.Printf("%s  %s, %d token(s)\n", user.Name, role, tokens)
+		}
+		return nil
+	},
+}
+
+var userAddCmd = &cobra.Command{
+	Use:   "add [name]",
+	Short: "Add a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := usersAccounts.AddUser(args[0], userAddAdmin); err != nil {
+			return err
+		}
+		if err := usersAccounts.Save(usersPath); err != nil {
+			return err
+		}
+		fmt.Printf("✓ User %q added\n", args[0])
+		fmt.Printf("  Create a token with 'facienda token create %s'\n", args[0])
+		return nil
+	},
+}
+
+var userRemoveCmd = &cobra.Command{
+	Use:   "remove [name]",
+	Short: "Remove a user and revoke their tokens",
+	Long: `Remove a user and revoke their tokens. The tasks they own or are
+assigned to are kept, and admins still see them.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := usersAccounts.RemoveUser(args[0]); err != nil {
+			return err
+		}
+		if err := usersAccounts.Save(usersPath); err != nil {
+			return err
+		}
+		fmt.Printf("✓ User %q removed\n", args[0])
+		return nil
+	},
+}
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens of the server's users",
+	Long: `API tokens sign users in to "facienda serve". Send a token as a bearer
+token, or for CalDAV apps as the password with the user's name.
+
+A token is shown only when it is created; the server keeps a hash of it.
+Revoked tokens stop working right away, also on a running server.
+
+Examples:
+  facienda token create bob --name phone
+  curl -H "Authorization: Bearer fct_..." http://localhost:5232/api/tasks
+  facienda token list
+  facienda token revoke 1a2b3c4d`,
+	PersistentPreRunE: loadUsers,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create [user]",
+	Short: "Create a token for a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, token, err := usersAccounts.CreateToken(args[0], tokenName)
+		if err != nil {
+			return err
+		}
+		if err := usersAccounts.Save(usersPath); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Token %s created for %q\n", token.ID, token.User)
+		fmt.Printf("  %s\n", value)
+		fmt.Println("  Copy it now: it can't be shown again.")
+		return nil
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tokens",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(usersAccounts.Tokens) == 0 {
+			fmt.Println("No tokens. Create one with 'facienda token create'.")
+			return nil
+		}
+		for _, token := range usersAccounts.Tokens {
+			line := fmt.Sprintf("%s  %s  created %s", token.ID, token.User, token.CreatedAt.Local().Format("2006-01-02"))
+			if token.Name != "" {
+				line += "  (" + token.Name + ")"
+			}
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke [id]",
+	Short: "Revoke a token",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := usersAccounts.RevokeToken(args[0]); err != nil {
+			return err
+		}
+		if err := usersAccounts.Save(usersPath); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Token %s revoked\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	userAddCmd.Flags().BoolVar(&userAddAdmin, "admin", false, "let the user see and change every task")
+	tokenCreateCmd.Flags().StringVar(&tokenName, "name", "", "what the token is for, such as phone")
+
+	userCmd.AddCommand(userListCmd)
+	userCmd.AddCommand(userAddCmd)
+	userCmd.AddCommand(userRemoveCmd)
+	rootCmd.AddCommand(userCmd)
+
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
@@ -0,0 +1,173 @@
+// Package config reads and writes facienda's configuration file, which holds
+// named profiles: task databases that can be switched between by name.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ProfileEnv names the environment variable that selects a profile for a
+// single shell or command, overriding the current profile in the file.
+const ProfileEnv = "FACIENDA_PROFILE"
+
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// profileNameRegex restricts names to ones that are easy to type and to
+// list with commas.
+var profileNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// Profile is a named task database.
+type Profile struct {
+	// Backend is a storage backend name (see storage.Open); empty means
+	// SQLite.
+	Backend string `json:"backend,omitempty"`
+	// Path is the database file, or the task directory for the Markdown
+	// backend. A leading ~ is expanded to the home directory.
+	Path string `json:"path"`
+}
+
+// Config is the contents of the configuration file.
+type Config struct {
+	// Current is the profile used when none is given on the command line
+	// or in FACIENDA_PROFILE. Empty means no profile: the --db and
+	// --backend defaults apply.
+	Current  string             `json:"current,omitempty"`
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// DefaultPath returns the configuration file used when none is given:
+// ~/.facienda.json.
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".facienda.json")
+}
+
+// Load reads the configuration file at path. A missing file is an empty
+// configuration.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Save writes the configuration to path, replacing it atomically.
+func (c *Config) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	return nil
+}
+
+// Names returns the profile names in alphabetical order.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the profile called name, with its path expanded.
+func (c *Config) Get(name string) (Profile, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("%w %q", ErrUnknownProfile, name)
+	}
+	profile.Path = expandHome(profile.Path)
+	return profile, nil
+}
+
+// Add defines a new profile.
+func (c *Config) Add(name string, profile Profile) error {
+	if !profileNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q (use letters, digits, - and _)", name)
+	}
+	if _, ok := c.Profiles[name]; ok {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+	if profile.Path == "" {
+		return fmt.Errorf("profile %q needs a database path", name)
+	}
+
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]Profile)
+	}
+	c.Profiles[name] = profile
+	return nil
+}
+
+// Use makes name the current profile.
+func (c *Config) Use(name string) error {
+	if _, ok := c.Profiles[name]; !ok {
+		return fmt.Errorf("%w %q", ErrUnknownProfile, name)
+	}
+	c.Current = name
+	return nil
+}
+
+// Active returns the name of the profile to use: flag if it is set, then
+// FACIENDA_PROFILE, then the current profile. It is empty when no profile is
+// selected.
+func (c *Config) Active(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	if env := os.Getenv(ProfileEnv); env != "" {
+		return env
+	}
+	return c.Current
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
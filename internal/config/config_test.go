@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_MissingFile(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Current != "" || len(cfg.Profiles) != 0 {
+		t.Errorf("expected an empty config, got %+v", cfg)
+	}
+}
+
+func TestConfig_AddUseSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "facienda.json")
+
+	cfg := &Config{}
+	if err := cfg.Add("work", Profile{Path: "/data/work.db"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := cfg.Add("personal", Profile{Backend: "markdown", Path: "~/notes/tasks"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := cfg.Use("work"); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Current != "work" {
+		t.Errorf("current = %q, want work", loaded.Current)
+	}
+	if names := loaded.Names(); len(names) != 2 || names[0] != "personal" || names[1] != "work" {
+		t.Errorf("names = %v", names)
+	}
+
+	personal, err := loaded.Get("personal")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	home, _ := os.UserHomeDir()
+	if personal.Backend != "markdown" || personal.Path != filepath.Join(home, "notes/tasks") {
+		t.Errorf("personal = %+v", personal)
+	}
+}
+
+func TestConfig_Errors(t *testing.T) {
+	cfg := &Config{}
+	cfg.Add("work", Profile{Path: "/data/work.db"})
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"duplicate", cfg.Add("work", Profile{Path: "/other.db"})},
+		{"invalid name", cfg.Add("my work", Profile{Path: "/other.db"})},
+		{"comma in name", cfg.Add("a,b", Profile{Path: "/other.db"})},
+		{"missing path", cfg.Add("empty", Profile{})},
+	}
+	for _, tt := range tests {
+		if tt.err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	if err := cfg.Use("missing"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Use() error = %v, want ErrUnknownProfile", err)
+	}
+	if _, err := cfg.Get("missing"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Get() error = %v, want ErrUnknownProfile", err)
+	}
+}
+
+func TestConfig_Active(t *testing.T) {
+	cfg := &Config{Current: "work"}
+
+	t.Setenv(ProfileEnv, "")
+	if got := cfg.Active(""); got != "work" {
+		t.Errorf("Active() = %q, want the current profile", got)
+	}
+
+	t.Setenv(ProfileEnv, "personal")
+	if got := cfg.Active(""); got != "personal" {
+		t.Errorf("Active() = %q, want the environment's profile", got)
+	}
+	if got := cfg.Active("other"); got != "other" {
+		t.Errorf("Active() = %q, want the flag's profile", got)
+	}
+}
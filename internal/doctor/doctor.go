@@ -0,0 +1,193 @@
+package doctor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// Kind identifies the type of problem an Issue describes.
+type Kind string
+
+const (
+	KindIntegrity         Kind = "integrity"
+	KindEmptyTitle        Kind = "empty-title"
+	KindInvalidRecurrence Kind = "invalid-recurrence"
+	KindDuplicateOpen     Kind = "duplicate-open-instance"
+)
+
+// untitled is the title given to tasks whose title is empty.
+const untitled = "Untitled task"
+
+// Issue is a problem found by Check.
+type Issue struct {
+	Kind    Kind
+	TaskID  int64
+	Message string
+
+	// Repair describes what Fix does. It is empty for issues that can't be
+	// repaired automatically.
+	Repair string
+
+	fix func(store storage.Storage) error
+}
+
+// Fixable reports whether Fix can repair the issue.
+func (i Issue) Fixable() bool {
+	return i.fix != nil
+}
+
+// Fix repairs the issue. The task is reloaded first, so fixes for several
+// issues on the same task can be applied one after another.
+func (i Issue) Fix(store storage.Storage) error {
+	if i.fix == nil {
+		return fmt.Errorf("%s issue can't be fixed automatically", i.Kind)
+	}
+	return i.fix(store)
+}
+
+// Check inspects the store and returns every problem found: database-level
+// corruption and unreadable dates (for backends that can check them), tasks
+// without a title, recurrence patterns that don't parse, and recurring series
+// with more than one open instance.
+func Check(store storage.Storage) ([]Issue, error) {
+	var issues []Issue
+
+	if checker, ok := storage.As[storage.IntegrityChecker](store); ok {
+		problems, err := checker.IntegrityCheck()
+		if err != nil {
+			return nil, err
+		}
+		for _, problem := range problems {
+			issues = append(issues, Issue{
+				Kind:    KindIntegrity,
+				Message: fmt.Sprintf("database integrity check failed: %s", problem),
+			})
+		}
+	}
+
+	tasks, err := store.All()
+	if err != nil {
+		// The problems found so far are likely the cause, so they are
+		// still worth reporting.
+		issues = append(issues, Issue{
+			Kind:    KindIntegrity,
+			Message: fmt.Sprintf("tasks can't be read: %v", err),
+		})
+		return issues, nil
+	}
+
+	for _, task := range tasks {
+		issues = append(issues, checkTask(task)...)
+	}
+	issues = append(issues, checkSeries(tasks)...)
+
+	return issues, nil
+}
+
+func checkTask(task *todo.Task) []Issue {
+	var issues []Issue
+
+	if strings.TrimSpace(task.Title) == "" {
+		issues = append(issues, Issue{
+			Kind:    KindEmptyTitle,
+			TaskID:  task.ID,
+			Message: fmt.Sprintf("task %d has an empty title", task.ID),
+			Repair:  fmt.Sprintf("rename it to %q", untitled),
+			fix: updateTask(task.ID, func(t *todo.Task) error {
+				return t.Update(untitled, t.Details)
+			}),
+		})
+	}
+
+	if err := task.RecurrencePattern.Validate(); err != nil {
+		issue := Issue{
+			Kind:    KindInvalidRecurrence,
+			TaskID:  task.ID,
+			Message: fmt.Sprintf("task %d has an invalid recurrence pattern %q", task.ID, string(task.RecurrencePattern)),
+		}
+
+		// Hand-edited rows often hold the phrase a user would type rather
+		// than the stored form; those can be parsed and normalised.
+		if pattern, err := recurrence.ParsePattern(string(task.RecurrencePattern)); err == nil && pattern.Validate() == nil {
+			issue.Repair = fmt.Sprintf("replace it with %q (%s)", string(pattern), pattern.String())
+			issue.fix = updateTask(task.ID, setPattern(pattern))
+		} else {
+			issue.Repair = "remove the recurrence, making it a one-off task"
+			issue.fix = updateTask(task.ID, setPattern(recurrence.PatternNone))
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
+
+// checkSeries finds recurring series with more than one open (neither
+// completed nor skipped) instance. A series is identified by title and
+// pattern, since that is what GenerateNextInstance carries over. The
+// earliest open instance is the one the user still has to act on; the later
+// ones are duplicates.
+func checkSeries(tasks []*todo.Task) []Issue {
+	type seriesKey struct {
+		title   string
+		pattern recurrence.Pattern
+	}
+
+	var issues []Issue
+	first := map[seriesKey]*todo.Task{}
+
+	// tasks are in date order, so the first open instance seen is the
+	// earliest one.
+	for _, task := range tasks {
+		if !task.IsRecurring() || task.Completed || task.Skipped || task.RecurrencePattern.Validate() != nil {
+			continue
+		}
+
+		key := seriesKey{title: task.Title, pattern: task.RecurrencePattern}
+		original, ok := first[key]
+		if !ok {
+			first[key] = task
+			continue
+		}
+
+		issues = append(issues, Issue{
+			Kind:   KindDuplicateOpen,
+			TaskID: task.ID,
+			Message: fmt.Sprintf("task %d (%s) is a second open instance of recurring task %q; task %d (%s) is already open",
+				task.ID, task.Date.Format("2006-01-02"), task.Title, original.ID, original.Date.Format("2006-01-02")),
+			Repair: fmt.Sprintf("skip task %d", task.ID),
+			fix: updateTask(task.ID, func(t *todo.Task) error {
+				t.Skip()
+				return nil
+			}),
+		})
+	}
+
+	return issues
+}
+
+func setPattern(pattern recurrence.Pattern) func(t *todo.Task) error {
+	return func(t *todo.Task) error {
+		t.RecurrencePattern = pattern
+		t.UpdatedAt = time.Now()
+		return nil
+	}
+}
+
+// updateTask returns a fix that reloads task id, applies change and saves it.
+func updateTask(id int64, change func(t *todo.Task) error) func(store storage.Storage) error {
+	return func(store storage.Storage) error {
+		task, err := store.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if err := change(task); err != nil {
+			return err
+		}
+		return store.Update(task)
+	}
+}
@@ -0,0 +1,226 @@
+package doctor
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+func createTasks(t *testing.T, store storage.Storage, tasks ...*todo.Task) {
+	t.Helper()
+
+	now := time.Now()
+	for _, task := range tasks {
+		if task.CreatedAt.IsZero() {
+			task.CreatedAt, task.UpdatedAt = now, now
+		}
+		if err := store.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+}
+
+func kinds(issues []Issue) map[Kind]int {
+	counts := map[Kind]int{}
+	for _, issue := range issues {
+		counts[issue.Kind]++
+	}
+	return counts
+}
+
+func TestCheck_HealthyStore(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	day := time.Date(2025, 11, 17, 0, 0, 0, 0, time.Local)
+
+	createTasks(t, store,
+		&todo.Task{Title: "One-off", Date: day},
+		&todo.Task{Title: "Weekly", Date: day, RecurrencePattern: "weekly:monday", Completed: true},
+		&todo.Task{Title: "Weekly", Date: day.AddDate(0, 0, 7), RecurrencePattern: "weekly:monday"},
+		&todo.Task{Title: "Weekly", Date: day.AddDate(0, 0, 14), RecurrencePattern: "weekly:monday", Skipped: true},
+	)
+
+	issues, err := Check(store)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("expected no issues, got %+v", issues)
+	}
+}
+
+func TestCheck_FindsAndFixesProblems(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	day := time.Date(2025, 11, 17, 0, 0, 0, 0, time.Local)
+
+	emptyTitle := &todo.Task{Title: "  ", Date: day}
+	phrasePattern := &todo.Task{Title: "Phrase", Date: day, RecurrencePattern: "every friday"}
+	garbagePattern := &todo.Task{Title: "Garbage", Date: day, RecurrencePattern: "weekly:someday"}
+	firstOpen := &todo.Task{Title: "Report", Date: day, RecurrencePattern: "weekly:monday"}
+	duplicate := &todo.Task{Title: "Report", Date: day.AddDate(0, 0, 7), RecurrencePattern: "weekly:monday"}
+	createTasks(t, store, emptyTitle, phrasePattern, garbagePattern, duplicate, firstOpen)
+
+	issues, err := Check(store)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	want := map[Kind]int{
+		KindEmptyTitle:        1,
+		KindInvalidRecurrence: 2,
+		KindDuplicateOpen:     1,
+	}
+	got := kinds(issues)
+	for kind, count := range want {
+		if got[kind] != count {
+			t.Errorf("expected %d %s issues, got %d", count, kind, got[kind])
+		}
+	}
+
+	for _, issue := range issues {
+		if issue.Kind == KindDuplicateOpen && issue.TaskID != duplicate.ID {
+			t.Errorf("expected the later instance %d to be reported, got %d", duplicate.ID, issue.TaskID)
+		}
+		if !issue.Fixable() {
+			t.Errorf("expected %s issue to be fixable", issue.Kind)
+			continue
+		}
+		if err := issue.Fix(store); err != nil {
+			t.Fatalf("failed to fix %s issue: %v", issue.Kind, err)
+		}
+	}
+
+	issues, err = Check(store)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("expected no issues after fixing, got %+v", issues)
+	}
+
+	checks := []struct {
+		id    int64
+		check func(*todo.Task) bool
+		desc  string
+	}{
+		{emptyTitle.ID, func(t *todo.Task) bool { return t.Title == untitled }, "renamed"},
+		{phrasePattern.ID, func(t *todo.Task) bool { return t.RecurrencePattern == "weekly:friday" }, "pattern normalised"},
+		{garbagePattern.ID, func(t *todo.Task) bool { return t.RecurrencePattern == recurrence.PatternNone }, "pattern removed"},
+		{duplicate.ID, func(t *todo.Task) bool { return t.Skipped }, "skipped"},
+		{firstOpen.ID, func(t *todo.Task) bool { return !t.Skipped && !t.Completed }, "left open"},
+	}
+	for _, c := range checks {
+		task, err := store.GetByID(c.id)
+		if err != nil {
+			t.Fatalf("failed to get task %d: %v", c.id, err)
+		}
+		if !c.check(task) {
+			t.Errorf("expected task %d to be %s, got %+v", c.id, c.desc, task)
+		}
+	}
+}
+
+func TestCheck_SeveralFixesOnOneTask(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	day := time.Date(2025, 11, 17, 0, 0, 0, 0, time.Local)
+
+	task := &todo.Task{Title: "", Date: day, RecurrencePattern: "bogus"}
+	createTasks(t, store, task)
+
+	issues, err := Check(store)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %+v", issues)
+	}
+	for _, issue := range issues {
+		if err := issue.Fix(store); err != nil {
+			t.Fatalf("failed to fix %s issue: %v", issue.Kind, err)
+		}
+	}
+
+	got, _ := store.GetByID(task.ID)
+	if got.Title != untitled || !got.Date.Equal(day) || got.IsRecurring() {
+		t.Errorf("expected every fix to be kept, got %+v", got)
+	}
+}
+
+func TestCheck_IntegrityProblems(t *testing.T) {
+	store := corruptStore{storage.NewMemoryStorage()}
+
+	issues, err := Check(store)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(issues) != 1 || issues[0].Kind != KindIntegrity {
+		t.Fatalf("expected one integrity issue, got %+v", issues)
+	}
+	if issues[0].Fixable() {
+		t.Error("expected integrity issues not to be fixable")
+	}
+}
+
+type corruptStore struct {
+	*storage.MemoryStorage
+}
+
+func (corruptStore) IntegrityCheck() ([]string, error) {
+	return []string{"row 3 missing from index idx_tasks_scheduled_date"}, nil
+}
+
+func TestCheck_UnreadableDate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	store, err := storage.NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	day := time.Date(2025, 11, 20, 0, 0, 0, 0, time.Local)
+	createTasks(t, store, &todo.Task{Title: "Pay rent", Date: day}, &todo.Task{Title: "", Date: day})
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`UPDATE tasks SET scheduled_date = 'garbage' WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := Check(store)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if got := kinds(issues); got[KindIntegrity] != 1 || got[KindEmptyTitle] != 1 {
+		t.Fatalf("expected the bad date and the other task's empty title, got %+v", issues)
+	}
+	if !strings.Contains(issues[0].Message, `task 1: invalid date "garbage"`) {
+		t.Errorf("unexpected message %q", issues[0].Message)
+	}
+}
+
+func TestCheck_UnreadableStore(t *testing.T) {
+	issues, err := Check(unreadableStore{corruptStore{storage.NewMemoryStorage()}})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(issues) != 2 || kinds(issues)[KindIntegrity] != 2 {
+		t.Errorf("expected the integrity problem and the failed read, got %+v", issues)
+	}
+}
+
+type unreadableStore struct {
+	corruptStore
+}
+
+func (unreadableStore) All() ([]*todo.Task, error) {
+	return nil, errors.New("database disk image is malformed")
+}
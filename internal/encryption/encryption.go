@@ -0,0 +1,203 @@
+// Package encryption seals backups and exports with a passphrase.
+//
+// Files are encrypted with AES-256-GCM under a key derived from the
+// passphrase with PBKDF2-HMAC-SHA256. The layout is:
+//
+//	magic       "FACIENDA-ENC"
+//	version     1 byte
+//	iterations  4 bytes, big endian
+//	salt        16 bytes
+//	nonce       12 bytes
+//	ciphertext  the sealed contents, followed by the 16-byte GCM tag
+//
+// The header is authenticated along with the contents, so tampering with any
+// part of the file makes decryption fail.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	magic   = "FACIENDA-ENC"
+	version = 1
+
+	saltSize  = 16
+	nonceSize = 12
+	keySize   = 32
+
+	headerSize = len(magic) + 1 + 4 + saltSize + nonceSize
+
+	// maxIterations bounds the work a crafted header can ask Decrypt to do.
+	maxIterations = 10_000_000
+)
+
+// kdfIterations is the PBKDF2 iteration count used for new files. It is
+// stored in each file, so it can be raised without breaking old ones.
+var kdfIterations uint32 = 600_000
+
+var (
+	// ErrDecrypt is returned when a file can't be decrypted, either because
+	// the passphrase is wrong or because the file was modified.
+	ErrDecrypt = errors.New("wrong passphrase or damaged file")
+
+	ErrNotEncrypted    = errors.New("not an encrypted facienda file")
+	ErrEmptyPassphrase = errors.New("passphrase must not be empty")
+)
+
+// IsEncrypted reports whether data starts with the header written by
+// Encrypt.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(magic))
+}
+
+// IsEncryptedFile reports whether the file at path was written by Encrypt.
+func IsEncryptedFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	prefix := make([]byte, len(magic))
+	if _, err := io.ReadFull(f, prefix); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return IsEncrypted(prefix), nil
+}
+
+// Encrypt seals plaintext with a key derived from passphrase.
+func Encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = append(header, version)
+	header = binary.BigEndian.AppendUint32(header, kdfIterations)
+
+	saltAndNonce := make([]byte, saltSize+nonceSize)
+	if _, err := rand.Read(saltAndNonce); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	header = append(header, saltAndNonce...)
+
+	aead, err := newAEAD(passphrase, saltAndNonce[:saltSize], kdfIterations)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(header, saltAndNonce[saltSize:], plaintext, header), nil
+}
+
+// Decrypt opens data written by Encrypt.
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, ErrNotEncrypted
+	}
+	if len(data) < headerSize {
+		return nil, ErrDecrypt
+	}
+
+	header := data[:headerSize]
+	rest := header[len(magic):]
+	if rest[0] != version {
+		return nil, fmt.Errorf("unsupported encrypted file version %d", rest[0])
+	}
+	iterations := binary.BigEndian.Uint32(rest[1:5])
+	if iterations == 0 || iterations > maxIterations {
+		return nil, ErrDecrypt
+	}
+	salt := rest[5 : 5+saltSize]
+	nonce := rest[5+saltSize:]
+
+	aead, err := newAEAD(passphrase, salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, nonce, data[headerSize:], header)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newAEAD(passphrase string, salt []byte, iterations uint32) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, int(iterations), keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptFile writes an encrypted copy of src to dest.
+func EncryptFile(src, dest, passphrase string) error {
+	plaintext, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := Encrypt(plaintext, passphrase)
+	if err != nil {
+		return err
+	}
+	return writeFile(dest, sealed)
+}
+
+// DecryptFile writes the decrypted contents of src to dest.
+func DecryptFile(src, dest, passphrase string) error {
+	sealed, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := Decrypt(sealed, passphrase)
+	if err != nil {
+		return err
+	}
+	return writeFile(dest, plaintext)
+}
+
+// writeFile writes data to a temporary file next to path and renames it into
+// place, so a failed write never leaves a truncated file behind.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,101 @@
+// Package events lets other parts of facienda react to task changes.
+//
+// Store wraps a storage.Storage and publishes an Event on a Bus after every
+// change made through it. Subsystems subscribe to the bus with a Handler;
+// external programs can be run as hooks with ExecHooks.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// Type identifies what happened to a task.
+type Type string
+
+const (
+	TaskCreated   Type = "created"
+	TaskUpdated   Type = "updated"
+	TaskCompleted Type = "completed"
+	TaskSkipped   Type = "skipped"
+	TaskDeleted   Type = "deleted"
+)
+
+// Types lists every event type, in the order they are documented.
+var Types = []Type{TaskCreated, TaskUpdated, TaskCompleted, TaskSkipped, TaskDeleted}
+
+// Event describes one change to a task. Before is nil for TaskCreated and
+// After is nil for TaskDeleted. Both are copies, so handlers may keep them.
+type Event struct {
+	Type   Type
+	TaskID int64
+	Before *todo.Task
+	After  *todo.Task
+	Time   time.Time
+}
+
+// Handler is called for each event it is subscribed to. Handlers run
+// synchronously, in subscription order, after the change has been saved.
+type Handler func(Event)
+
+type subscription struct {
+	id      int
+	handler Handler
+	types   map[Type]bool
+}
+
+// Bus delivers events to subscribed handlers. The zero value is ready to
+// use.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []subscription
+	nextID        int
+}
+
+// Subscribe registers handler for events of the given types, or for every
+// event if no types are given. The returned function unsubscribes it.
+func (b *Bus) Subscribe(handler Handler, types ...Type) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := subscription{id: b.nextID, handler: handler}
+	b.nextID++
+	if len(types) > 0 {
+		sub.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+	b.subscriptions = append(b.subscriptions, sub)
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, s := range b.subscriptions {
+			if s.id == sub.id {
+				b.subscriptions = append(b.subscriptions[:i:i], b.subscriptions[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers event to every handler subscribed to its type.
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	var handlers []Handler
+	for _, s := range b.subscriptions {
+		if s.types == nil || s.types[event.Type] {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	// Handlers are called without the lock held, so they may subscribe,
+	// unsubscribe or change tasks themselves.
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
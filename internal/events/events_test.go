@@ -0,0 +1,209 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// recorder collects the events it is subscribed to.
+type recorder struct {
+	events []Event
+}
+
+func (r *recorder) handle(event Event) {
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) types() string {
+	types := make([]string, len(r.events))
+	for i, event := range r.events {
+		types[i] = string(event.Type)
+	}
+	return strings.Join(types, ",")
+}
+
+func TestStore_PublishesChanges(t *testing.T) {
+	bus := &Bus{}
+	rec := &recorder{}
+	bus.Subscribe(rec.handle)
+	store := NewStore(storage.NewMemoryStorage(), bus)
+
+	task, _ := todo.NewTask("Write report", "", time.Now())
+	if err := store.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	task.Update("Write the report", "")
+	if err := store.Update(task); err != nil {
+		t.Fatalf("failed to update task: %v", err)
+	}
+
+	task.Complete()
+	store.Update(task)
+	task.Skip()
+	store.Update(task)
+	store.Delete(task.ID)
+
+	want := "created,updated,completed,skipped,deleted"
+	if got := rec.types(); got != want {
+		t.Fatalf("got events %s, want %s", got, want)
+	}
+
+	created, renamed, deleted := rec.events[0], rec.events[1], rec.events[4]
+	if created.Before != nil || created.After == nil || created.After.ID != task.ID {
+		t.Errorf("unexpected created event: %+v", created)
+	}
+	if renamed.Before.Title != "Write report" || renamed.After.Title != "Write the report" {
+		t.Errorf("expected before/after titles, got %q/%q", renamed.Before.Title, renamed.After.Title)
+	}
+	if deleted.Before == nil || deleted.After != nil || deleted.TaskID != task.ID {
+		t.Errorf("unexpected deleted event: %+v", deleted)
+	}
+}
+
+func TestStore_BeforeIsTheVersionRead(t *testing.T) {
+	bus := &Bus{}
+	rec := &recorder{}
+	bus.Subscribe(rec.handle)
+
+	inner := storage.NewMemoryStorage()
+	task, _ := todo.NewTask("Original", "", time.Now())
+	inner.Create(task)
+
+	store := NewStore(inner, bus)
+	got, err := store.GetByID(task.ID)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+
+	// Changing the returned task must not change the before snapshot.
+	got.Complete()
+	if err := store.Update(got); err != nil {
+		t.Fatalf("failed to update task: %v", err)
+	}
+
+	if len(rec.events) != 1 || rec.events[0].Type != TaskCompleted || rec.events[0].Before.Completed {
+		t.Errorf("unexpected events: %+v", rec.events)
+	}
+}
+
+func TestStore_FailedChangesPublishNothing(t *testing.T) {
+	bus := &Bus{}
+	rec := &recorder{}
+	bus.Subscribe(rec.handle)
+	store := NewStore(storage.NewMemoryStorage(), bus)
+
+	if err := store.Update(&todo.Task{ID: 42, Title: "Missing"}); err != todo.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := store.Delete(42); err != todo.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if len(rec.events) != 0 {
+		t.Errorf("expected no events, got %s", rec.types())
+	}
+}
+
+func TestBus_SubscribeToTypes(t *testing.T) {
+	bus := &Bus{}
+	completed := &recorder{}
+	all := &recorder{}
+	unsubscribe := bus.Subscribe(completed.handle, TaskCompleted, TaskSkipped)
+	bus.Subscribe(all.handle)
+
+	for _, typ := range Types {
+		bus.Publish(Event{Type: typ})
+	}
+	unsubscribe()
+	bus.Publish(Event{Type: TaskCompleted})
+
+	if got := completed.types(); got != "completed,skipped" {
+		t.Errorf("filtered subscriber got %s", got)
+	}
+	if got := all.types(); got != "created,updated,completed,skipped,deleted,completed" {
+		t.Errorf("unfiltered subscriber got %s", got)
+	}
+}
+
+func TestAs_FindsWrappedStorage(t *testing.T) {
+	inner := storage.NewMemoryStorage()
+	store := NewStore(inner, &Bus{})
+
+	got, ok := storage.As[*storage.MemoryStorage](store)
+	if !ok || got != inner {
+		t.Errorf("expected As to find the wrapped storage")
+	}
+	if _, ok := storage.As[storage.Snapshotter](store); ok {
+		t.Errorf("expected memory storage not to be a Snapshotter")
+	}
+}
+
+func TestExecHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts are shell scripts")
+	}
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "payload.json")
+	script := "#!/bin/sh\ncat > " + out + "\necho \"ran $FACIENDA_EVENT $FACIENDA_TASK_ID\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "completed"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "deleted"), []byte("#!/bin/sh\nexit 3\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	var errs []error
+	bus := &Bus{}
+	bus.Subscribe(ExecHooks(dir, &output, func(err error) { errs = append(errs, err) }))
+	store := NewStore(storage.NewMemoryStorage(), bus)
+
+	task, _ := todo.NewTask("Pay rent", "Transfer", time.Date(2025, 12, 1, 0, 0, 0, 0, time.Local))
+	store.Create(task)
+	task.Complete()
+	store.Update(task)
+
+	if got := strings.TrimSpace(output.String()); got != "ran completed 1" {
+		t.Errorf("hook output = %q", got)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook didn't receive the event: %v", err)
+	}
+	var payload struct {
+		Type   string `json:"type"`
+		Before struct {
+			Completed bool `json:"completed"`
+		} `json:"before"`
+		After struct {
+			Title     string `json:"title"`
+			Date      string `json:"date"`
+			Completed bool   `json:"completed"`
+		} `json:"after"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("invalid payload %s: %v", data, err)
+	}
+	if payload.Type != "completed" || payload.Before.Completed || !payload.After.Completed ||
+		payload.After.Title != "Pay rent" || payload.After.Date != "2025-12-01" {
+		t.Errorf("unexpected payload %s", data)
+	}
+
+	if err := store.Delete(task.ID); err != nil {
+		t.Fatalf("a failing hook must not fail the change: %v", err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected the failing hook to be reported, got %v", errs)
+	}
+}
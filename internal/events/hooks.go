@@ -0,0 +1,133 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// hookTimeout is how long a hook may run before it is killed.
+const hookTimeout = 10 * time.Second
+
+// ExecHooks returns a Handler that runs the executable named after each
+// event's type in dir (dir/created, dir/completed, ...), if there is one.
+// The event is written to the hook's stdin as JSON, and FACIENDA_EVENT and
+// FACIENDA_TASK_ID are set in its environment. The hook's output goes to
+// output.
+//
+// A failing hook doesn't undo the change that triggered it; the error is
+// passed to onError instead.
+func ExecHooks(dir string, output io.Writer, onError func(error)) Handler {
+	return func(event Event) {
+		path := filepath.Join(dir, string(event.Type))
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		if err != nil {
+			onError(fmt.Errorf("hook %s: %w", path, err))
+			return
+		}
+		if info.IsDir() || info.Mode()&0o111 == 0 {
+			return
+		}
+
+		if err := runHook(path, event, output); err != nil {
+			onError(fmt.Errorf("hook %s: %w", path, err))
+		}
+	}
+}
+
+func runHook(path string, event Event, output io.Writer) error {
+	payload, err := json.Marshal(newHookEvent(event))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.Env = append(os.Environ(),
+		"FACIENDA_EVENT="+string(event.Type),
+		"FACIENDA_TASK_ID="+strconv.FormatInt(event.TaskID, 10),
+	)
+	return cmd.Run()
+}
+
+// hookEvent is the JSON document hooks receive on stdin.
+type hookEvent struct {
+	Type   Type      `json:"type"`
+	TaskID int64     `json:"task_id"`
+	Time   time.Time `json:"time"`
+	Before *hookTask `json:"before"`
+	After  *hookTask `json:"after"`
+}
+
+type hookTask struct {
+	ID         int64     `json:"id"`
+	UID        string    `json:"uid"`
+	Title      string    `json:"title"`
+	Details    string    `json:"details"`
+	Date       string    `json:"date"`
+	Time       string    `json:"time,omitempty"`
+	Zone       string    `json:"zone,omitempty"`
+	Completed  bool      `json:"completed"`
+	Skipped    bool      `json:"skipped"`
+	Recurrence string    `json:"recurrence"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Owner      string    `json:"owner,omitempty"`
+	Assignee   string    `json:"assignee,omitempty"`
+}
+
+func newHookEvent(event Event) hookEvent {
+	return hookEvent{
+		Type:   event.Type,
+		TaskID: event.TaskID,
+		Time:   event.Time,
+		Before: newHookTask(event.Before),
+		After:  newHookTask(event.After),
+	}
+}
+
+func newHookTask(task *todo.Task) *hookTask {
+	if task == nil {
+		return nil
+	}
+
+	h := &hookTask{
+		ID:         task.ID,
+		UID:        task.StableUID(),
+		Title:      task.Title,
+		Details:    task.Details,
+		Date:       task.Date.Format("2006-01-02"),
+		Completed:  task.Completed,
+		Skipped:    task.Skipped,
+		Recurrence: string(task.RecurrencePattern),
+		CreatedAt:  task.CreatedAt,
+		UpdatedAt:  task.UpdatedAt,
+		Owner:      task.Owner,
+		Assignee:   task.Assignee,
+	}
+	if task.HasTime() {
+		h.Time = task.Date.Format("15:04")
+		if task.Date.Location() != time.Local {
+			h.Zone = task.Date.Location().String()
+		}
+	}
+	return h
+}
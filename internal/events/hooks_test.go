@@ -0,0 +1,26 @@
+package events
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+func TestNewHookTask_UID(t *testing.T) {
+	created := time.Date(2025, 11, 20, 9, 0, 0, 0, time.UTC)
+	imported := &todo.Task{ID: 7, UID: "rent@example.com", Title: "Pay rent", CreatedAt: created}
+	legacy := &todo.Task{ID: 8, Title: "Water plants", CreatedAt: created}
+
+	for task, want := range map[*todo.Task]string{imported: "rent@example.com", legacy: legacy.StableUID()} {
+		data, err := json.Marshal(newHookTask(task))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), `"uid":"`+want+`"`) {
+			t.Errorf("expected uid %q in %s", want, data)
+		}
+	}
+}
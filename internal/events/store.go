@@ -0,0 +1,135 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// Store wraps a storage.Storage and publishes an Event on its bus after each
+// successful Create, Update and Delete. Changes made by other processes, or
+// by restoring a backup, are not observed.
+//
+// The Before snapshot of an update is the task as this Store last read or
+// wrote it, which is the version the update is based on. Tasks it hasn't seen
+// are read from the underlying storage first.
+type Store struct {
+	storage.Storage
+	bus *Bus
+
+	mu   sync.Mutex
+	seen map[int64]todo.Task
+}
+
+// NewStore returns a Store publishing changes to inner on bus.
+func NewStore(inner storage.Storage, bus *Bus) *Store {
+	return &Store{Storage: inner, bus: bus, seen: make(map[int64]todo.Task)}
+}
+
+// Unwrap returns the underlying storage.
+func (s *Store) Unwrap() storage.Storage {
+	return s.Storage
+}
+
+func (s *Store) remember(tasks ...*todo.Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, task := range tasks {
+		s.seen[task.ID] = *task
+	}
+}
+
+// before returns the last known state of task id.
+func (s *Store) before(id int64) *todo.Task {
+	s.mu.Lock()
+	task, ok := s.seen[id]
+	s.mu.Unlock()
+	if ok {
+		return &task
+	}
+
+	current, err := s.Storage.GetByID(id)
+	if err != nil {
+		return nil
+	}
+	return current
+}
+
+func (s *Store) Create(task *todo.Task) error {
+	if err := s.Storage.Create(task); err != nil {
+		return err
+	}
+
+	after := *task
+	s.remember(&after)
+	s.bus.Publish(Event{Type: TaskCreated, TaskID: task.ID, After: &after, Time: time.Now()})
+	return nil
+}
+
+func (s *Store) GetByID(id int64) (*todo.Task, error) {
+	task, err := s.Storage.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.remember(task)
+	return task, nil
+}
+
+func (s *Store) List(filter storage.TimeFilter) ([]*todo.Task, error) {
+	tasks, err := s.Storage.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	s.remember(tasks...)
+	return tasks, nil
+}
+
+func (s *Store) All() ([]*todo.Task, error) {
+	tasks, err := s.Storage.All()
+	if err != nil {
+		return nil, err
+	}
+	s.remember(tasks...)
+	return tasks, nil
+}
+
+func (s *Store) Update(task *todo.Task) error {
+	before := s.before(task.ID)
+	if err := s.Storage.Update(task); err != nil {
+		return err
+	}
+
+	after := *task
+	s.remember(&after)
+	s.bus.Publish(Event{Type: updateType(before, &after), TaskID: task.ID, Before: before, After: &after, Time: time.Now()})
+	return nil
+}
+
+// updateType classifies an update: completing or skipping a task gets its
+// own event type, any other change is TaskUpdated.
+func updateType(before, after *todo.Task) Type {
+	switch {
+	case after.Completed && (before == nil || !before.Completed):
+		return TaskCompleted
+	case after.Skipped && (before == nil || !before.Skipped):
+		return TaskSkipped
+	default:
+		return TaskUpdated
+	}
+}
+
+func (s *Store) Delete(id int64) error {
+	before := s.before(id)
+	if err := s.Storage.Delete(id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.seen, id)
+	s.mu.Unlock()
+
+	s.bus.Publish(Event{Type: TaskDeleted, TaskID: id, Before: before, Time: time.Now()})
+	return nil
+}
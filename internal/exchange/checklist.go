@@ -0,0 +1,275 @@
+package exchange
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// Markdown checklists are written the way notes apps such as Obsidian (with
+// its Tasks plugin) keep tasks:
+//
+//	## 2025-11-24
+//
+//	- [ ] Weekly report 🔁 every monday 📅 2025-11-24
+//	- [x] Call the bank 📅 2025-11-24 ✅ 2025-11-24
+//	    Ask about the new card.
+//	- [-] Team lunch 📅 2025-11-24 ⏰ 12:30
+//
+// Tasks are grouped under a heading per date, like the past and future
+// views. 📅 is the task's date, 🔁 its recurrence as the add command's
+// --recur takes it, ⏰ a time of day (followed by a zone unless it is
+// local), ✅ the date it was completed and ➕ the date it was created. [x]
+// marks completed tasks and [-] skipped ones. Details are indented below
+// their item.
+
+const (
+	markerDue       = "📅"
+	markerScheduled = "⏳"
+	markerDone      = "✅"
+	markerCreated   = "➕"
+	markerRecurs    = "🔁"
+	markerTime      = "⏰"
+	markerStart     = "🛫"
+)
+
+var (
+	checklistItem    = regexp.MustCompile(`^(\s*)[-*+] \[([ xX-])\] ?(.*)$`)
+	checklistHeading = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+
+	// checklistMarkers introduce a value, which runs until the next
+	// marker.
+	checklistMarkers = map[string]bool{
+		markerDue: true, markerScheduled: true, markerDone: true, markerCreated: true,
+		markerRecurs: true, markerTime: true, markerStart: true,
+	}
+
+	// checklistPriorities have no equivalent in facienda and stay in the
+	// title, but end the value of the marker before them.
+	checklistPriorities = map[string]bool{"🔺": true, "⏫": true, "🔼": true, "🔽": true, "⏬": true}
+)
+
+// ExportChecklist writes tasks as a Markdown checklist with a heading for
+// each date.
+func ExportChecklist(w io.Writer, tasks []*todo.Task) error {
+	bw := bufio.NewWriter(w)
+	currentDate := ""
+	for _, task := range tasks {
+		r := NewRecord(task)
+		if r.Date != currentDate {
+			if currentDate != "" {
+				fmt.Fprintln(bw)
+			}
+			currentDate = r.Date
+			fmt.Fprintf(bw, "## %s\n\n", currentDate)
+		}
+		fmt.Fprintln(bw, checklistLine(r))
+
+		if r.Details != "" {
+			for _, line := range strings.Split(r.Details, "\n") {
+				if line == "" {
+					fmt.Fprintln(bw)
+				} else {
+					fmt.Fprintf(bw, "    %s\n", line)
+				}
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func checklistLine(r Record) string {
+	status := " "
+	switch {
+	case r.Skipped:
+		status = "-"
+	case r.Completed:
+		status = "x"
+	}
+
+	parts := []string{"- [" + status + "]", r.Title}
+	if r.Recurrence != "" {
+		parts = append(parts, markerRecurs, recurrence.Pattern(r.Recurrence).Phrase())
+	}
+	parts = append(parts, markerDue, r.Date)
+	if r.Time != "" {
+		parts = append(parts, markerTime, r.Time)
+		if r.Zone != "" {
+			parts = append(parts, r.Zone)
+		}
+	}
+	if r.Completed {
+		parts = append(parts, markerDone, r.UpdatedAt.In(time.Local).Format(dateLayout))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ImportChecklist reads the checklist items in a Markdown file, including
+// nested ones; other content is ignored. An item without a 📅 (or ⏳) date
+// takes the date of the heading it is under, if that starts with one, and
+// is otherwise scheduled for today.
+func ImportChecklist(r io.Reader) ([]Row, error) {
+	type item struct {
+		line    int
+		indent  int
+		record  Record
+		err     error
+		details []string
+	}
+	var items []*item
+	var current *item
+	headingDate := ""
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+
+		if m := checklistItem.FindStringSubmatch(text); m != nil {
+			current = &item{line: line, indent: len(m[1])}
+			current.record, current.err = parseChecklistItem(m[2], m[3], headingDate)
+			items = append(items, current)
+			continue
+		}
+
+		// Lines indented below an item are its details; a blank line
+		// only ends them if nothing indented follows.
+		indent := len(text) - len(strings.TrimLeft(text, " \t"))
+		if current != nil && (text == "" || indent > current.indent) {
+			current.details = append(current.details, trimIndent(text, current.indent+4))
+			continue
+		}
+
+		current = nil
+		if m := checklistHeading.FindStringSubmatch(text); m != nil {
+			headingDate = ""
+			if fields := strings.Fields(m[1]); len(fields) > 0 && todoTxtDate.MatchString(fields[0]) {
+				headingDate = fields[0]
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Markdown file: %w", err)
+	}
+
+	rows := make([]Row, len(items))
+	for i, it := range items {
+		if it.err != nil {
+			rows[i] = Row{Line: it.line, Err: it.err}
+			continue
+		}
+		it.record.Details = strings.TrimRight(strings.Join(it.details, "\n"), "\n")
+		rows[i] = rowTask(it.line, it.record)
+	}
+	return rows, nil
+}
+
+// trimIndent removes up to n columns of leading whitespace, counting a tab
+// as four.
+func trimIndent(line string, n int) string {
+	removed := 0
+	for i, c := range line {
+		switch {
+		case removed >= n:
+			return line[i:]
+		case c == ' ':
+			removed++
+		case c == '\t':
+			removed += 4
+		default:
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+func parseChecklistItem(status, text, headingDate string) (Record, error) {
+	record := Record{
+		Completed: status == "x" || status == "X",
+		Skipped:   status == "-",
+	}
+
+	var title, recurs []string
+	var due, scheduled string
+	marker := ""
+	for _, token := range strings.Fields(text) {
+		// Emoji are sometimes followed by a variation selector.
+		key := strings.TrimSuffix(token, "\uFE0F")
+		switch {
+		case checklistMarkers[key]:
+			marker = key
+			continue
+		case checklistPriorities[key]:
+			title = append(title, token)
+			marker = ""
+			continue
+		}
+
+		var err error
+		switch marker {
+		case markerDue:
+			due, marker = token, ""
+		case markerScheduled:
+			scheduled, marker = token, ""
+		case markerStart:
+			marker = ""
+		case markerDone:
+			record.UpdatedAt, err = parseChecklistDate("completion", token)
+			marker = ""
+		case markerCreated:
+			record.CreatedAt, err = parseChecklistDate("creation", token)
+			marker = ""
+		case markerTime:
+			if record.Time == "" {
+				record.Time = token
+				continue
+			}
+			marker = ""
+			if isZoneName(token) {
+				record.Zone = token
+			} else {
+				title = append(title, token)
+			}
+		case markerRecurs:
+			recurs = append(recurs, token)
+		default:
+			title = append(title, token)
+		}
+		if err != nil {
+			return Record{}, err
+		}
+	}
+
+	record.Title = strings.Join(title, " ")
+	record.Recurrence = strings.Join(recurs, " ")
+	switch {
+	case due != "":
+		record.Date = due
+	case scheduled != "":
+		record.Date = scheduled
+	case headingDate != "":
+		record.Date = headingDate
+	default:
+		record.Date = time.Now().Format(dateLayout)
+	}
+	return record, nil
+}
+
+// isZoneName reports whether s looks like a time zone written after a time
+// of day: an IANA name, UTC or an offset.
+func isZoneName(s string) bool {
+	return strings.Contains(s, "/") || s == "UTC" || isOffsetZone(s)
+}
+
+func parseChecklistDate(kind, value string) (time.Time, error) {
+	t, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s date %q", kind, value)
+	}
+	return t, nil
+}
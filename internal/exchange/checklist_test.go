@@ -0,0 +1,164 @@
+package exchange
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+const sampleNote = `# Team sync
+
+Notes from the meeting.
+
+- [ ] Send the minutes 📅 2025-11-20
+- [x] Book the room ⏫ 📅 2025-11-19 ✅ 2025-11-19
+    Room 4 on the second floor.
+
+    Bring the adapter.
+- [ ] Weekly report 🔁 every monday 📅 2025-11-24
+  - [ ] Collect numbers ⏳ 2025-11-21
+- Not a task
+
+## 2025-11-25 Follow-ups
+
+* [-] Dropped idea
+- [ ] Standup ⏰️ 09:30 Europe/Berlin
+- [ ] Bad date 📅 2025-02-30
+- [ ] Bad recurrence 🔁 every other day 📅 2025-11-25
+`
+
+func TestImportChecklist(t *testing.T) {
+	rows, err := ImportChecklist(strings.NewReader(sampleNote))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(rows) != 8 {
+		t.Fatalf("expected 8 items, got %d", len(rows))
+	}
+	day := func(d int) time.Time { return time.Date(2025, 11, d, 0, 0, 0, 0, time.Local) }
+
+	if rows[0].Line != 5 || rows[0].Task.Title != "Send the minutes" || !rows[0].Task.Date.Equal(day(20)) {
+		t.Errorf("unexpected first task at line %d: %+v", rows[0].Line, rows[0].Task)
+	}
+
+	room := rows[1].Task
+	if !room.Completed || room.Title != "Book the room ⏫" || !room.UpdatedAt.Equal(day(19)) {
+		t.Errorf("unexpected completed task %+v", room)
+	}
+	if room.Details != "Room 4 on the second floor.\n\nBring the adapter." {
+		t.Errorf("details = %q", room.Details)
+	}
+
+	if rows[2].Task.RecurrencePattern != recurrence.Pattern("weekly:monday") {
+		t.Errorf("recurrence = %q, want weekly:monday", rows[2].Task.RecurrencePattern)
+	}
+	if rows[3].Task.Title != "Collect numbers" || !rows[3].Task.Date.Equal(day(21)) {
+		t.Errorf("expected the nested item with its scheduled date, got %+v", rows[3].Task)
+	}
+
+	if !rows[4].Task.Skipped || !rows[4].Task.Date.Equal(day(25)) {
+		t.Errorf("expected a skipped task on the heading's date, got %+v", rows[4].Task)
+	}
+	standup := rows[5].Task
+	if standup.Date.Location().String() != "Europe/Berlin" || standup.Date.Hour() != 9 || !strings.HasPrefix(standup.Date.Format(dateLayout), "2025-11-25") {
+		t.Errorf("unexpected standup date %v", standup.Date)
+	}
+
+	if rows[6].Err == nil || rows[7].Err == nil {
+		t.Errorf("expected the invalid items to be rejected, got %v and %v", rows[6].Err, rows[7].Err)
+	}
+}
+
+func TestExportChecklist(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportChecklist(&buf, sampleTasks(t)); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	want := `## 2025-11-24
+
+- [ ] Weekly report 🔁 every monday 📅 2025-11-24
+    Send to the whole team.
+
+## 2025-11-20
+
+- [x] Call, "Berlin" office 📅 2025-11-20 ⏰ 14:30 Europe/Berlin ✅ 2025-11-17
+
+## 2025-10-01
+
+- [-] Old task 📅 2025-10-01
+    line one
+    line two
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestChecklist_RoundTrip(t *testing.T) {
+	tasks := sampleTasks(t)
+	for i, pattern := range []string{"monthly:22", "monthly-nth-weekday:3", "monthly-last-weekend"} {
+		task, _ := todo.NewTask("Recurring", "", time.Date(2025, 12, 1+i, 0, 0, 0, 0, time.Local))
+		task.RecurrencePattern = recurrence.Pattern(pattern)
+		tasks = append(tasks, task)
+	}
+
+	var buf bytes.Buffer
+	ExportChecklist(&buf, tasks)
+	rows, err := ImportChecklist(&buf)
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(rows) != len(tasks) {
+		t.Fatalf("expected %d rows, got %d", len(tasks), len(rows))
+	}
+
+	for i, row := range rows {
+		if row.Err != nil {
+			t.Fatalf("row %d rejected: %v", row.Line, row.Err)
+		}
+		want, got := tasks[i], row.Task
+		if got.Title != want.Title || got.Details != want.Details ||
+			got.Completed != want.Completed || got.Skipped != want.Skipped ||
+			got.RecurrencePattern != want.RecurrencePattern ||
+			!got.Date.Equal(want.Date) || got.Date.Location().String() != want.Date.Location().String() {
+			t.Errorf("task %d changed in the round trip:\n got %+v\nwant %+v", i, got, want)
+		}
+	}
+}
+
+func TestNewPlan_CompletesOnReimport(t *testing.T) {
+	monday := time.Date(2025, 11, 24, 0, 0, 0, 0, time.Local)
+	report, _ := todo.NewTask("Weekly report", "", monday)
+	report.ID = 3
+	report.RecurrencePattern = recurrence.Pattern("weekly:monday")
+	done, _ := todo.NewTask("Already done", "", monday)
+	done.ID = 4
+	done.Completed = true
+
+	rows, err := ImportChecklist(strings.NewReader(`- [x] Weekly report 🔁 every monday 📅 2025-11-24
+- [x] Already done 📅 2025-11-24
+- [ ] Weekly report 🔁 every monday 📅 2025-12-01
+`))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+
+	plan := NewPlan(rows, []*todo.Task{report, done})
+	if len(plan.Updates) != 1 || plan.Updates[0].ID != 3 || !plan.Updates[0].Completed {
+		t.Fatalf("expected the open task to be completed, got updates %+v", plan.Updates)
+	}
+	if report.Completed {
+		t.Error("expected the existing task to be left unchanged")
+	}
+	if len(plan.New) != 1 || !plan.New[0].Date.Equal(monday.AddDate(0, 0, 7)) {
+		t.Errorf("expected the next occurrence to be created once, got %+v", plan.New)
+	}
+	if len(plan.Duplicates) != 2 {
+		t.Errorf("expected 2 duplicates, got %d", len(plan.Duplicates))
+	}
+}
@@ -0,0 +1,155 @@
+package exchange
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// csvHeader is the first row of a CSV export. The columns are the fields of
+// Record, named as in the JSON format.
+var csvHeader = []string{
+	"id", "title", "details", "date", "time", "zone",
+	"completed", "skipped", "recurrence", "created_at", "updated_at", "uid",
+	"owner", "assignee",
+}
+
+// ExportCSV writes tasks as CSV, one Record per row after a header row.
+// Booleans are written as true/false and timestamps as RFC 3339.
+func ExportCSV(w io.Writer, tasks []*todo.Task) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		r := NewRecord(task)
+		row := []string{
+			strconv.FormatInt(r.ID, 10),
+			r.Title,
+			r.Details,
+			r.Date,
+			r.Time,
+			r.Zone,
+			strconv.FormatBool(r.Completed),
+			strconv.FormatBool(r.Skipped),
+			r.Recurrence,
+			r.CreatedAt.Format(time.RFC3339Nano),
+			r.UpdatedAt.Format(time.RFC3339Nano),
+			r.UID,
+			r.Owner,
+			r.Assignee,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportCSV reads CSV with a header row naming the columns, as ExportCSV
+// writes it. Only the title and date columns are required, so hand-written
+// files can leave out the rest; columns may come in any order and unknown
+// columns are ignored.
+func ImportCSV(r io.Reader) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, required := range []string{"title", "date"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header has no %q column", required)
+		}
+	}
+
+	var rows []Row
+	for {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// FieldPos only works for records that parsed, so the line of a
+			// malformed one comes from the error.
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			rows = append(rows, Row{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return fields[i]
+			}
+			return ""
+		}
+		record, err := csvRecord(field)
+		if err != nil {
+			rows = append(rows, Row{Line: line, Err: err})
+			continue
+		}
+		rows = append(rows, rowTask(line, record))
+	}
+	return rows, nil
+}
+
+func csvRecord(field func(name string) string) (Record, error) {
+	record := Record{
+		Title:      field("title"),
+		Details:    field("details"),
+		Date:       field("date"),
+		Time:       field("time"),
+		Zone:       field("zone"),
+		Recurrence: field("recurrence"),
+		UID:        field("uid"),
+		Owner:      field("owner"),
+		Assignee:   field("assignee"),
+	}
+
+	var err error
+	if record.Completed, err = parseBool("completed", field("completed")); err != nil {
+		return Record{}, err
+	}
+	if record.Skipped, err = parseBool("skipped", field("skipped")); err != nil {
+		return Record{}, err
+	}
+	if record.CreatedAt, err = parseTimestamp("created_at", field("created_at")); err != nil {
+		return Record{}, err
+	}
+	if record.UpdatedAt, err = parseTimestamp("updated_at", field("updated_at")); err != nil {
+		return Record{}, err
+	}
+	return record, nil
+}
+
+// parseBool parses an optional boolean column; empty is false.
+func parseBool(field, value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q (use true or false)", field, value)
+	}
+	return b, nil
+}
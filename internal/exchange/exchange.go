@@ -0,0 +1,128 @@
+// Package exchange converts tasks to and from the file formats facienda
+// exports, so task lists can be moved between machines and other tools.
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Format is a file format tasks can be written in and read from.
+type Format struct {
+	Name string
+	// Extension is the usual file name extension, used to guess the format
+	// of a file.
+	Extension string
+	// Export writes tasks to w in this format. It is nil for formats that
+	// can only be imported.
+	Export func(w io.Writer, tasks []*todo.Task) error
+	// Import reads the tasks in r. It fails only when the file as a whole
+	// can't be read; problems with single tasks are reported in their Row.
+	Import func(r io.Reader) ([]Row, error)
+}
+
+// formats are the supported formats, in the order they are listed in help
+// texts.
+var formats = []Format{
+	{Name: "json", Extension: ".json", Export: ExportJSON, Import: ImportJSON},
+	{Name: "csv", Extension: ".csv", Export: ExportCSV, Import: ImportCSV},
+	{Name: "todotxt", Extension: ".txt", Export: ExportTodoTxt, Import: ImportTodoTxt},
+	{Name: "ics", Extension: ".ics", Export: ExportICS, Import: ImportICS},
+	{Name: "markdown", Extension: ".md", Export: ExportChecklist, Import: ImportChecklist},
+	{Name: "org", Extension: ".org", Export: ExportOrg, Import: ImportOrg},
+	{Name: "taskwarrior", Import: ImportTaskwarrior},
+	{Name: "todoist", Import: ImportTodoist},
+}
+
+// Lookup returns the format called name.
+func Lookup(name string) (Format, error) {
+	for _, format := range formats {
+		if format.Name == name {
+			return format, nil
+		}
+	}
+	return Format{}, fmt.Errorf("%w %q (use %s)", ErrUnknownFormat, name, strings.Join(Names(), ", "))
+}
+
+// ForFile returns the format whose extension path has, ignoring a trailing
+// ".enc" added by encryption.
+func ForFile(path string) (Format, bool) {
+	ext := strings.ToLower(filepath.Ext(strings.TrimSuffix(path, ".enc")))
+	for _, format := range formats {
+		if format.Extension != "" && format.Extension == ext {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// Names returns the names of the formats that can be imported, which is
+// all of them.
+func Names() []string {
+	names := make([]string, len(formats))
+	for i, format := range formats {
+		names[i] = format.Name
+	}
+	return names
+}
+
+// ExportNames returns the names of the formats that can be exported.
+func ExportNames() []string {
+	var names []string
+	for _, format := range formats {
+		if format.Export != nil {
+			names = append(names, format.Name)
+		}
+	}
+	return names
+}
+
+// Filter selects tasks to export. Zero fields select every task.
+type Filter struct {
+	// From and To bound the calendar dates tasks are scheduled on, both
+	// inclusive.
+	From, To time.Time
+	// Completed, if set, selects only completed (true) or only open (false)
+	// tasks.
+	Completed *bool
+	// Recurring, if set, selects only recurring (true) or only one-off
+	// (false) tasks.
+	Recurring *bool
+}
+
+// Match reports whether the filter selects task.
+func (f Filter) Match(task *todo.Task) bool {
+	day := task.Date.Format(dateLayout)
+	if !f.From.IsZero() && day < f.From.Format(dateLayout) {
+		return false
+	}
+	if !f.To.IsZero() && day > f.To.Format(dateLayout) {
+		return false
+	}
+	if f.Completed != nil && task.Completed != *f.Completed {
+		return false
+	}
+	if f.Recurring != nil && task.IsRecurring() != *f.Recurring {
+		return false
+	}
+	return true
+}
+
+// Apply returns the tasks the filter selects, in their original order.
+func (f Filter) Apply(tasks []*todo.Task) []*todo.Task {
+	var selected []*todo.Task
+	for _, task := range tasks {
+		if f.Match(task) {
+			selected = append(selected, task)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,165 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// sampleTasks returns a whole-day recurring task, a completed task at a
+// time in another zone and a skipped task.
+func sampleTasks(t *testing.T) []*todo.Task {
+	t.Helper()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed to load zone: %v", err)
+	}
+	created := time.Date(2025, 11, 17, 9, 12, 3, 0, time.UTC)
+
+	weekly, _ := todo.NewTask("Weekly report", "Send to the whole team.", time.Date(2025, 11, 24, 0, 0, 0, 0, time.Local))
+	weekly.ID = 1
+	weekly.RecurrencePattern = recurrence.Pattern("weekly:monday")
+
+	call, _ := todo.NewTask("Call, \"Berlin\" office", "", time.Date(2025, 11, 20, 14, 30, 0, 0, berlin))
+	call.ID = 2
+	call.Completed = true
+
+	skipped, _ := todo.NewTask("Old task", "line one\nline two", time.Date(2025, 10, 1, 0, 0, 0, 0, time.Local))
+	skipped.ID = 3
+	skipped.Skipped = true
+
+	tasks := []*todo.Task{weekly, call, skipped}
+	for _, task := range tasks {
+		task.CreatedAt = created
+		task.UpdatedAt = created.Add(time.Hour)
+	}
+	return tasks
+}
+
+func TestExportJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportJSON(&buf, sampleTasks(t)); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	var doc Document
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse export: %v\n%s", err, buf.String())
+	}
+	if doc.SchemaVersion != SchemaVersion {
+		t.Errorf("schema_version = %d, want %d", doc.SchemaVersion, SchemaVersion)
+	}
+	if len(doc.Tasks) != 3 {
+		t.Fatalf("expected 3 tasks, got %d", len(doc.Tasks))
+	}
+
+	weekly := doc.Tasks[0]
+	if weekly.ID != 1 || weekly.Title != "Weekly report" || weekly.Details != "Send to the whole team." ||
+		weekly.Date != "2025-11-24" || weekly.Time != "" || weekly.Recurrence != "weekly:monday" {
+		t.Errorf("unexpected weekly record %+v", weekly)
+	}
+	if !weekly.CreatedAt.Equal(time.Date(2025, 11, 17, 9, 12, 3, 0, time.UTC)) ||
+		!weekly.UpdatedAt.Equal(time.Date(2025, 11, 17, 10, 12, 3, 0, time.UTC)) {
+		t.Errorf("unexpected timestamps %v, %v", weekly.CreatedAt, weekly.UpdatedAt)
+	}
+
+	call := doc.Tasks[1]
+	if call.Date != "2025-11-20" || call.Time != "14:30" || call.Zone != "Europe/Berlin" || !call.Completed {
+		t.Errorf("unexpected call record %+v", call)
+	}
+	if !doc.Tasks[2].Skipped {
+		t.Errorf("expected the skipped task to be exported as skipped")
+	}
+
+	// Field names are part of the documented schema.
+	var raw map[string][]map[string]interface{}
+	json.Unmarshal(buf.Bytes(), &raw)
+	for _, key := range []string{"id", "title", "details", "date", "completed", "skipped", "recurrence", "created_at", "updated_at"} {
+		if _, ok := raw["tasks"][0][key]; !ok {
+			t.Errorf("expected key %q in task records", key)
+		}
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportCSV(&buf, sampleTasks(t)); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse export: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected a header and 3 rows, got %d rows", len(rows))
+	}
+
+	want := [][]string{
+		csvHeader,
+		{"1", "Weekly report", "Send to the whole team.", "2025-11-24", "", "", "false", "false", "weekly:monday", "2025-11-17T09:12:03Z", "2025-11-17T10:12:03Z"},
+		{"2", "Call, \"Berlin\" office", "", "2025-11-20", "14:30", "Europe/Berlin", "true", "false", "", "2025-11-17T09:12:03Z", "2025-11-17T10:12:03Z"},
+		{"3", "Old task", "line one\nline two", "2025-10-01", "", "", "false", "true", "", "2025-11-17T09:12:03Z", "2025-11-17T10:12:03Z"},
+	}
+	for i := range want {
+		for j := range want[i] {
+			if rows[i][j] != want[i][j] {
+				t.Errorf("row %d column %s = %q, want %q", i, csvHeader[j], rows[i][j], want[i][j])
+			}
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tasks := sampleTasks(t)
+	yes, no := true, false
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int64
+	}{
+		{"everything", Filter{}, []int64{1, 2, 3}},
+		{"from", Filter{From: time.Date(2025, 11, 20, 0, 0, 0, 0, time.Local)}, []int64{1, 2}},
+		{"to", Filter{To: time.Date(2025, 11, 20, 0, 0, 0, 0, time.Local)}, []int64{2, 3}},
+		{"range", Filter{From: time.Date(2025, 11, 1, 0, 0, 0, 0, time.Local), To: time.Date(2025, 11, 21, 0, 0, 0, 0, time.Local)}, []int64{2}},
+		{"completed", Filter{Completed: &yes}, []int64{2}},
+		{"open", Filter{Completed: &no}, []int64{1, 3}},
+		{"recurring", Filter{Recurring: &yes}, []int64{1}},
+		{"one-off", Filter{Recurring: &no}, []int64{2, 3}},
+	}
+	for _, tt := range tests {
+		got := tt.filter.Apply(tasks)
+		var ids []int64
+		for _, task := range got {
+			ids = append(ids, task.ID)
+		}
+		if len(ids) != len(tt.want) {
+			t.Errorf("%s: got tasks %v, want %v", tt.name, ids, tt.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.want[i] {
+				t.Errorf("%s: got tasks %v, want %v", tt.name, ids, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if format, err := Lookup("csv"); err != nil || format.Name != "csv" {
+		t.Errorf("Lookup(csv) = %v, %v", format.Name, err)
+	}
+	if _, err := Lookup("xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Lookup(xml) error = %v, want ErrUnknownFormat", err)
+	}
+}
@@ -0,0 +1,474 @@
+package exchange
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// iCalendar (RFC 5545) files hold each task as a VTODO component:
+//
+//	BEGIN:VTODO
+//	UID:42-1763370723@facienda
+//	SUMMARY:Weekly report
+//	DTSTART;VALUE=DATE:20251124
+//	DUE;VALUE=DATE:20251124
+//	RRULE:FREQ=WEEKLY;BYDAY=MO
+//	STATUS:NEEDS-ACTION
+//	END:VTODO
+//
+// DTSTART and DUE are both the task's date: a DATE for whole-day tasks, a
+// floating local time, or a time with a TZID. Time zones given as a UTC
+// offset get a VTIMEZONE with that fixed offset. Skipped tasks are
+// CANCELLED.
+//
+// Every exported task has a UID (see todo.Task.StableUID), so importing an
+// exported file again, or a calendar's copy of it, updates the tasks instead
+// of duplicating them.
+
+const (
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405"
+	icsUTC      = "20060102T150405Z"
+)
+
+// ExportICS writes tasks as a VCALENDAR of VTODOs.
+func ExportICS(w io.Writer, tasks []*todo.Task) error {
+	bw := bufio.NewWriter(w)
+	ics := &icsWriter{w: bw}
+
+	ics.prop("BEGIN", "", "VCALENDAR")
+	ics.prop("VERSION", "", "2.0")
+	ics.prop("PRODID", "", "-//facienda//facienda//EN")
+
+	offsets := map[string]bool{}
+	for _, task := range tasks {
+		r := NewRecord(task)
+		if isOffsetZone(r.Zone) && !offsets[r.Zone] {
+			offsets[r.Zone] = true
+			ics.timezone(r.Zone)
+		}
+	}
+
+	stamp := time.Now().UTC().Format(icsUTC)
+	for _, task := range tasks {
+		r := NewRecord(task)
+
+		ics.prop("BEGIN", "", "VTODO")
+		ics.prop("UID", "", icsEscape(task.StableUID()))
+		ics.prop("DTSTAMP", "", stamp)
+		ics.prop("CREATED", "", r.CreatedAt.UTC().Format(icsUTC))
+		ics.prop("LAST-MODIFIED", "", r.UpdatedAt.UTC().Format(icsUTC))
+		ics.prop("SUMMARY", "", icsEscape(r.Title))
+		if r.Details != "" {
+			ics.prop("DESCRIPTION", "", icsEscape(r.Details))
+		}
+
+		params, value := icsSchedule(task)
+		ics.prop("DTSTART", params, value)
+		ics.prop("DUE", params, value)
+		if rule, ok := icsRRule(task.RecurrencePattern); ok {
+			ics.prop("RRULE", "", rule)
+		}
+
+		switch {
+		case r.Skipped:
+			ics.prop("STATUS", "", "CANCELLED")
+		case r.Completed:
+			ics.prop("STATUS", "", "COMPLETED")
+			ics.prop("COMPLETED", "", r.UpdatedAt.UTC().Format(icsUTC))
+			ics.prop("PERCENT-COMPLETE", "", "100")
+		default:
+			ics.prop("STATUS", "", "NEEDS-ACTION")
+		}
+		ics.prop("END", "", "VTODO")
+	}
+
+	ics.prop("END", "", "VCALENDAR")
+	if ics.err != nil {
+		return ics.err
+	}
+	return bw.Flush()
+}
+
+// icsWriter writes content lines, folded at 75 octets, and keeps the first
+// error.
+type icsWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ics *icsWriter) prop(name, params, value string) {
+	if ics.err != nil {
+		return
+	}
+	line := name + params + ":" + value
+	for len(line) > 75 {
+		cut := 75
+		// Don't split a UTF-8 sequence.
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if _, ics.err = io.WriteString(ics.w, line[:cut]+"\r\n"); ics.err != nil {
+			return
+		}
+		line = " " + line[cut:]
+	}
+	_, ics.err = io.WriteString(ics.w, line+"\r\n")
+}
+
+// timezone writes a VTIMEZONE for a fixed UTC offset such as "+05:30",
+// which has no IANA name calendar apps would know.
+func (ics *icsWriter) timezone(zone string) {
+	offset := strings.ReplaceAll(zone, ":", "")
+	ics.prop("BEGIN", "", "VTIMEZONE")
+	ics.prop("TZID", "", zone)
+	ics.prop("BEGIN", "", "STANDARD")
+	ics.prop("DTSTART", "", "19700101T000000")
+	ics.prop("TZOFFSETFROM", "", offset)
+	ics.prop("TZOFFSETTO", "", offset)
+	ics.prop("END", "", "STANDARD")
+	ics.prop("END", "", "VTIMEZONE")
+}
+
+func isOffsetZone(zone string) bool {
+	return strings.HasPrefix(zone, "+") || strings.HasPrefix(zone, "-")
+}
+
+// icsSchedule returns the parameters and value of the task's DTSTART.
+func icsSchedule(task *todo.Task) (params, value string) {
+	r := NewRecord(task)
+	if r.Time == "" {
+		return ";VALUE=DATE", task.Date.Format(icsDate)
+	}
+	value = task.Date.Format(icsDateTime)
+	if r.Zone == "" {
+		return "", value
+	}
+	if isOffsetZone(r.Zone) {
+		// Parameter values containing a colon must be quoted.
+		return `;TZID="` + r.Zone + `"`, value
+	}
+	return ";TZID=" + r.Zone, value
+}
+
+var icsWeekdays = map[string]string{
+	"monday": "MO", "tuesday": "TU", "wednesday": "WE", "thursday": "TH",
+	"friday": "FR", "saturday": "SA", "sunday": "SU",
+}
+
+// icsRRule returns the RRULE for a recurrence pattern.
+func icsRRule(pattern recurrence.Pattern) (string, bool) {
+	if pattern == "monthly-last-weekend" {
+		// The last Saturday or Sunday of the month, whichever comes last.
+		return "FREQ=MONTHLY;BYDAY=SA,SU;BYSETPOS=-1", true
+	}
+
+	kind, value, _ := strings.Cut(string(pattern), ":")
+	switch kind {
+	case "weekly":
+		if day, ok := icsWeekdays[value]; ok {
+			return "FREQ=WEEKLY;BYDAY=" + day, true
+		}
+	case "monthly":
+		if day, err := strconv.Atoi(value); err == nil && day > 28 {
+			// Clients skip months without the day, where facienda uses
+			// the month's last day instead: the last of the 28th up to
+			// the day that the month has.
+			return "FREQ=MONTHLY;BYMONTHDAY=" + clampedMonthDays(day) + ";BYSETPOS=-1", true
+		}
+		return "FREQ=MONTHLY;BYMONTHDAY=" + value, true
+	case "monthly-nth-weekday":
+		return "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=" + value, true
+	}
+	return "", false
+}
+
+// clampedMonthDays lists the days of the month from the 28th up to day.
+func clampedMonthDays(day int) string {
+	days := make([]string, 0, day-27)
+	for d := 28; d <= day; d++ {
+		days = append(days, strconv.Itoa(d))
+	}
+	return strings.Join(days, ",")
+}
+
+// icsPattern maps an RRULE back to a recurrence pattern. start is the
+// task's date, which a rule without BYDAY or BYMONTHDAY repeats on.
+func icsPattern(rule string, start time.Time) (recurrence.Pattern, error) {
+	parts := map[string]string{}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		parts[strings.ToUpper(key)] = strings.ToUpper(value)
+	}
+	unsupported := fmt.Errorf("RRULE %q can't be imported (only weekly and monthly rules without an end are supported)", rule)
+
+	if interval, ok := parts["INTERVAL"]; ok && interval != "1" {
+		return "", unsupported
+	}
+	if _, ok := parts["COUNT"]; ok {
+		return "", unsupported
+	}
+	if _, ok := parts["UNTIL"]; ok {
+		return "", unsupported
+	}
+
+	byDay, setPos := parts["BYDAY"], parts["BYSETPOS"]
+	switch parts["FREQ"] {
+	case "WEEKLY":
+		if byDay == "" {
+			return weeklyOn(start), nil
+		}
+		for name, day := range icsWeekdays {
+			if byDay == day && setPos == "" {
+				return recurrence.Pattern("weekly:" + name), nil
+			}
+		}
+	case "MONTHLY":
+		switch {
+		case byDay == "" && setPos == "" && parts["BYMONTHDAY"] == "":
+			return monthlyOn(start), nil
+		case byDay == "" && setPos == "":
+			return recurrence.Pattern("monthly:" + parts["BYMONTHDAY"]), nil
+		case byDay == "" && setPos == "-1":
+			for day := 29; day <= 31; day++ {
+				if parts["BYMONTHDAY"] == clampedMonthDays(day) {
+					return recurrence.Pattern("monthly:" + strconv.Itoa(day)), nil
+				}
+			}
+		case byDay == "MO,TU,WE,TH,FR" && parts["BYMONTHDAY"] == "":
+			return recurrence.Pattern("monthly-nth-weekday:" + setPos), nil
+		case (byDay == "SA,SU" || byDay == "SU,SA") && setPos == "-1":
+			return recurrence.Pattern("monthly-last-weekend"), nil
+		}
+	}
+	return "", unsupported
+}
+
+// ImportICS reads the VTODOs in an iCalendar file; other components, such
+// as events, are ignored. Tasks without DTSTART or DUE are scheduled for
+// today.
+func ImportICS(r io.Reader) ([]Row, error) {
+	lines, err := icsContentLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	var todoLine int
+	var props []icsProperty
+	depth := 0 // nesting below the VTODO, such as a VALARM
+	for _, l := range lines {
+		name, params, value := parseICSLine(l.text)
+		switch {
+		case todoLine == 0 && name == "BEGIN" && strings.EqualFold(value, "VTODO"):
+			todoLine, props = l.number, nil
+		case todoLine == 0:
+		case name == "BEGIN":
+			depth++
+		case name == "END" && depth > 0:
+			depth--
+		case name == "END" && strings.EqualFold(value, "VTODO"):
+			rows = append(rows, icsRow(todoLine, props))
+			todoLine = 0
+		case depth == 0:
+			props = append(props, icsProperty{name, params, value})
+		}
+	}
+	if todoLine != 0 {
+		return nil, fmt.Errorf("VTODO at line %d has no END", todoLine)
+	}
+	return rows, nil
+}
+
+type icsLine struct {
+	number int
+	text   string
+}
+
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// icsContentLines unfolds the file's lines, keeping the number of the line
+// each one starts on.
+func icsContentLines(r io.Reader) ([]icsLine, error) {
+	var lines []icsLine
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		if text != "" {
+			lines = append(lines, icsLine{number, text})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read iCalendar file: %w", err)
+	}
+	return lines, nil
+}
+
+// parseICSLine splits a content line into its upper-cased name, its
+// parameters and its value.
+func parseICSLine(line string) (name string, params map[string]string, value string) {
+	// The value starts at the first colon outside a quoted parameter.
+	inQuotes, colon := false, len(line)
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	head := line[:colon]
+	if colon < len(line) {
+		value = line[colon+1:]
+	}
+
+	fields := strings.Split(head, ";")
+	name = strings.ToUpper(fields[0])
+	params = map[string]string{}
+	for _, field := range fields[1:] {
+		key, v, _ := strings.Cut(field, "=")
+		params[strings.ToUpper(key)] = strings.Trim(v, `"`)
+	}
+	return name, params, value
+}
+
+func icsRow(line int, props []icsProperty) Row {
+	var record Record
+	var start, due, rule *icsProperty
+	var status string
+	for i := range props {
+		p := &props[i]
+		var err error
+		switch p.name {
+		case "UID":
+			record.UID = icsUnescape(p.value)
+		case "SUMMARY":
+			record.Title = icsUnescape(p.value)
+		case "DESCRIPTION":
+			record.Details = icsUnescape(p.value)
+		case "DTSTART":
+			start = p
+		case "DUE":
+			due = p
+		case "RRULE":
+			rule = p
+		case "STATUS":
+			status = strings.ToUpper(p.value)
+		case "CREATED":
+			record.CreatedAt, err = parseICSTimestamp(p)
+		case "LAST-MODIFIED":
+			record.UpdatedAt, err = parseICSTimestamp(p)
+		}
+		if err != nil {
+			return Row{Line: line, Err: err}
+		}
+	}
+
+	var warnings []string
+	if start == nil {
+		start = due
+	}
+	if start == nil {
+		record.Date = time.Now().Format(dateLayout)
+	} else {
+		warning, err := icsScheduleRecord(start, &record)
+		if err != nil {
+			return Row{Line: line, Err: err}
+		}
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	if rule != nil {
+		date, err := time.ParseInLocation(dateLayout, record.Date, time.Local)
+		if err != nil {
+			return Row{Line: line, Err: fmt.Errorf("invalid date %q: %w", record.Date, err)}
+		}
+		if pattern, err := icsPattern(rule.value, date); err == nil {
+			record.Recurrence = string(pattern)
+		} else {
+			warnings = append(warnings, untranslated(rule.value))
+		}
+	}
+
+	record.Completed = status == "COMPLETED"
+	record.Skipped = status == "CANCELLED"
+	row := rowTask(line, record)
+	if row.Err == nil {
+		row.Warning = strings.Join(warnings, "; ")
+	}
+	return row
+}
+
+// icsScheduleRecord sets the record's date, time and zone from a DTSTART or
+// DUE property. Seconds are dropped. A TZID that isn't an IANA zone, such
+// as a Windows zone name, is read as local time and reported in warning.
+func icsScheduleRecord(p *icsProperty, record *Record) (warning string, err error) {
+	value := p.value
+	invalid := fmt.Errorf("invalid %s %q", p.name, value)
+
+	if p.params["VALUE"] == "DATE" || len(value) == len(icsDate) {
+		day, err := time.Parse(icsDate, value)
+		if err != nil {
+			return "", invalid
+		}
+		record.Date = day.Format(dateLayout)
+		return "", nil
+	}
+
+	layout := icsDateTime
+	if strings.HasSuffix(value, "Z") {
+		layout = icsUTC
+		record.Zone = "UTC"
+	} else if tzid := p.params["TZID"]; tzid != "" {
+		if _, err := storage.JoinSchedule("2000-01-01", "00:00", tzid); err == nil {
+			record.Zone = tzid
+		} else {
+			warning = fmt.Sprintf("time zone %q is unknown; imported in local time", tzid)
+		}
+	}
+	at, err := time.Parse(layout, value)
+	if err != nil {
+		return "", invalid
+	}
+	record.Date = at.Format(dateLayout)
+	record.Time = at.Format("15:04")
+	return warning, nil
+}
+
+func parseICSTimestamp(p *icsProperty) (time.Time, error) {
+	t, err := time.Parse(icsUTC, p.value)
+	if err != nil {
+		t, err = time.ParseInLocation(icsDateTime, p.value, time.Local)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q", p.name, p.value)
+	}
+	return t, nil
+}
+
+var (
+	icsEscaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	icsUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+func icsEscape(s string) string   { return icsEscaper.Replace(s) }
+func icsUnescape(s string) string { return icsUnescaper.Replace(s) }
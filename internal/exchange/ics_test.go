@@ -0,0 +1,276 @@
+package exchange
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+func TestICS_RoundTrip(t *testing.T) {
+	tasks := sampleTasks(t)
+	offset, _ := todo.NewTask("Call Mumbai", "", time.Date(2025, 12, 1, 6, 15, 0, 0, time.FixedZone("", 5*3600+30*60)))
+	offset.ID = 4
+	tasks = append(tasks, offset)
+	for i, pattern := range []string{"monthly:15", "monthly:29", "monthly:31", "monthly-nth-weekday:2", "monthly-last-weekend"} {
+		task, _ := todo.NewTask("Recurring "+pattern, "", time.Date(2025, 12, 15, 0, 0, 0, 0, time.Local))
+		task.ID = int64(5 + i)
+		task.RecurrencePattern = recurrence.Pattern(pattern)
+		tasks = append(tasks, task)
+	}
+	tasks[0].UID = "weekly-report@example.com"
+	for _, task := range tasks {
+		task.CreatedAt = task.CreatedAt.Truncate(time.Second)
+		task.UpdatedAt = task.UpdatedAt.Truncate(time.Second)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportICS(&buf, tasks); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	rows, err := ImportICS(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(rows) != len(tasks) {
+		t.Fatalf("expected %d rows, got %d:\n%s", len(tasks), len(rows), buf.String())
+	}
+
+	for i, row := range rows {
+		if row.Err != nil {
+			t.Fatalf("row %d rejected: %v", row.Line, row.Err)
+		}
+		want, got := tasks[i], row.Task
+		if got.Title != want.Title || got.Details != want.Details ||
+			got.Completed != want.Completed || got.Skipped != want.Skipped ||
+			got.RecurrencePattern != want.RecurrencePattern || got.UID != want.StableUID() ||
+			!got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+			t.Errorf("task %d changed in the round trip:\n got %+v\nwant %+v", i, got, want)
+		}
+		gotRecord, wantRecord := NewRecord(got), NewRecord(want)
+		if gotRecord.Date != wantRecord.Date || gotRecord.Time != wantRecord.Time || gotRecord.Zone != wantRecord.Zone {
+			t.Errorf("task %d schedule = %s %s %s, want %s %s %s", i,
+				gotRecord.Date, gotRecord.Time, gotRecord.Zone, wantRecord.Date, wantRecord.Time, wantRecord.Zone)
+		}
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"UID:weekly-report@example.com\r\n",
+		"DTSTART;VALUE=DATE:20251124\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO\r\n",
+		"DTSTART;TZID=Europe/Berlin:20251120T143000\r\n",
+		"SUMMARY:Call\\, \"Berlin\" office\r\n",
+		"STATUS:COMPLETED\r\n",
+		"STATUS:CANCELLED\r\n",
+		"DESCRIPTION:line one\\nline two\r\n",
+		"TZID:+05:30\r\n",
+		"RRULE:FREQ=MONTHLY;BYDAY=SA,SU;BYSETPOS=-1\r\n",
+		"RRULE:FREQ=MONTHLY;BYMONTHDAY=15\r\n",
+		"RRULE:FREQ=MONTHLY;BYMONTHDAY=28,29;BYSETPOS=-1\r\n",
+		"RRULE:FREQ=MONTHLY;BYMONTHDAY=28,29,30,31;BYSETPOS=-1\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in the export", want)
+		}
+	}
+}
+
+func TestExportICS_FoldsLongLines(t *testing.T) {
+	task, _ := todo.NewTask(strings.Repeat("Größe ", 30), "", time.Date(2025, 11, 20, 0, 0, 0, 0, time.Local))
+
+	var buf bytes.Buffer
+	ExportICS(&buf, []*todo.Task{task})
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+
+	rows, err := ImportICS(&buf)
+	if err != nil || len(rows) != 1 || rows[0].Err != nil {
+		t.Fatalf("failed to import: %v %+v", err, rows)
+	}
+	if rows[0].Task.Title != task.Title {
+		t.Errorf("title = %q, want %q", rows[0].Task.Title, task.Title)
+	}
+}
+
+// sampleICS is shaped like a calendar app's export: folded lines, an alarm
+// inside a task, an event and UTC times.
+const sampleICS = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Example Corp//Calendar//EN
+BEGIN:VEVENT
+UID:event-1
+SUMMARY:Not a task
+DTSTART:20251120T100000Z
+END:VEVENT
+BEGIN:VTODO
+UID:todo-1@example.com
+SUMMARY:Submit expenses for the offsite in Lisbon\, including travel a
+ nd hotel
+DTSTART:20251120T163000Z
+STATUS:NEEDS-ACTION
+BEGIN:VALARM
+ACTION:DISPLAY
+DESCRIPTION:Reminder
+TRIGGER:-PT15M
+END:VALARM
+END:VTODO
+BEGIN:VTODO
+UID:todo-2@example.com
+SUMMARY:Pay rent
+DUE;VALUE=DATE:20251201
+RRULE:FREQ=MONTHLY
+STATUS:COMPLETED
+END:VTODO
+BEGIN:VTODO
+UID:todo-3@example.com
+SUMMARY:Gym
+DTSTART;VALUE=DATE:20251120
+RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TH
+END:VTODO
+BEGIN:VTODO
+SUMMARY:
+DTSTART;VALUE=DATE:20251120
+END:VTODO
+END:VCALENDAR
+`
+
+func TestImportICS_CalendarApp(t *testing.T) {
+	rows, err := ImportICS(strings.NewReader(sampleICS))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 tasks, got %d", len(rows))
+	}
+
+	expenses := rows[0].Task
+	if rows[0].Line != 9 || expenses.UID != "todo-1@example.com" ||
+		expenses.Title != "Submit expenses for the offsite in Lisbon, including travel and hotel" {
+		t.Errorf("unexpected task at line %d: %+v", rows[0].Line, expenses)
+	}
+	if !expenses.Date.Equal(time.Date(2025, 11, 20, 16, 30, 0, 0, time.UTC)) || expenses.Date.Location().String() != "UTC" {
+		t.Errorf("expected a UTC time, got %v", expenses.Date)
+	}
+
+	rent := rows[1].Task
+	if !rent.Completed || rent.RecurrencePattern != recurrence.Pattern("monthly:1") ||
+		!rent.Date.Equal(time.Date(2025, 12, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("unexpected task %+v", rent)
+	}
+
+	if rows[2].Err != nil || rows[2].Task.IsRecurring() || !strings.Contains(rows[2].Warning, "INTERVAL=2") {
+		t.Errorf("expected a fortnightly task imported as a one-off with a warning, got %+v", rows[2])
+	}
+	if rows[3].Err == nil {
+		t.Errorf("expected a task without a summary to be rejected")
+	}
+}
+
+func TestImportICS_UnmappedRule(t *testing.T) {
+	rows, err := ImportICS(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:Stretch\r\n" +
+		"DTSTART;VALUE=DATE:20251120\r\nRRULE:FREQ=DAILY\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"))
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("failed to import: %v %+v", err, rows)
+	}
+	stretch := rows[0]
+	if stretch.Err != nil || stretch.Task.IsRecurring() || !strings.Contains(stretch.Warning, `"FREQ=DAILY"`) {
+		t.Errorf("expected a one-off task with a warning, got %+v", stretch)
+	}
+}
+
+func TestImportICS_UnknownZone(t *testing.T) {
+	rows, err := ImportICS(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:Call Berlin\r\n" +
+		"DTSTART;TZID=W. Europe Standard Time:20251120T143000\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"))
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("failed to import: %v %+v", err, rows)
+	}
+	call := rows[0]
+	if call.Err != nil || !strings.Contains(call.Warning, `"W. Europe Standard Time"`) {
+		t.Fatalf("expected the task imported with a warning, got %+v", call)
+	}
+	if !call.Task.Date.Equal(time.Date(2025, 11, 20, 14, 30, 0, 0, time.Local)) || call.Task.Date.Location() != time.Local {
+		t.Errorf("expected local time, got %v", call.Task.Date)
+	}
+}
+
+func TestNewPlan_CompletesRecurringByUID(t *testing.T) {
+	monday := time.Date(2025, 11, 24, 0, 0, 0, 0, time.Local)
+	plants, _ := todo.NewRecurringTask("Water plants", "", recurrence.Pattern("weekly:monday"))
+	plants.ID = 3
+	plants.Date = monday
+	plants.UID = "plants@example.com"
+	plants.Owner = "carol"
+
+	var buf bytes.Buffer
+	ExportICS(&buf, []*todo.Task{plants})
+	edited := strings.Replace(buf.String(), "STATUS:NEEDS-ACTION", "STATUS:COMPLETED", 1)
+	// The calendar app already created the next occurrence itself.
+	edited = strings.Replace(edited, "END:VCALENDAR", "BEGIN:VTODO\r\nUID:plants-2@example.com\r\nSUMMARY:Water plants\r\n"+
+		"DTSTART;VALUE=DATE:20251201\r\nRRULE:FREQ=WEEKLY;BYDAY=MO\r\nEND:VTODO\r\nEND:VCALENDAR", 1)
+
+	rows, err := ImportICS(strings.NewReader(edited))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	plan := NewPlan(rows, []*todo.Task{plants})
+	if len(plan.Updates) != 1 || plan.Updates[0].ID != 3 || !plan.Updates[0].Completed {
+		t.Fatalf("expected the task to be completed, got updates %+v", plan.Updates)
+	}
+	if len(plan.New) != 1 || !plan.New[0].Date.Equal(monday.AddDate(0, 0, 7)) || plan.New[0].Owner != "carol" {
+		t.Errorf("expected the next occurrence to be created once, got %+v", plan.New)
+	}
+	if len(plan.Duplicates) != 1 {
+		t.Errorf("expected the app's own next occurrence to be a duplicate, got %+v", plan.Duplicates)
+	}
+}
+
+func TestNewPlan_UpdatesByUID(t *testing.T) {
+	day := time.Date(2025, 11, 20, 0, 0, 0, 0, time.Local)
+	imported, _ := todo.NewTask("Plan sprint", "", day)
+	imported.ID = 7
+	imported.UID = "sprint@example.com"
+	local, _ := todo.NewTask("Local task", "", day)
+	local.ID = 8
+
+	var buf bytes.Buffer
+	ExportICS(&buf, []*todo.Task{imported, local})
+	edited := strings.Replace(buf.String(), "SUMMARY:Plan sprint", "SUMMARY:Plan the sprint", 1)
+	edited = strings.Replace(edited, "SUMMARY:Local task\r\nDTSTART;VALUE=DATE:20251120\r\nDUE;VALUE=DATE:20251120\r\nSTATUS:NEEDS-ACTION",
+		"SUMMARY:Local task\r\nDTSTART;VALUE=DATE:20251120\r\nDUE;VALUE=DATE:20251120\r\nSTATUS:COMPLETED", 1)
+
+	rows, err := ImportICS(strings.NewReader(edited))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	plan := NewPlan(rows, []*todo.Task{imported, local})
+	if len(plan.New) != 0 || len(plan.Updates) != 2 {
+		t.Fatalf("expected 2 updates and no new tasks, got %+v", plan)
+	}
+	if plan.Updates[0].ID != 7 || plan.Updates[0].Title != "Plan the sprint" {
+		t.Errorf("unexpected update %+v", plan.Updates[0])
+	}
+	if plan.Updates[1].ID != 8 || !plan.Updates[1].Completed || plan.Updates[1].UID != local.StableUID() {
+		t.Errorf("unexpected update %+v", plan.Updates[1])
+	}
+
+	rows, _ = ImportICS(&buf)
+	if plan := NewPlan(rows, []*todo.Task{imported, local}); len(plan.Duplicates) != 2 {
+		t.Errorf("expected an unchanged file to only have duplicates, got %+v", plan)
+	}
+
+	// A UID repeated within the file is only imported once.
+	again, _ := todo.NewTask("Plan sprint again", "", day.AddDate(0, 0, 1))
+	again.UID = imported.UID
+	fresh := &todo.Task{Title: "Fresh", Date: day, UID: "fresh@example.com"}
+	rows = []Row{{Task: fresh}, {Task: imported}, {Task: again}, {Task: fresh}}
+	if plan := NewPlan(rows, []*todo.Task{local}); len(plan.New) != 2 || len(plan.Duplicates) != 2 {
+		t.Errorf("expected 2 new tasks and 2 duplicates, got %+v", plan)
+	}
+}
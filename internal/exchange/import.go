@@ -0,0 +1,224 @@
+package exchange
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// Row is one task read from an import file, or the reason it couldn't be
+// read.
+type Row struct {
+	// Line is where the task starts in the file: the line number for
+	// line-based formats, the position in the task list for JSON.
+	Line int
+	Task *todo.Task
+	Err  error
+	// Warning describes something about the task that couldn't be
+	// imported, although the task itself was.
+	Warning string
+}
+
+// Task converts the record to a new task, validating it as the add command
+// would. The ID is not kept, since the importing store assigns its own;
+// missing timestamps are set to now.
+func (r Record) Task() (*todo.Task, error) {
+	date, err := storage.JoinSchedule(r.Date, r.Time, r.Zone)
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := parseRecurrence(r.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := todo.NewTask(r.Title, r.Details, date)
+	if err != nil {
+		return nil, err
+	}
+	task.Completed = r.Completed
+	task.Skipped = r.Skipped
+	task.RecurrencePattern = pattern
+	task.UID = r.UID
+	task.Owner = r.Owner
+	task.Assignee = r.Assignee
+	if !r.CreatedAt.IsZero() {
+		task.CreatedAt = r.CreatedAt
+	}
+	if !r.UpdatedAt.IsZero() {
+		task.UpdatedAt = r.UpdatedAt
+	} else if task.UpdatedAt.Before(task.CreatedAt) {
+		task.UpdatedAt = task.CreatedAt
+	}
+	return task, nil
+}
+
+// parseRecurrence accepts a pattern in its stored form, as exports write
+// it ("weekly:monday"), or as the add command's --recur flag takes it
+// ("every monday").
+func parseRecurrence(value string) (recurrence.Pattern, error) {
+	if pattern := recurrence.Pattern(value); pattern.Validate() == nil {
+		return pattern, nil
+	}
+	pattern, err := recurrence.ParsePattern(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid recurrence %q: %w", value, err)
+	}
+	return pattern, nil
+}
+
+// Plan is what an import would change in a store.
+type Plan struct {
+	// New are the tasks to create.
+	New []*todo.Task
+	// Updates are existing tasks, changed to match the rows with their UID.
+	Updates []*todo.Task
+	// Duplicates are rows for tasks the store, or an earlier row, already
+	// has.
+	Duplicates []Row
+	// Rejected are rows that couldn't be read or failed validation.
+	Rejected []Row
+	// Warnings are imported rows with a Warning.
+	Warnings []Row
+}
+
+// NewPlan sorts imported rows into new tasks, updates of existing tasks,
+// duplicates and rejected rows.
+//
+// A row with a UID matches the existing task with that UID (see
+// todo.Task.StableUID), which is updated if the row differs from it; a
+// later row with the same UID is a duplicate, since UIDs are unique. Other
+// rows are duplicates when a task with the same title is on the same
+// calendar date, so importing a file twice doesn't double its tasks; if the
+// row is completed and the existing task isn't, the task is completed. A
+// recurring task completed this way, or completed or skipped through its
+// UID, gets its next occurrence as with the complete and skip commands.
+func NewPlan(rows []Row, existing []*todo.Task) *Plan {
+	seen := make(map[string]bool, len(existing))
+	byKey := make(map[string]*todo.Task, len(existing))
+	byUID := make(map[string]*todo.Task, len(existing))
+	for _, task := range existing {
+		seen[dedupeKey(task)] = true
+		byKey[dedupeKey(task)] = task
+		byUID[task.StableUID()] = task
+	}
+
+	plan := &Plan{}
+	inFile := make(map[string]bool)
+	for _, row := range rows {
+		if row.Err != nil {
+			plan.Rejected = append(plan.Rejected, row)
+			continue
+		}
+		if row.Warning != "" {
+			plan.Warnings = append(plan.Warnings, row)
+		}
+
+		if uid := row.Task.UID; uid != "" {
+			if inFile[uid] {
+				plan.Duplicates = append(plan.Duplicates, row)
+				continue
+			}
+			inFile[uid] = true
+		}
+		if current, ok := byUID[row.Task.UID]; ok && row.Task.UID != "" {
+			updated := *row.Task
+			// Most formats have no owner or assignee; the task keeps its own.
+			if updated.Owner == "" {
+				updated.Owner = current.Owner
+			}
+			if updated.Assignee == "" {
+				updated.Assignee = current.Assignee
+			}
+			if sameContent(current, &updated) {
+				plan.Duplicates = append(plan.Duplicates, row)
+				continue
+			}
+			updated.ID = current.ID
+			updated.CreatedAt = current.CreatedAt
+			plan.Updates = append(plan.Updates, &updated)
+
+			finished := (updated.Completed && !current.Completed) || (updated.Skipped && !current.Skipped)
+			if next, err := updated.GenerateNextInstance(); finished && err == nil && next != nil && !seen[dedupeKey(next)] {
+				seen[dedupeKey(next)] = true
+				plan.New = append(plan.New, next)
+			}
+			continue
+		}
+
+		key := dedupeKey(row.Task)
+		if current := byKey[key]; current != nil && row.Task.Completed && !current.Completed {
+			updated := *current
+			updated.Complete()
+			plan.Updates = append(plan.Updates, &updated)
+			byKey[key] = &updated
+
+			if next, err := updated.GenerateNextInstance(); err == nil && next != nil && !seen[dedupeKey(next)] {
+				seen[dedupeKey(next)] = true
+				plan.New = append(plan.New, next)
+			}
+			continue
+		}
+		if seen[key] {
+			plan.Duplicates = append(plan.Duplicates, row)
+			continue
+		}
+		seen[key] = true
+		plan.New = append(plan.New, row.Task)
+	}
+	return plan
+}
+
+func dedupeKey(task *todo.Task) string {
+	return task.Date.Format(dateLayout) + "\x00" + task.Title
+}
+
+// sameContent reports whether two tasks have the same user-visible fields.
+func sameContent(a, b *todo.Task) bool {
+	aDate, aClock, aZone := storage.SplitSchedule(a.Date)
+	bDate, bClock, bZone := storage.SplitSchedule(b.Date)
+	return a.Title == b.Title && a.Details == b.Details &&
+		aDate == bDate && aClock == bClock && aZone == bZone &&
+		a.Completed == b.Completed && a.Skipped == b.Skipped &&
+		a.RecurrencePattern == b.RecurrencePattern &&
+		a.Owner == b.Owner && a.Assignee == b.Assignee
+}
+
+// rowTask is a helper for importers: it returns the Row for a record read
+// at line.
+func rowTask(line int, record Record) Row {
+	task, err := record.Task()
+	return Row{Line: line, Task: task, Err: err}
+}
+
+// weeklyOn returns the weekly pattern that repeats on date's weekday.
+func weeklyOn(date time.Time) recurrence.Pattern {
+	return recurrence.Pattern("weekly:" + strings.ToLower(date.Weekday().String()))
+}
+
+// monthlyOn returns the monthly pattern that repeats on date's day of the
+// month.
+func monthlyOn(date time.Time) recurrence.Pattern {
+	return recurrence.Pattern(fmt.Sprintf("monthly:%d", date.Day()))
+}
+
+// untranslated is the warning for a recurrence with no equivalent pattern.
+func untranslated(value string) string {
+	return fmt.Sprintf("recurrence %q could not be translated; imported as a one-off task", value)
+}
+
+// parseTimestamp parses an optional RFC 3339 timestamp.
+func parseTimestamp(field, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q (use RFC 3339)", field, value)
+	}
+	return t, nil
+}
@@ -0,0 +1,169 @@
+package exchange
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+func TestImport_RoundTrip(t *testing.T) {
+	// JSON and CSV keep every field; the other formats have their own
+	// round-trip tests for the fields they can represent.
+	for _, name := range []string{"json", "csv"} {
+		format, _ := Lookup(name)
+		t.Run(format.Name, func(t *testing.T) {
+			tasks := sampleTasks(t)
+
+			var buf bytes.Buffer
+			if err := format.Export(&buf, tasks); err != nil {
+				t.Fatalf("failed to export: %v", err)
+			}
+			rows, err := format.Import(&buf)
+			if err != nil {
+				t.Fatalf("failed to import: %v", err)
+			}
+			if len(rows) != len(tasks) {
+				t.Fatalf("expected %d rows, got %d", len(tasks), len(rows))
+			}
+
+			for i, row := range rows {
+				if row.Err != nil {
+					t.Fatalf("row %d rejected: %v", row.Line, row.Err)
+				}
+				want, got := tasks[i], row.Task
+				if got.Title != want.Title || got.Details != want.Details ||
+					got.Completed != want.Completed || got.Skipped != want.Skipped ||
+					got.RecurrencePattern != want.RecurrencePattern ||
+					!got.Date.Equal(want.Date) || got.Date.Location().String() != want.Date.Location().String() ||
+					!got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+					t.Errorf("task %d changed in the round trip:\n got %+v\nwant %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestImportCSV_RejectsInvalidRows(t *testing.T) {
+	input := `title,date,time,completed,recurrence
+Plan sprint,2025-11-20,,,
+,2025-11-20,,,
+Review,2025-13-01,,,
+Standup,2025-11-20,25:00,,
+Report,2025-11-21,,maybe,
+Pay rent,2025-12-01,,,1st of each month
+Gardening,2025-12-01,,,every fortnight
+`
+	rows, err := ImportCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+
+	var rejected []int
+	for _, row := range rows {
+		if row.Err != nil {
+			rejected = append(rejected, row.Line)
+		}
+	}
+	want := []int{3, 4, 5, 6, 8}
+	if len(rejected) != len(want) {
+		t.Fatalf("rejected lines %v, want %v", rejected, want)
+	}
+	for i := range want {
+		if rejected[i] != want[i] {
+			t.Fatalf("rejected lines %v, want %v", rejected, want)
+		}
+	}
+
+	if rows[5].Task.RecurrencePattern != recurrence.Pattern("monthly:1") {
+		t.Errorf("expected a human recurrence to be parsed, got %q", rows[5].Task.RecurrencePattern)
+	}
+}
+
+func TestImportCSV_MalformedRow(t *testing.T) {
+	input := "title,date\nPlan sprint,2025-11-20\nBad \"quote,2025-11-21\nReview,2025-11-22\n"
+	rows, err := ImportCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+	if rows[1].Err == nil || rows[1].Line != 3 {
+		t.Errorf("expected line 3 to be rejected, got line %d: %v", rows[1].Line, rows[1].Err)
+	}
+	if rows[0].Err != nil || rows[2].Err != nil || rows[2].Line != 4 {
+		t.Errorf("expected the other rows to be imported, got %+v and %+v", rows[0], rows[2])
+	}
+}
+
+func TestImportCSV_RequiresHeader(t *testing.T) {
+	if _, err := ImportCSV(strings.NewReader("name,when\nx,y\n")); err == nil {
+		t.Error("expected an error for a header without title and date")
+	}
+	if _, err := ImportCSV(strings.NewReader("")); err == nil {
+		t.Error("expected an error for an empty file")
+	}
+}
+
+func TestImportJSON_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"not JSON", `title,date`},
+		{"missing version", `{"tasks": []}`},
+		{"newer version", `{"schema_version": 99, "tasks": []}`},
+	}
+	for _, tt := range tests {
+		if _, err := ImportJSON(strings.NewReader(tt.input)); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	rows, err := ImportJSON(strings.NewReader(`{"schema_version": 1, "tasks": [
+		{"title": "Good", "date": "2025-11-20"},
+		{"title": "Bad type", "date": "2025-11-20", "completed": "yes"},
+		{"title": "Bad recurrence", "date": "2025-11-20", "recurrence": "weekly:someday"}
+	]}`))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(rows) != 3 || rows[0].Err != nil || rows[1].Err == nil || rows[2].Err == nil {
+		t.Errorf("expected only the first task to be accepted, got %+v", rows)
+	}
+	if rows[2].Line != 3 {
+		t.Errorf("expected the row to be numbered by position, got %d", rows[2].Line)
+	}
+}
+
+func TestNewPlan_Dedupes(t *testing.T) {
+	day := time.Date(2025, 11, 20, 0, 0, 0, 0, time.Local)
+	existing, _ := todo.NewTask("Plan sprint", "", day)
+
+	newTask := func(title string, date time.Time) Row {
+		task, _ := todo.NewTask(title, "", date)
+		return Row{Task: task}
+	}
+	rows := []Row{
+		newTask("Plan sprint", day),
+		newTask("Plan sprint", day.AddDate(0, 0, 1)),
+		newTask("Review", day),
+		newTask("Review", day),
+		{Line: 5, Err: todo.ErrEmptyTitle},
+	}
+
+	plan := NewPlan(rows, []*todo.Task{existing})
+	if len(plan.New) != 2 || plan.New[0].Title != "Plan sprint" || plan.New[1].Title != "Review" {
+		t.Errorf("unexpected new tasks %+v", plan.New)
+	}
+	if len(plan.Duplicates) != 2 {
+		t.Errorf("expected 2 duplicates, got %d", len(plan.Duplicates))
+	}
+	if len(plan.Rejected) != 1 || plan.Rejected[0].Line != 5 {
+		t.Errorf("unexpected rejected rows %+v", plan.Rejected)
+	}
+}
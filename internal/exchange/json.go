@@ -0,0 +1,129 @@
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// SchemaVersion is the version of the JSON export format. It is increased
+// when a field is removed or changes meaning; fields may be added without a
+// new version, so readers should ignore fields they don't know.
+const SchemaVersion = 1
+
+const dateLayout = "2006-01-02"
+
+// Document is the JSON export format:
+//
+//	{
+//	  "schema_version": 1,
+//	  "exported_at": "2025-11-20T08:30:00Z",
+//	  "tasks": [ ... ]
+//	}
+type Document struct {
+	SchemaVersion int       `json:"schema_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+	Tasks         []Record  `json:"tasks"`
+}
+
+// Record is one task in the JSON and CSV formats.
+type Record struct {
+	ID      int64  `json:"id"`
+	Title   string `json:"title"`
+	Details string `json:"details"`
+	// Date is the calendar date the task is scheduled on, as YYYY-MM-DD.
+	Date string `json:"date"`
+	// Time is the time of day as HH:MM, empty for whole-day tasks.
+	Time string `json:"time,omitempty"`
+	// Zone is the time zone of Time: an IANA name such as "Europe/Berlin"
+	// or a UTC offset such as "+05:30". It is empty for times in the local
+	// zone of whoever reads the task.
+	Zone      string `json:"zone,omitempty"`
+	Completed bool   `json:"completed"`
+	Skipped   bool   `json:"skipped"`
+	// Recurrence is the stored recurrence pattern, such as "weekly:monday"
+	// or "monthly:15", empty for one-off tasks.
+	Recurrence string    `json:"recurrence"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// UID is the identifier the task was imported with, such as a calendar
+	// entry's UID. Importing a record whose UID matches an existing task
+	// updates that task.
+	UID string `json:"uid,omitempty"`
+	// Owner and Assignee are the users of the server mode who own and are
+	// to do the task, empty if there are none.
+	Owner    string `json:"owner,omitempty"`
+	Assignee string `json:"assignee,omitempty"`
+}
+
+// NewRecord converts task to its exported form.
+func NewRecord(task *todo.Task) Record {
+	date, clock, zone := storage.SplitSchedule(task.Date)
+	return Record{
+		ID:         task.ID,
+		Title:      task.Title,
+		Details:    task.Details,
+		Date:       date,
+		Time:       clock,
+		Zone:       zone,
+		Completed:  task.Completed,
+		Skipped:    task.Skipped,
+		Recurrence: string(task.RecurrencePattern),
+		CreatedAt:  task.CreatedAt,
+		UpdatedAt:  task.UpdatedAt,
+		UID:        task.UID,
+		Owner:      task.Owner,
+		Assignee:   task.Assignee,
+	}
+}
+
+// ExportJSON writes tasks as a Document.
+func ExportJSON(w io.Writer, tasks []*todo.Task) error {
+	doc := Document{
+		SchemaVersion: SchemaVersion,
+		ExportedAt:    time.Now().UTC(),
+		Tasks:         make([]Record, len(tasks)),
+	}
+	for i, task := range tasks {
+		doc.Tasks[i] = NewRecord(task)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// ImportJSON reads a Document. Documents from newer, incompatible versions
+// of facienda are refused; a task that doesn't decode is rejected on its
+// own.
+func ImportJSON(r io.Reader) ([]Row, error) {
+	var doc struct {
+		SchemaVersion *int              `json:"schema_version"`
+		Tasks         []json.RawMessage `json:"tasks"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if doc.SchemaVersion == nil {
+		return nil, errors.New("not a facienda export: schema_version is missing")
+	}
+	if *doc.SchemaVersion < 1 || *doc.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d (this version of facienda reads version %d)", *doc.SchemaVersion, SchemaVersion)
+	}
+
+	rows := make([]Row, len(doc.Tasks))
+	for i, raw := range doc.Tasks {
+		var record Record
+		if err := json.Unmarshal(raw, &record); err != nil {
+			rows[i] = Row{Line: i + 1, Err: err}
+			continue
+		}
+		rows[i] = rowTask(i+1, record)
+	}
+	return rows, nil
+}
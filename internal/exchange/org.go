@@ -0,0 +1,400 @@
+package exchange
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// Org-mode files keep tasks as headlines with a TODO keyword:
+//
+//	#+TODO: TODO | DONE CANCELLED
+//
+//	* TODO [#A] Weekly report
+//	  SCHEDULED: <2025-11-24 Mon +1w>
+//	  :PROPERTIES:
+//	  :ID: 1-1763370723@facienda
+//	  :END:
+//	  Send to the whole team.
+//	* DONE Call the bank
+//	  CLOSED: [2025-11-20 Thu 15:02] SCHEDULED: <2025-11-20 Thu 14:30>
+//
+// The task's date is its SCHEDULED timestamp, so tasks show up in the
+// agenda on the day they are planned for. DONE marks completed tasks and
+// CANCELLED skipped ones; the body below the headline is the details.
+// Weekly and monthly recurrences are written as +1w and +1m repeaters,
+// which repeat from the scheduled date; recurrences with no repeater
+// equivalent are written in their stored form as a RECURRENCE property.
+// Org timestamps have no time zone, so a zone is kept in a TIMEZONE
+// property. The priority is kept in the title as a pri: token, as for
+// todo.txt, and written back as a priority cookie.
+
+const orgTimestampDate = "2006-01-02 Mon"
+
+var (
+	orgHeadline = regexp.MustCompile(`^(\*+)\s+(.*?)\s*$`)
+	orgPriority = regexp.MustCompile(`^\[#([A-Z0-9])\]\s*`)
+	orgTags     = regexp.MustCompile(`\s+:([\w@#%:]+):$`)
+	orgPlanning = regexp.MustCompile(`(SCHEDULED|DEADLINE|CLOSED):\s*([<\[][^>\]]*[>\]])`)
+	// orgPlanningLine is the line right below a headline with its
+	// SCHEDULED, DEADLINE and CLOSED timestamps.
+	orgPlanningLine = regexp.MustCompile(`^(SCHEDULED|DEADLINE|CLOSED):`)
+	orgDrawer       = regexp.MustCompile(`^:[\w-]+:$`)
+	orgProperty     = regexp.MustCompile(`^:([^:\s]+):\s*(.*)$`)
+	orgRepeater     = regexp.MustCompile(`^(?:\+|\+\+|\.\+)(\d+)([hdwmy])$`)
+	orgClock        = regexp.MustCompile(`^(\d{1,2}:\d{2})(?:-\d{1,2}:\d{2})?$`)
+)
+
+// ExportOrg writes tasks as Org-mode headlines.
+func ExportOrg(w io.Writer, tasks []*todo.Task) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#+TODO: TODO | DONE CANCELLED")
+	for _, task := range tasks {
+		fmt.Fprintln(bw)
+		writeOrgTask(bw, task)
+	}
+	return bw.Flush()
+}
+
+func writeOrgTask(w io.Writer, task *todo.Task) {
+	r := NewRecord(task)
+
+	keyword := "TODO"
+	switch {
+	case r.Skipped:
+		keyword = "CANCELLED"
+	case r.Completed:
+		keyword = "DONE"
+	}
+	headline := []string{"*", keyword}
+	var title []string
+	for _, token := range strings.Fields(r.Title) {
+		if p, ok := strings.CutPrefix(token, "pri:"); ok && len(headline) == 2 && orgPriority.MatchString("[#"+p+"]") {
+			headline = append(headline, "[#"+p+"]")
+			continue
+		}
+		title = append(title, token)
+	}
+	fmt.Fprintln(w, strings.Join(append(headline, title...), " "))
+
+	scheduled := r.Date + " " + task.Date.Format("Mon")
+	if r.Time != "" {
+		scheduled += " " + r.Time
+	}
+	repeater, ok := orgRepeaterFor(task)
+	if ok && repeater != "" {
+		scheduled += " " + repeater
+	}
+	planning := "SCHEDULED: <" + scheduled + ">"
+	if r.Completed {
+		planning = "CLOSED: [" + r.UpdatedAt.In(time.Local).Format(orgTimestampDate+" 15:04") + "] " + planning
+	}
+	fmt.Fprintf(w, "  %s\n", planning)
+
+	fmt.Fprintln(w, "  :PROPERTIES:")
+	fmt.Fprintf(w, "  :ID: %s\n", task.StableUID())
+	if r.Zone != "" {
+		fmt.Fprintf(w, "  :TIMEZONE: %s\n", r.Zone)
+	}
+	if !ok {
+		fmt.Fprintf(w, "  :RECURRENCE: %s\n", r.Recurrence)
+	}
+	fmt.Fprintln(w, "  :END:")
+
+	if r.Details != "" {
+		for _, line := range strings.Split(r.Details, "\n") {
+			if line == "" {
+				fmt.Fprintln(w)
+			} else {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+		}
+	}
+}
+
+// orgRepeaterFor returns the repeater for a task's recurrence, which is
+// empty for one-off tasks, or false if the recurrence has none.
+func orgRepeaterFor(task *todo.Task) (string, bool) {
+	switch task.RecurrencePattern {
+	case recurrence.PatternNone:
+		return "", true
+	case weeklyOn(task.Date):
+		return "+1w", true
+	case monthlyOn(task.Date):
+		return "+1m", true
+	default:
+		return "", false
+	}
+}
+
+// orgKeywords are the TODO keywords an Org file uses. Besides the ones a
+// #+TODO line declares, the keywords common in Org configurations are
+// recognised, since they are usually declared in Emacs rather than in the
+// file.
+type orgKeywords map[string]orgState
+
+type orgState int
+
+const (
+	orgOpen orgState = iota + 1
+	orgDone
+	orgCancelled
+)
+
+func defaultOrgKeywords() orgKeywords {
+	return orgKeywords{
+		"TODO": orgOpen, "NEXT": orgOpen, "STARTED": orgOpen, "WAITING": orgOpen, "HOLD": orgOpen,
+		"DONE": orgDone, "CANCELLED": orgCancelled, "CANCELED": orgCancelled,
+	}
+}
+
+// declare adds the keywords of a #+TODO line such as "TODO NEXT | DONE
+// CANCELLED". Keywords after the bar are done states; without a bar, only
+// the last one is. Fast-access keys, as in "WAIT(w@)", are ignored.
+func (k orgKeywords) declare(line string) {
+	words := strings.Fields(line)
+	bar := len(words) - 1
+	for i, word := range words {
+		if word == "|" {
+			bar = i
+		}
+	}
+	for i, word := range words {
+		if word == "|" {
+			continue
+		}
+		word, _, _ = strings.Cut(word, "(")
+		switch {
+		case i < bar:
+			k[word] = orgOpen
+		case strings.HasPrefix(word, "CANCEL"):
+			k[word] = orgCancelled
+		default:
+			k[word] = orgDone
+		}
+	}
+}
+
+// orgEntry is a task headline and the lines below it.
+type orgEntry struct {
+	line       int
+	state      orgState
+	title      string
+	planning   map[string]string
+	properties map[string]string
+	body       []string
+}
+
+// ImportOrg reads the headlines with a TODO keyword in an Org file,
+// including nested ones; other headlines and text outside tasks are
+// ignored. A task without a SCHEDULED timestamp is scheduled on its
+// DEADLINE, or otherwise for today.
+func ImportOrg(r io.Reader) ([]Row, error) {
+	keywords := defaultOrgKeywords()
+	var entries []*orgEntry
+	var current *orgEntry
+	drawer := ""
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(text)
+
+		if m := orgHeadline.FindStringSubmatch(text); m != nil {
+			current, drawer = parseOrgHeadline(line, m[2], keywords), ""
+			if current != nil {
+				entries = append(entries, current)
+			}
+			continue
+		}
+		if current == nil {
+			for _, prefix := range []string{"#+TODO:", "#+SEQ_TODO:", "#+TYP_TODO:"} {
+				if len(trimmed) > len(prefix) && strings.EqualFold(trimmed[:len(prefix)], prefix) {
+					keywords.declare(trimmed[len(prefix):])
+				}
+			}
+			continue
+		}
+
+		switch {
+		case drawer != "":
+			if strings.EqualFold(trimmed, ":END:") {
+				drawer = ""
+			} else if m := orgProperty.FindStringSubmatch(trimmed); m != nil && drawer == "PROPERTIES" {
+				current.properties[strings.ToUpper(m[1])] = m[2]
+			}
+		case len(current.body) == 0 && orgPlanningLine.MatchString(trimmed):
+			for _, m := range orgPlanning.FindAllStringSubmatch(trimmed, -1) {
+				current.planning[m[1]] = m[2]
+			}
+		case orgDrawer.MatchString(trimmed):
+			drawer = strings.ToUpper(strings.Trim(trimmed, ":"))
+		default:
+			current.body = append(current.body, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Org file: %w", err)
+	}
+
+	rows := make([]Row, len(entries))
+	for i, entry := range entries {
+		rows[i] = entry.row()
+	}
+	return rows, nil
+}
+
+// parseOrgHeadline returns the entry for a headline, or nil if it isn't a
+// task.
+func parseOrgHeadline(line int, text string, keywords orgKeywords) *orgEntry {
+	keyword, title, _ := strings.Cut(text, " ")
+	state := keywords[keyword]
+	if state == 0 {
+		return nil
+	}
+
+	var tokens []string
+	if m := orgPriority.FindStringSubmatch(title); m != nil {
+		tokens = append(tokens, "pri:"+m[1])
+		title = title[len(m[0]):]
+	}
+	var tags []string
+	if m := orgTags.FindStringSubmatch(title); m != nil {
+		for _, tag := range strings.Split(m[1], ":") {
+			if tag != "" {
+				tags = append(tags, "@"+tag)
+			}
+		}
+		title = title[:len(title)-len(m[0])]
+	}
+	tokens = append(tokens, strings.Fields(title)...)
+	tokens = append(tokens, tags...)
+
+	return &orgEntry{
+		line:       line,
+		state:      state,
+		title:      strings.Join(tokens, " "),
+		planning:   map[string]string{},
+		properties: map[string]string{},
+	}
+}
+
+func (e *orgEntry) row() Row {
+	record := Record{
+		Title:     e.title,
+		Details:   orgDetails(e.body),
+		Completed: e.state == orgDone,
+		Skipped:   e.state == orgCancelled,
+		Zone:      e.properties["TIMEZONE"],
+		UID:       e.properties["ID"],
+	}
+
+	timestamp, ok := e.planning["SCHEDULED"]
+	if !ok {
+		timestamp, ok = e.planning["DEADLINE"]
+	}
+	repeater := ""
+	if ok {
+		var err error
+		if record.Date, record.Time, repeater, err = parseOrgTimestamp(timestamp); err != nil {
+			return Row{Line: e.line, Err: err}
+		}
+	} else {
+		record.Date = time.Now().Format(dateLayout)
+	}
+	if closed, ok := e.planning["CLOSED"]; ok && record.Completed {
+		date, clock, _, err := parseOrgTimestamp(closed)
+		if err != nil {
+			return Row{Line: e.line, Err: err}
+		}
+		if clock == "" {
+			clock = "00:00"
+		}
+		record.UpdatedAt, _ = time.ParseInLocation(dateLayout+" 15:04", date+" "+clock, time.Local)
+	}
+
+	warning := ""
+	if pattern, ok := e.properties["RECURRENCE"]; ok {
+		record.Recurrence = pattern
+	} else if repeater != "" {
+		date, _ := time.ParseInLocation(dateLayout, record.Date, time.Local)
+		if pattern, ok := orgPattern(repeater, date); ok {
+			record.Recurrence = string(pattern)
+		} else {
+			warning = untranslated(repeater)
+		}
+	}
+
+	row := rowTask(e.line, record)
+	if row.Err == nil {
+		row.Warning = warning
+	}
+	return row
+}
+
+// parseOrgTimestamp splits a timestamp such as "<2025-11-24 Mon 09:30
+// +1w>" into its date, time of day and repeater. A time range keeps its
+// start, and warning delays such as "-2d" are ignored.
+func parseOrgTimestamp(timestamp string) (date, clock, repeater string, err error) {
+	fields := strings.Fields(strings.Trim(timestamp, "<>[]"))
+	if len(fields) == 0 || !todoTxtDate.MatchString(fields[0]) {
+		return "", "", "", fmt.Errorf("invalid timestamp %s", timestamp)
+	}
+	if _, err := time.Parse(dateLayout, fields[0]); err != nil {
+		return "", "", "", fmt.Errorf("invalid timestamp %s", timestamp)
+	}
+	date = fields[0]
+	for _, field := range fields[1:] {
+		if m := orgClock.FindStringSubmatch(field); m != nil {
+			clock = m[1]
+			if len(clock) == 4 {
+				clock = "0" + clock
+			}
+		} else if orgRepeater.MatchString(field) {
+			repeater = field
+		}
+	}
+	return date, clock, repeater, nil
+}
+
+// orgPattern maps a repeater to the pattern that repeats from date: +1w
+// (or +7d) weekly and +1m monthly, in any of Org's repeater styles.
+func orgPattern(repeater string, date time.Time) (recurrence.Pattern, bool) {
+	m := orgRepeater.FindStringSubmatch(repeater)
+	if m == nil {
+		return "", false
+	}
+	switch m[1] + m[2] {
+	case "1w", "7d":
+		return weeklyOn(date), true
+	case "1m":
+		return monthlyOn(date), true
+	default:
+		return "", false
+	}
+}
+
+// orgDetails joins body lines, removing the indentation they share and
+// the blank lines around them.
+func orgDetails(body []string) string {
+	indent := -1
+	for _, line := range body {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(line) - len(strings.TrimLeft(line, " \t")); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	lines := make([]string, len(body))
+	for i, line := range body {
+		lines[i] = trimIndent(line, indent)
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
@@ -0,0 +1,170 @@
+package exchange
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+const sampleOrg = `#+TITLE: Agenda
+#+TODO: TODO WAIT(w@) | DONE CANCELED(c@)
+
+* Work
+** TODO [#A] Weekly report                                       :work:
+   SCHEDULED: <2025-11-24 Mon 09:30 +1w>
+   :PROPERTIES:
+   :ID:       report-1
+   :END:
+   Send to the whole team.
+
+   Include the numbers.
+** DONE Call the bank
+   CLOSED: [2025-11-19 Wed 16:05] SCHEDULED: <2025-11-19 Wed>
+   :LOGBOOK:
+   - State "DONE"       from "TODO"       [2025-11-19 Wed 16:05]
+   :END:
+** WAIT Reply from legal
+   DEADLINE: <2025-11-28 Fri -2d>
+** CANCELED Team lunch
+   SCHEDULED: <2025-11-21 Fri 12:00-13:30>
+* Home
+** TODO Pay rent
+   SCHEDULED: <2025-12-01 Mon .+1m>
+** TODO Water plants
+   SCHEDULED: <2025-11-20 Thu ++3d>
+** TODO Standup
+   SCHEDULED: <2025-11-24 Mon 09:30>
+   :PROPERTIES:
+   :TIMEZONE: Europe/Berlin
+   :RECURRENCE: monthly-nth-weekday:1
+   :END:
+** TODO Someday
+** TODO Bad date
+   SCHEDULED: <2025-02-30 Sun>
+`
+
+func TestImportOrg(t *testing.T) {
+	rows, err := ImportOrg(strings.NewReader(sampleOrg))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(rows) != 9 {
+		t.Fatalf("expected 9 tasks, got %d", len(rows))
+	}
+	day := func(d int) time.Time { return time.Date(2025, 11, d, 0, 0, 0, 0, time.Local) }
+
+	report := rows[0]
+	if report.Line != 5 || report.Task.Title != "pri:A Weekly report @work" || report.Task.UID != "report-1" {
+		t.Errorf("unexpected task at line %d: %+v", report.Line, report.Task)
+	}
+	if !report.Task.Date.Equal(day(24).Add(9*time.Hour+30*time.Minute)) || report.Task.RecurrencePattern != recurrence.Pattern("weekly:monday") {
+		t.Errorf("unexpected schedule %v %q", report.Task.Date, report.Task.RecurrencePattern)
+	}
+	if report.Task.Details != "Send to the whole team.\n\nInclude the numbers." {
+		t.Errorf("details = %q", report.Task.Details)
+	}
+
+	bank := rows[1].Task
+	if !bank.Completed || !bank.Date.Equal(day(19)) || !bank.UpdatedAt.Equal(day(19).Add(16*time.Hour+5*time.Minute)) || bank.Details != "" {
+		t.Errorf("unexpected completed task %+v", bank)
+	}
+	if legal := rows[2].Task; legal.Completed || !legal.Date.Equal(day(28)) {
+		t.Errorf("expected the declared open keyword and the deadline, got %+v", legal)
+	}
+	if lunch := rows[3].Task; !lunch.Skipped || lunch.Date.Format("15:04") != "12:00" {
+		t.Errorf("expected a skipped task at the start of its time range, got %+v", lunch)
+	}
+
+	if rows[4].Task.RecurrencePattern != recurrence.Pattern("monthly:1") {
+		t.Errorf("recurrence = %q, want monthly:1", rows[4].Task.RecurrencePattern)
+	}
+	plants := rows[5]
+	if plants.Err != nil || plants.Task.IsRecurring() || !strings.Contains(plants.Warning, `"++3d"`) {
+		t.Errorf("expected a one-off task with a warning, got %+v", plants)
+	}
+	standup := rows[6].Task
+	if standup.Date.Location().String() != "Europe/Berlin" || standup.RecurrencePattern != recurrence.Pattern("monthly-nth-weekday:1") {
+		t.Errorf("unexpected task %+v", standup)
+	}
+	if rows[7].Task.Date.Format(dateLayout) != time.Now().Format(dateLayout) {
+		t.Errorf("expected an unscheduled task for today, got %v", rows[7].Task.Date)
+	}
+	if rows[8].Err == nil {
+		t.Errorf("expected the invalid date to be rejected")
+	}
+}
+
+func TestExportOrg(t *testing.T) {
+	tasks := sampleTasks(t)
+	tasks[0].Title = "pri:B Weekly report"
+
+	var buf bytes.Buffer
+	if err := ExportOrg(&buf, tasks); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	closed := tasks[1].UpdatedAt.In(time.Local).Format("2006-01-02 Mon 15:04")
+	want := `#+TODO: TODO | DONE CANCELLED
+
+* TODO [#B] Weekly report
+  SCHEDULED: <2025-11-24 Mon +1w>
+  :PROPERTIES:
+  :ID: ` + tasks[0].StableUID() + `
+  :END:
+  Send to the whole team.
+
+* DONE Call, "Berlin" office
+  CLOSED: [` + closed + `] SCHEDULED: <2025-11-20 Thu 14:30>
+  :PROPERTIES:
+  :ID: ` + tasks[1].StableUID() + `
+  :TIMEZONE: Europe/Berlin
+  :END:
+
+* CANCELLED Old task
+  SCHEDULED: <2025-10-01 Wed>
+  :PROPERTIES:
+  :ID: ` + tasks[2].StableUID() + `
+  :END:
+  line one
+  line two
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestOrg_RoundTrip(t *testing.T) {
+	tasks := sampleTasks(t)
+	for i, pattern := range []string{"monthly:2", "monthly-nth-weekday:3", "monthly-last-weekend"} {
+		task, _ := todo.NewTask("Recurring", "", time.Date(2025, 12, 1+i, 0, 0, 0, 0, time.Local))
+		task.RecurrencePattern = recurrence.Pattern(pattern)
+		tasks = append(tasks, task)
+	}
+
+	var buf bytes.Buffer
+	ExportOrg(&buf, tasks)
+	rows, err := ImportOrg(&buf)
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(rows) != len(tasks) {
+		t.Fatalf("expected %d rows, got %d", len(tasks), len(rows))
+	}
+
+	for i, row := range rows {
+		if row.Err != nil {
+			t.Fatalf("row %d rejected: %v", row.Line, row.Err)
+		}
+		want, got := tasks[i], row.Task
+		if got.Title != want.Title || got.Details != want.Details ||
+			got.Completed != want.Completed || got.Skipped != want.Skipped ||
+			got.RecurrencePattern != want.RecurrencePattern || got.UID != want.StableUID() ||
+			!got.Date.Equal(want.Date) || got.Date.Location().String() != want.Date.Location().String() {
+			t.Errorf("task %d changed in the round trip:\n got %+v\nwant %+v", i, got, want)
+		}
+	}
+}
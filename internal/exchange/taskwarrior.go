@@ -0,0 +1,184 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+)
+
+// Taskwarrior's `task export` writes a JSON array of tasks:
+//
+//	[{"uuid": "...", "description": "Pay rent", "status": "recurring",
+//	  "due": "20251201T000000Z", "recur": "monthly", "priority": "H",
+//	  "project": "home", "tags": ["bills"], "entry": "...", "modified": "..."}]
+//
+// A recurring task is a template (status "recurring") plus instances that
+// name it as their parent. The template is imported as the recurring task,
+// scheduled on the earliest pending instance; pending instances are not
+// imported separately, and completed ones are imported as history.
+//
+// The project and tags are added to the title as +project and @tag, and the
+// priority as pri:A (H), pri:B (M) or pri:C (L), as for todo.txt.
+// Annotations become the details. Deleted tasks are imported as skipped.
+
+const taskwarriorTime = "20060102T150405Z"
+
+type taskwarriorTask struct {
+	UUID        string   `json:"uuid"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Due         string   `json:"due"`
+	Scheduled   string   `json:"scheduled"`
+	Entry       string   `json:"entry"`
+	Modified    string   `json:"modified"`
+	End         string   `json:"end"`
+	Recur       string   `json:"recur"`
+	Parent      string   `json:"parent"`
+	Priority    string   `json:"priority"`
+	Project     string   `json:"project"`
+	Tags        []string `json:"tags"`
+	Annotations []struct {
+		Description string `json:"description"`
+	} `json:"annotations"`
+}
+
+var taskwarriorPriorities = map[string]string{"H": "pri:A", "M": "pri:B", "L": "pri:C"}
+
+// ImportTaskwarrior reads the output of `task export`.
+func ImportTaskwarrior(r io.Reader) ([]Row, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse Taskwarrior export: %w", err)
+	}
+
+	tasks := make([]*taskwarriorTask, len(raw))
+	decodeErrs := make([]error, len(raw))
+	nextDue := map[string]string{} // template UUID -> earliest pending due
+	for i, data := range raw {
+		var task taskwarriorTask
+		if err := json.Unmarshal(data, &task); err != nil {
+			decodeErrs[i] = err
+			continue
+		}
+		tasks[i] = &task
+		if task.Parent != "" && isPending(task.Status) && task.Due != "" {
+			if due, ok := nextDue[task.Parent]; !ok || task.Due < due {
+				nextDue[task.Parent] = task.Due
+			}
+		}
+	}
+
+	var rows []Row
+	for i, task := range tasks {
+		line := i + 1
+		switch {
+		case decodeErrs[i] != nil:
+			rows = append(rows, Row{Line: line, Err: decodeErrs[i]})
+		case task.Parent != "" && isPending(task.Status):
+			// Represented by its template.
+		default:
+			if due, ok := nextDue[task.UUID]; ok {
+				task.Due = due
+			}
+			rows = append(rows, taskwarriorRow(line, task))
+		}
+	}
+	return rows, nil
+}
+
+func isPending(status string) bool {
+	return status == "pending" || status == "waiting"
+}
+
+func taskwarriorRow(line int, task *taskwarriorTask) Row {
+	title := []string{}
+	if pri, ok := taskwarriorPriorities[task.Priority]; ok {
+		title = append(title, pri)
+	}
+	title = append(title, task.Description)
+	if task.Project != "" {
+		title = append(title, "+"+task.Project)
+	}
+	for _, tag := range task.Tags {
+		title = append(title, "@"+tag)
+	}
+
+	var details []string
+	for _, annotation := range task.Annotations {
+		details = append(details, annotation.Description)
+	}
+
+	record := Record{
+		Title:     strings.Join(title, " "),
+		Details:   strings.Join(details, "\n"),
+		Completed: task.Status == "completed",
+		Skipped:   task.Status == "deleted",
+		UID:       task.UUID,
+	}
+
+	var err error
+	if record.CreatedAt, err = parseTaskwarriorTime("entry", task.Entry); err != nil {
+		return Row{Line: line, Err: err}
+	}
+	if record.UpdatedAt, err = parseTaskwarriorTime("modified", task.Modified); err != nil {
+		return Row{Line: line, Err: err}
+	}
+
+	due := task.Due
+	if due == "" {
+		due = task.Scheduled
+	}
+	date := time.Now()
+	if due != "" {
+		at, err := parseTaskwarriorTime("due", due)
+		if err != nil {
+			return Row{Line: line, Err: err}
+		}
+		date = at.In(time.Local)
+		if !date.Equal(time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)) {
+			record.Time = date.Format("15:04")
+		}
+	}
+	record.Date = date.Format(dateLayout)
+
+	warning := ""
+	if task.Recur != "" {
+		if pattern, ok := taskwarriorPattern(task.Recur, date); ok {
+			record.Recurrence = string(pattern)
+		} else {
+			warning = untranslated(task.Recur)
+		}
+	}
+
+	row := rowTask(line, record)
+	row.Warning = warning
+	return row
+}
+
+// taskwarriorPattern maps a recur value to a pattern repeating on date's
+// weekday or day of the month. Taskwarrior accepts many spellings of the
+// same period.
+func taskwarriorPattern(recur string, date time.Time) (recurrence.Pattern, bool) {
+	switch strings.ToLower(recur) {
+	case "weekly", "week", "1w", "1wk", "1wks", "1week", "1weeks", "p1w", "7d", "7days", "p7d":
+		return weeklyOn(date), true
+	case "monthly", "month", "1m", "1mo", "1mth", "1mths", "1month", "1months", "p1m":
+		return monthlyOn(date), true
+	}
+	return "", false
+}
+
+func parseTaskwarriorTime(field, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(taskwarriorTime, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q", field, value)
+	}
+	return t, nil
+}
@@ -0,0 +1,87 @@
+package exchange
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+)
+
+const sampleTaskwarrior = `[
+{"id":1,"description":"Buy milk","entry":"20251117T091203Z","modified":"20251118T100000Z","status":"pending","uuid":"a1","priority":"H","project":"home","tags":["shop","errand"],"annotations":[{"entry":"20251117T091500Z","description":"Oat milk"},{"entry":"20251117T091600Z","description":"Two cartons"}]},
+{"id":0,"description":"File taxes","entry":"20250101T080000Z","modified":"20250401T080000Z","end":"20250401T080000Z","status":"completed","uuid":"b2","due":"20250331T143000Z"},
+{"id":2,"description":"Team report","entry":"20251101T080000Z","status":"recurring","uuid":"c3","due":"20251103T000000Z","recur":"weekly","mask":"++-"},
+{"id":3,"description":"Team report","entry":"20251101T080000Z","status":"pending","uuid":"c3-3","parent":"c3","due":"20251117T000000Z","recur":"weekly","imask":2},
+{"id":0,"description":"Team report","entry":"20251101T080000Z","status":"completed","uuid":"c3-1","parent":"c3","due":"20251110T000000Z","recur":"weekly","imask":1},
+{"id":4,"description":"Water plants","entry":"20251101T080000Z","status":"recurring","uuid":"d4","due":"20251120T000000Z","recur":"3d"},
+{"id":0,"description":"Old idea","entry":"20251101T080000Z","status":"deleted","uuid":"e5"},
+{"id":5,"description":"","entry":"20251101T080000Z","status":"pending","uuid":"f6"}
+]`
+
+func TestImportTaskwarrior(t *testing.T) {
+	rows, err := ImportTaskwarrior(strings.NewReader(sampleTaskwarrior))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(rows) != 7 {
+		t.Fatalf("expected 7 rows (the pending instance is part of its template), got %d", len(rows))
+	}
+
+	milk := rows[0].Task
+	if milk.Title != "pri:A Buy milk +home @shop @errand" || milk.Details != "Oat milk\nTwo cartons" || milk.UID != "a1" {
+		t.Errorf("unexpected task %+v", milk)
+	}
+	if !milk.CreatedAt.Equal(time.Date(2025, 11, 17, 9, 12, 3, 0, time.UTC)) {
+		t.Errorf("created at %v", milk.CreatedAt)
+	}
+
+	taxes := rows[1].Task
+	due := time.Date(2025, 3, 31, 14, 30, 0, 0, time.UTC).In(time.Local)
+	if !taxes.Completed || !taxes.Date.Equal(due) {
+		t.Errorf("unexpected completed task %+v", taxes)
+	}
+
+	report := rows[2].Task
+	nextDue := time.Date(2025, 11, 17, 0, 0, 0, 0, time.UTC).In(time.Local)
+	if report.RecurrencePattern != weeklyOn(nextDue) || report.Date.Format(dateLayout) != nextDue.Format(dateLayout) {
+		t.Errorf("expected the template on its next pending instance, got %+v", report)
+	}
+	if history := rows[3].Task; !history.Completed || history.UID != "c3-1" {
+		t.Errorf("expected the completed instance as history, got %+v", history)
+	}
+
+	plants := rows[4]
+	if plants.Err != nil || plants.Task.IsRecurring() || !strings.Contains(plants.Warning, `"3d"`) {
+		t.Errorf("expected a one-off task with a warning, got %+v", plants)
+	}
+	if !rows[5].Task.Skipped {
+		t.Errorf("expected the deleted task to be skipped")
+	}
+	if rows[6].Err == nil {
+		t.Errorf("expected the task without a description to be rejected")
+	}
+}
+
+func TestTaskwarriorPattern(t *testing.T) {
+	monday := time.Date(2025, 11, 24, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		recur string
+		want  recurrence.Pattern
+	}{
+		{"weekly", "weekly:monday"},
+		{"1wk", "weekly:monday"},
+		{"P1W", "weekly:monday"},
+		{"monthly", "monthly:24"},
+		{"1mo", "monthly:24"},
+		{"daily", ""},
+		{"2w", ""},
+		{"yearly", ""},
+	}
+	for _, tt := range tests {
+		got, ok := taskwarriorPattern(tt.recur, monday)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("taskwarriorPattern(%q) = %q, %t, want %q", tt.recur, got, ok, tt.want)
+		}
+	}
+}
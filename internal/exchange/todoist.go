@@ -0,0 +1,248 @@
+package exchange
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+)
+
+// Todoist's CSV backups have one row per task, section or comment:
+//
+//	TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE
+//	task,Pay rent,Transfer to landlord,1,1,Ann (1),,every 1st,en,Europe/Berlin
+//	note,Reference: flat 2,,,,Ann (1),,,,
+//
+// DATE is the date as typed in Todoist, such as "Nov 20", "tomorrow at
+// 10:00" or "every monday". Recurring dates are translated where facienda
+// has an equivalent, starting from the next occurrence; others are
+// imported as one-off tasks for today with a warning. Comments are appended
+// to their task's details, and priorities 1 to 3 (p1 to p3) are added to the
+// title as pri:A to pri:C. Sections are ignored.
+
+var (
+	// todoistAt splits off a time of day, which needs "at", minutes or
+	// am/pm so that "nov 20" stays a date.
+	todoistAt       = regexp.MustCompile(`^(.*?)\s+(?:at\s+(\d{1,2}(?::\d{2})?\s*(?:am|pm)?)|(\d{1,2}:\d{2}\s*(?:am|pm)?|\d{1,2}\s*(?:am|pm)))$`)
+	todoistClock    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?\s*(am|pm)?$`)
+	todoistMonthDay = regexp.MustCompile(`^(?:every\s+)?(?:month\s+on\s+the\s+)?(\d{1,2})(?:st|nd|rd|th)?$`)
+
+	todoistWeekdays = map[string]string{
+		"mon": "monday", "tue": "tuesday", "tues": "tuesday", "wed": "wednesday",
+		"thu": "thursday", "thur": "thursday", "thurs": "thursday", "fri": "friday",
+		"sat": "saturday", "sun": "sunday",
+	}
+	todoistDateLayouts = []string{"2006-01-02", "Jan 2 2006", "Jan 2, 2006", "2 Jan 2006", "January 2 2006", "2 January 2006"}
+	todoistDayLayouts  = []string{"Jan 2", "2 Jan", "January 2", "2 January"}
+)
+
+// ImportTodoist reads a Todoist CSV backup.
+func ImportTodoist(r io.Reader) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"TYPE", "CONTENT"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("not a Todoist backup: no %s column", required)
+		}
+	}
+
+	type pending struct {
+		row    Row
+		record Record
+	}
+	var tasks []*pending
+	var last *pending
+	for {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// FieldPos only works for records that parsed, so the line of a
+			// malformed one comes from the error.
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			tasks = append(tasks, &pending{row: Row{Line: parseErr.StartLine, Err: parseErr.Err}})
+			last = nil
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		switch strings.ToLower(field("TYPE")) {
+		case "task":
+			last = &pending{row: Row{Line: line}}
+			last.record, last.row.Warning, last.row.Err = todoistRecord(field)
+			tasks = append(tasks, last)
+		case "note":
+			if last != nil && field("CONTENT") != "" {
+				if last.record.Details != "" {
+					last.record.Details += "\n"
+				}
+				last.record.Details += field("CONTENT")
+			}
+		default:
+			// Sections and blank separator rows.
+		}
+	}
+
+	rows := make([]Row, len(tasks))
+	for i, task := range tasks {
+		if task.row.Err != nil {
+			rows[i] = task.row
+			continue
+		}
+		rows[i] = rowTask(task.row.Line, task.record)
+		rows[i].Warning = task.row.Warning
+	}
+	return rows, nil
+}
+
+// todoistRecord converts a task row. The warning is set when the row's
+// recurrence couldn't be translated.
+func todoistRecord(field func(name string) string) (record Record, warning string, err error) {
+	var title []string
+	if p, err := strconv.Atoi(field("PRIORITY")); err == nil && p >= 1 && p <= 3 {
+		title = append(title, "pri:"+string(rune('A'+p-1)))
+	}
+	title = append(title, field("CONTENT"))
+	record.Title = strings.Join(title, " ")
+	record.Details = field("DESCRIPTION")
+
+	value := strings.ToLower(field("DATE"))
+	now := time.Now()
+	date := now
+	if m := todoistAt.FindStringSubmatch(value); m != nil {
+		if record.Time, err = parseTodoistClock(m[2] + m[3]); err != nil {
+			return Record{}, "", err
+		}
+		value = m[1]
+		record.Zone = field("TIMEZONE")
+	}
+
+	switch {
+	case value == "":
+	case strings.HasPrefix(value, "every") || strings.HasPrefix(value, "ev "):
+		if pattern, ok := todoistPattern(value, now); ok {
+			record.Recurrence = string(pattern)
+			if date, err = pattern.NextOccurrence(now.AddDate(0, 0, -1)); err != nil {
+				return Record{}, "", err
+			}
+		} else {
+			warning = untranslated(field("DATE"))
+		}
+	default:
+		if date, err = parseTodoistDate(value, now); err != nil {
+			return Record{}, "", err
+		}
+	}
+	record.Date = date.Format(dateLayout)
+	return record, warning, nil
+}
+
+// todoistPattern translates the recurring dates facienda has patterns for.
+// Dates that repeat from the completion date ("every!") are treated like
+// ordinary ones.
+func todoistPattern(value string, now time.Time) (recurrence.Pattern, bool) {
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(value, "ev "), "every!"))
+	if !strings.HasPrefix(value, "every") {
+		value = "every " + value
+	}
+
+	if pattern, err := recurrence.ParsePattern(value); err == nil {
+		return pattern, true
+	}
+
+	rest := strings.TrimSpace(strings.TrimPrefix(value, "every"))
+	switch {
+	case rest == "week":
+		return weeklyOn(now), true
+	case rest == "month":
+		return monthlyOn(now), true
+	case todoistWeekdays[rest] != "":
+		return recurrence.Pattern("weekly:" + todoistWeekdays[rest]), true
+	}
+	if m := todoistMonthDay.FindStringSubmatch(rest); m != nil {
+		pattern := recurrence.Pattern("monthly:" + strings.TrimLeft(m[1], "0"))
+		if pattern.Validate() == nil {
+			return pattern, true
+		}
+	}
+	if pattern, err := recurrence.ParsePattern(rest); err == nil {
+		return pattern, true
+	}
+	return "", false
+}
+
+// parseTodoistDate parses a one-off date. Dates without a year are in the
+// current year, or the next one if they have already passed.
+func parseTodoistDate(value string, now time.Time) (time.Time, error) {
+	switch value {
+	case "today":
+		return now, nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), nil
+	}
+
+	for _, layout := range todoistDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range todoistDayLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			t = t.AddDate(now.Year()-t.Year(), 0, 0)
+			if t.Format(dateLayout) < now.Format(dateLayout) {
+				t = t.AddDate(1, 0, 0)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", value)
+}
+
+// parseTodoistClock parses a time of day such as "9am", "9:30 pm" or
+// "21:00" into HH:MM.
+func parseTodoistClock(value string) (string, error) {
+	m := todoistClock.FindStringSubmatch(value)
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	switch {
+	case m[3] == "pm" && hour < 12:
+		hour += 12
+	case m[3] == "am" && hour == 12:
+		hour = 0
+	}
+	if hour > 23 || minute > 59 {
+		return "", fmt.Errorf("invalid time %q", value)
+	}
+	return fmt.Sprintf("%02d:%02d", hour, minute), nil
+}
@@ -0,0 +1,125 @@
+package exchange
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+)
+
+const sampleTodoist = `TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE
+section,Home,,,,,,,,
+task,Pay rent,Transfer to landlord,1,1,Ann (1),,every 1st,en,Europe/Berlin
+note,Reference: flat 2,,,,Ann (1),,,,
+note,Due before noon,,,,Ann (1),,,,
+,,,,,,,,,
+task,Standup,,4,1,Ann (1),,every mon at 9:30am,en,Europe/Berlin
+task,Dentist,,2,1,Ann (1),,2025-11-20 14:00,en,Europe/Berlin
+task,Call Bob,,4,1,Ann (1),,Nov 20 2025,en,Europe/Berlin
+task,Stretch,,4,1,Ann (1),,every day,en,Europe/Berlin
+task,Plan trip,,4,1,Ann (1),,next spring,en,Europe/Berlin
+task,Read,,4,1,Ann (1),,,en,Europe/Berlin
+`
+
+func TestImportTodoist(t *testing.T) {
+	rows, err := ImportTodoist(strings.NewReader(sampleTodoist))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(rows) != 7 {
+		t.Fatalf("expected 7 tasks, got %d", len(rows))
+	}
+	today := time.Now().Format(dateLayout)
+
+	rent := rows[0]
+	if rent.Line != 3 || rent.Task.Title != "pri:A Pay rent" || rent.Task.RecurrencePattern != recurrence.Pattern("monthly:1") {
+		t.Errorf("unexpected task at line %d: %+v", rent.Line, rent.Task)
+	}
+	if rent.Task.Details != "Transfer to landlord\nReference: flat 2\nDue before noon" {
+		t.Errorf("details = %q", rent.Task.Details)
+	}
+	if rent.Task.Date.Day() != 1 || rent.Task.Date.Format(dateLayout) < today {
+		t.Errorf("expected the next 1st of the month, got %v", rent.Task.Date)
+	}
+
+	standup := rows[1].Task
+	if standup.RecurrencePattern != recurrence.Pattern("weekly:monday") || standup.Date.Weekday() != time.Monday ||
+		standup.Date.Format("15:04") != "09:30" || standup.Date.Location().String() != "Europe/Berlin" {
+		t.Errorf("unexpected recurring task %+v", standup)
+	}
+
+	dentist := rows[2].Task
+	if dentist.Title != "pri:B Dentist" || dentist.Date.Format("2006-01-02 15:04") != "2025-11-20 14:00" {
+		t.Errorf("unexpected task %+v", dentist)
+	}
+	if rows[3].Task.Date.Format(dateLayout) != "2025-11-20" || rows[3].Task.HasTime() {
+		t.Errorf("expected a whole-day task, got %v", rows[3].Task.Date)
+	}
+
+	stretch := rows[4]
+	if stretch.Err != nil || stretch.Task.IsRecurring() || stretch.Task.Date.Format(dateLayout) != today ||
+		!strings.Contains(stretch.Warning, `"every day"`) {
+		t.Errorf("expected a one-off task for today with a warning, got %+v", stretch)
+	}
+	if rows[5].Err == nil {
+		t.Errorf("expected an unrecognised date to be rejected")
+	}
+	if rows[6].Task.Date.Format(dateLayout) != today {
+		t.Errorf("expected a task without a date to be for today, got %v", rows[6].Task.Date)
+	}
+}
+
+func TestImportTodoist_MalformedRow(t *testing.T) {
+	input := "TYPE,CONTENT,DATE\ntask,Pay rent,2025-11-20\nta\"sk,Bad quote,2025-11-21\nnote,Orphan note,\ntask,Read,2025-11-22\n"
+	rows, err := ImportTodoist(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+	if rows[1].Err == nil || rows[1].Line != 3 {
+		t.Errorf("expected line 3 to be rejected, got line %d: %v", rows[1].Line, rows[1].Err)
+	}
+	if rows[0].Err != nil || rows[0].Task.Details != "" {
+		t.Errorf("expected the note after the malformed row not to attach to an earlier task, got %+v", rows[0])
+	}
+	if rows[2].Err != nil || rows[2].Line != 5 || rows[2].Task.Title != "Read" {
+		t.Errorf("expected the last task to be imported, got %+v", rows[2])
+	}
+}
+
+func TestTodoistPattern(t *testing.T) {
+	now := time.Date(2025, 11, 20, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		date string
+		want recurrence.Pattern
+	}{
+		{"every monday", "weekly:monday"},
+		{"every! fri", "weekly:friday"},
+		{"ev tue", "weekly:tuesday"},
+		{"every week", "weekly:thursday"},
+		{"every month", "monthly:20"},
+		{"every 15th", "monthly:15"},
+		{"every month on the 3rd", "monthly:3"},
+		{"every 2nd weekday of the month", "monthly-nth-weekday:2"},
+		{"every day", ""},
+		{"every other week", ""},
+		{"every 32nd", ""},
+	}
+	for _, tt := range tests {
+		got, ok := todoistPattern(tt.date, now)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("todoistPattern(%q) = %q, %t, want %q", tt.date, got, ok, tt.want)
+		}
+	}
+}
+
+func TestParseTodoistClock(t *testing.T) {
+	for value, want := range map[string]string{"9am": "09:00", "9:30 pm": "21:30", "12am": "00:00", "12pm": "12:00", "21:05": "21:05"} {
+		if got, err := parseTodoistClock(value); err != nil || got != want {
+			t.Errorf("parseTodoistClock(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package exchange
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// todo.txt (http://todotxt.org) keeps one task per line:
+//
+//	x 2025-11-20 2025-11-17 Call the bank +finances @phone due:2025-11-20
+//	(A) 2025-11-17 Weekly report +work due:2025-11-24 rec:1w
+//
+// The date a task is scheduled on is its due: date. +project and @context
+// tokens are part of the title, as they are in todo.txt. Facienda has no
+// priorities, so a priority is kept in the title as a pri: token, which is
+// how todo.txt records the priority of completed tasks, and is restored when
+// the task is exported again. Times of day and zones are written as time:
+// and tz: tokens. Details have no place on a todo.txt line and are not
+// exported.
+//
+// Weekly and monthly recurrences are written as the common rec:1w and rec:1m
+// extensions, which repeat from the due date; recurrences with no todo.txt
+// equivalent are written in their stored form, such as
+// rec:monthly-last-weekend.
+
+var (
+	todoTxtPriority = regexp.MustCompile(`^\(([A-Z])\)$`)
+	todoTxtDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	todoTxtInterval = regexp.MustCompile(`^\+?(\d+)([dbwmy])$`)
+)
+
+// ExportTodoTxt writes tasks as todo.txt lines.
+func ExportTodoTxt(w io.Writer, tasks []*todo.Task) error {
+	bw := bufio.NewWriter(w)
+	for _, task := range tasks {
+		if _, err := fmt.Fprintln(bw, todoTxtLine(task)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func todoTxtLine(task *todo.Task) string {
+	r := NewRecord(task)
+
+	var parts []string
+	title := strings.Fields(r.Title)
+	if r.Completed {
+		parts = append(parts, "x", r.UpdatedAt.In(time.Local).Format(dateLayout))
+	} else {
+		// Open tasks carry their priority in front, as todo.txt expects.
+		for i, token := range title {
+			if p, ok := strings.CutPrefix(token, "pri:"); ok && todoTxtPriority.MatchString("("+p+")") {
+				parts = append(parts, "("+p+")")
+				title = append(title[:i:i], title[i+1:]...)
+				break
+			}
+		}
+	}
+	parts = append(parts, r.CreatedAt.In(time.Local).Format(dateLayout))
+	parts = append(parts, title...)
+	parts = append(parts, "due:"+r.Date)
+
+	if r.Time != "" {
+		parts = append(parts, "time:"+r.Time)
+	}
+	if r.Zone != "" {
+		parts = append(parts, "tz:"+r.Zone)
+	}
+	if r.Recurrence != "" {
+		parts = append(parts, "rec:"+todoTxtRecurrence(task))
+	}
+	if r.Skipped {
+		parts = append(parts, "skipped:true")
+	}
+	return strings.Join(parts, " ")
+}
+
+// todoTxtRecurrence returns the rec: value for a recurring task.
+func todoTxtRecurrence(task *todo.Task) string {
+	kind, value, _ := strings.Cut(string(task.RecurrencePattern), ":")
+	switch {
+	case kind == "weekly" && value == strings.ToLower(task.Date.Weekday().String()):
+		return "1w"
+	case kind == "monthly" && value == fmt.Sprint(task.Date.Day()):
+		return "1m"
+	default:
+		return string(task.RecurrencePattern)
+	}
+}
+
+// ImportTodoTxt reads todo.txt lines. Blank lines are ignored. Tasks without
+// a due: date are scheduled for today.
+func ImportTodoTxt(r io.Reader) ([]Row, error) {
+	var rows []Row
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		record, err := parseTodoTxtLine(text)
+		if err != nil {
+			rows = append(rows, Row{Line: line, Err: err})
+			continue
+		}
+		rows = append(rows, rowTask(line, record))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read todo.txt: %w", err)
+	}
+	return rows, nil
+}
+
+func parseTodoTxtLine(line string) (Record, error) {
+	tokens := strings.Fields(line)
+	var record Record
+
+	if len(tokens) > 0 && tokens[0] == "x" {
+		record.Completed = true
+		tokens = tokens[1:]
+		if len(tokens) > 0 && todoTxtDate.MatchString(tokens[0]) {
+			completed, err := time.ParseInLocation(dateLayout, tokens[0], time.Local)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid completion date %q", tokens[0])
+			}
+			record.UpdatedAt = completed
+			tokens = tokens[1:]
+		}
+	}
+
+	var title []string
+	if len(tokens) > 0 {
+		if m := todoTxtPriority.FindStringSubmatch(tokens[0]); m != nil {
+			title = append(title, "pri:"+m[1])
+			tokens = tokens[1:]
+		}
+	}
+
+	if len(tokens) > 0 && todoTxtDate.MatchString(tokens[0]) {
+		created, err := time.ParseInLocation(dateLayout, tokens[0], time.Local)
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid creation date %q", tokens[0])
+		}
+		record.CreatedAt = created
+		tokens = tokens[1:]
+	}
+
+	var rec string
+	for _, token := range tokens {
+		key, value, _ := strings.Cut(token, ":")
+		switch key {
+		case "due":
+			record.Date = value
+		case "time":
+			record.Time = value
+		case "tz":
+			record.Zone = value
+		case "rec":
+			rec = value
+		case "skipped":
+			record.Skipped = value == "true"
+		default:
+			title = append(title, token)
+		}
+	}
+	record.Title = strings.Join(title, " ")
+
+	if record.Date == "" {
+		record.Date = time.Now().Format(dateLayout)
+	}
+	if rec != "" {
+		pattern, err := todoTxtPattern(rec, record.Date)
+		if err != nil {
+			return Record{}, err
+		}
+		record.Recurrence = string(pattern)
+	}
+	return record, nil
+}
+
+// todoTxtPattern maps a rec: value to a recurrence pattern. Intervals of one
+// week or month repeat on the due date's weekday or day of the month; other
+// intervals can't be represented. Any other value is taken as a pattern.
+func todoTxtPattern(rec, due string) (recurrence.Pattern, error) {
+	m := todoTxtInterval.FindStringSubmatch(rec)
+	if m == nil {
+		return recurrence.Pattern(rec), nil
+	}
+
+	date, err := time.ParseInLocation(dateLayout, due, time.Local)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q: %w", due, err)
+	}
+	switch {
+	case m[1] == "1" && m[2] == "w":
+		return weeklyOn(date), nil
+	case m[1] == "1" && m[2] == "m":
+		return monthlyOn(date), nil
+	default:
+		return "", fmt.Errorf("recurrence rec:%s can't be imported (only rec:1w and rec:1m are supported)", rec)
+	}
+}
@@ -0,0 +1,137 @@
+package exchange
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+const sampleTodoTxt = `(A) 2025-11-17 Call Mom +family @phone due:2025-11-20
+
+x 2025-11-21 2025-11-17 Pay electricity bill +finances due:2025-11-20 pri:B
+2025-11-17 Weekly report +work due:2025-11-24 rec:1w
+Rent due:2025-12-01 rec:+1m
+Standup due:2025-11-20 time:09:30 tz:Europe/Berlin
+Water plants due:2025-11-20 rec:3d
+Read http://example.com/article due:2025-11-20
+x Old chore due:2025-10-01 skipped:true
+`
+
+func TestImportTodoTxt(t *testing.T) {
+	rows, err := ImportTodoTxt(strings.NewReader(sampleTodoTxt))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if len(rows) != 8 {
+		t.Fatalf("expected 8 rows, got %d", len(rows))
+	}
+
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	}
+
+	call := rows[0].Task
+	if call.Title != "pri:A Call Mom +family @phone" || !call.Date.Equal(day(2025, 11, 20)) ||
+		call.Completed || !call.CreatedAt.Equal(day(2025, 11, 17)) {
+		t.Errorf("unexpected task %+v", call)
+	}
+
+	bill := rows[1].Task
+	if rows[1].Line != 3 || !bill.Completed || bill.Title != "Pay electricity bill +finances pri:B" ||
+		!bill.UpdatedAt.Equal(day(2025, 11, 21)) {
+		t.Errorf("unexpected completed task at line %d: %+v", rows[1].Line, bill)
+	}
+
+	if got := rows[2].Task.RecurrencePattern; got != recurrence.Pattern("weekly:monday") {
+		t.Errorf("rec:1w on a Monday = %q, want weekly:monday", got)
+	}
+	if got := rows[3].Task.RecurrencePattern; got != recurrence.Pattern("monthly:1") {
+		t.Errorf("rec:+1m on the 1st = %q, want monthly:1", got)
+	}
+
+	standup := rows[4].Task
+	if standup.Date.Location().String() != "Europe/Berlin" || standup.Date.Hour() != 9 || standup.Date.Minute() != 30 {
+		t.Errorf("unexpected time %v", standup.Date)
+	}
+
+	if rows[5].Err == nil || !strings.Contains(rows[5].Err.Error(), "rec:3d") {
+		t.Errorf("expected rec:3d to be rejected, got %v", rows[5].Err)
+	}
+	if rows[6].Task.Title != "Read http://example.com/article" {
+		t.Errorf("expected the URL to stay in the title, got %q", rows[6].Task.Title)
+	}
+	if !rows[7].Task.Completed || !rows[7].Task.Skipped {
+		t.Errorf("expected a completed, skipped task, got %+v", rows[7].Task)
+	}
+}
+
+func TestTodoTxt_RoundTrip(t *testing.T) {
+	rows, err := ImportTodoTxt(strings.NewReader(sampleTodoTxt))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	var tasks []*todo.Task
+	for _, row := range rows {
+		if row.Err == nil {
+			tasks = append(tasks, row.Task)
+		}
+	}
+
+	var first bytes.Buffer
+	if err := ExportTodoTxt(&first, tasks); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	reimported, err := ImportTodoTxt(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to import the export: %v", err)
+	}
+
+	for i, row := range reimported {
+		if row.Err != nil {
+			t.Fatalf("line %d rejected: %v", row.Line, row.Err)
+		}
+		want, got := tasks[i], row.Task
+		if got.Title != want.Title || !got.Date.Equal(want.Date) ||
+			got.Completed != want.Completed || got.Skipped != want.Skipped ||
+			got.RecurrencePattern != want.RecurrencePattern {
+			t.Errorf("task %d changed in the round trip:\n got %+v\nwant %+v", i, got, want)
+		}
+	}
+
+	lines := strings.Split(first.String(), "\n")
+	if lines[0] != "(A) 2025-11-17 Call Mom +family @phone due:2025-11-20" {
+		t.Errorf("expected the priority to be restored in front, got %q", lines[0])
+	}
+	if lines[2] != "2025-11-17 Weekly report +work due:2025-11-24 rec:1w" {
+		t.Errorf("expected a rec:1w extension, got %q", lines[2])
+	}
+
+	var second bytes.Buffer
+	tasks = tasks[:0]
+	for _, row := range reimported {
+		tasks = append(tasks, row.Task)
+	}
+	ExportTodoTxt(&second, tasks)
+	if first.String() != second.String() {
+		t.Errorf("export is not stable:\n%s\n---\n%s", first.String(), second.String())
+	}
+}
+
+func TestExportTodoTxt_StoredRecurrence(t *testing.T) {
+	task, _ := todo.NewTask("Hike", "", time.Date(2025, 11, 29, 0, 0, 0, 0, time.Local))
+	task.RecurrencePattern = recurrence.Pattern("monthly-last-weekend")
+
+	line := todoTxtLine(task)
+	if !strings.HasSuffix(line, "rec:monthly-last-weekend") {
+		t.Fatalf("expected the stored pattern, got %q", line)
+	}
+
+	rows, _ := ImportTodoTxt(strings.NewReader(line))
+	if rows[0].Err != nil || rows[0].Task.RecurrencePattern != task.RecurrencePattern {
+		t.Errorf("expected the pattern to be read back, got %+v", rows[0])
+	}
+}
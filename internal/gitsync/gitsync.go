@@ -0,0 +1,300 @@
+// Package gitsync syncs tasks through a git repository, for teams that
+// already share everything else that way.
+//
+// Each machine keeps a clone of the repository next to its database. The
+// clone holds one file per task under tasks/, in the format of the markdown
+// backend and named after the task's UID, and one file per deleted task
+// under deleted/ with the time it was deleted. A sync exports the changes
+// made to the database since the last sync into the clone and commits them,
+// pulls, and imports the result back into the database before pushing it.
+// Task files changed on two machines are merged field by field by
+// MergeFile, which git runs as a merge driver, so that conflicts are settled
+// per task rather than per repository.
+package gitsync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/syncer"
+)
+
+const (
+	tasksDir   = "tasks"
+	deletedDir = "deleted"
+
+	// driverName is the merge driver .gitattributes assigns to task and
+	// tombstone files; Open configures the command it runs.
+	driverName = "facienda"
+	attributes = "tasks/*.md merge=facienda\ndeleted/* merge=facienda\n"
+
+	// maxAttempts bounds how often Sync starts over when another machine
+	// pushed while it was running.
+	maxAttempts = 3
+)
+
+var ErrPushRejected = errors.New("the repository changed while syncing")
+
+// Repo is the clone of a shared task repository.
+type Repo struct {
+	dir    string
+	remote string
+	branch string
+}
+
+// Open prepares dir as a clone of remote, a URL or path git understands,
+// creating it on first use. Tasks are kept on branch. mergeDriver is the
+// command git runs to merge a task file, which is given the common, current
+// and other version of the file and its path; see RunMergeDriver.
+func Open(dir, remote, branch, mergeDriver string) (*Repo, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create clone: %w", err)
+	}
+	r := &Repo{dir: dir, remote: remote, branch: branch}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if _, err := r.git("init", "-q"); err != nil {
+			return nil, err
+		}
+		if _, err := r.git("remote", "add", "origin", remote); err != nil {
+			return nil, err
+		}
+		if _, err := r.git("symbolic-ref", "HEAD", "refs/heads/"+branch); err != nil {
+			return nil, err
+		}
+	} else if url, _ := r.git("remote", "get-url", "origin"); url != remote {
+		// The repository moved, or the clone was made for another one;
+		// either way the given remote is the one to sync with.
+		if _, err := r.git("remote", "set-url", "origin", remote); err != nil {
+			return nil, err
+		}
+	}
+
+	settings := [][2]string{
+		{"merge." + driverName + ".name", "facienda task merge"},
+		{"merge." + driverName + ".driver", mergeDriver + " %O %A %B %P"},
+	}
+	// Commits need an author, which a machine that only syncs tasks may
+	// not have configured.
+	if email, _ := r.git("config", "user.email"); email == "" {
+		host, _ := os.Hostname()
+		settings = append(settings, [2]string{"user.name", "facienda"}, [2]string{"user.email", "facienda@" + host})
+	}
+	for _, setting := range settings {
+		if _, err := r.git("config", setting[0], setting[1]); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Result is what Sync changed.
+type Result struct {
+	// Exported are the changes made to the repository, and Imported the
+	// changes made to the database.
+	Exported, Imported []syncer.Change
+	// Conflicts are the tasks changed on both sides that the repository and
+	// the database disagreed on after merging; the newer version was kept.
+	Conflicts []syncer.Conflict
+}
+
+// Sync exports the changes made to local since the last sync to the clone
+// and commits them, pulls and merges the repository, imports the merged
+// tasks into local, and pushes. If another machine pushed in the meantime,
+// it starts over from the export.
+func (r *Repo) Sync(local storage.Storage) (*Result, error) {
+	host, _ := os.Hostname()
+	result := &Result{}
+
+	for attempt := 1; ; attempt++ {
+		exported, err := r.syncTasks(local)
+		if err != nil {
+			return nil, err
+		}
+		result.Exported = append(result.Exported, exported.Remote...)
+		result.Imported = append(result.Imported, exported.Local...)
+		result.Conflicts = append(result.Conflicts, exported.Conflicts...)
+		if err := r.commit("Update tasks from " + host); err != nil {
+			return nil, err
+		}
+
+		if err := r.Pull(); err != nil {
+			return nil, err
+		}
+
+		imported, err := r.syncTasks(local)
+		if err != nil {
+			return nil, err
+		}
+		result.Exported = append(result.Exported, imported.Remote...)
+		result.Imported = append(result.Imported, imported.Local...)
+		result.Conflicts = append(result.Conflicts, imported.Conflicts...)
+		if err := r.commit("Merge tasks on " + host); err != nil {
+			return nil, err
+		}
+
+		err = r.push()
+		if errors.Is(err, ErrPushRejected) && attempt < maxAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+}
+
+// syncTasks brings the tasks in the clone and local in line, keeping the
+// newer version of every task both changed.
+func (r *Repo) syncTasks(local storage.Storage) (*syncer.Plan, error) {
+	tasks, err := r.Tasks()
+	if err != nil {
+		return nil, err
+	}
+	plan, err := syncer.NewPlan(local, tasks)
+	if err != nil {
+		return nil, err
+	}
+	// The other machines weigh a deletion against their changes by when
+	// it was made, not when it was synced.
+	replica, _ := storage.As[storage.Replica](local)
+	deleted, err := replica.Tombstones()
+	if err != nil {
+		return nil, err
+	}
+	for _, tombstone := range deleted {
+		tasks.deletedAt[tombstone.UID] = tombstone.DeletedAt
+	}
+	for _, c := range plan.Conflicts {
+		plan.Resolve(c, c.Newer())
+	}
+	if err := plan.Apply(); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// Pull fetches the branch and merges it into the clone. Task files changed
+// on both sides are merged by the merge driver; a task file one side
+// deleted and the other changed is kept if it was changed after it was
+// deleted, and removed otherwise.
+func (r *Repo) Pull() error {
+	if _, err := r.git("fetch", "-q", "origin"); err != nil {
+		return err
+	}
+	upstream := "refs/remotes/origin/" + r.branch
+	if _, err := r.git("rev-parse", "-q", "--verify", upstream); err != nil {
+		// Nothing was pushed to the branch yet.
+		return nil
+	}
+
+	// A machine's first sync commits its tasks before it has pulled the
+	// tasks of the others, so its history starts separately.
+	_, mergeErr := r.git("merge", "-q", "--no-edit", "--allow-unrelated-histories", upstream)
+	if mergeErr == nil {
+		return nil
+	}
+	unmerged, err := r.git("diff", "--name-only", "--diff-filter=U")
+	if err != nil || unmerged == "" {
+		return mergeErr
+	}
+	for _, file := range strings.Split(unmerged, "\n") {
+		if err := r.resolveDeletion(file); err != nil {
+			r.git("merge", "--abort")
+			return err
+		}
+	}
+	_, err = r.git("commit", "-q", "--no-edit")
+	return err
+}
+
+// resolveDeletion settles a task file that one side of a merge deleted and
+// the other changed, which git doesn't hand to the merge driver.
+func (r *Repo) resolveDeletion(file string) error {
+	dir, name := path.Split(file)
+	escaped, ok := strings.CutSuffix(name, ".md")
+	if dir != tasksDir+"/" || !ok {
+		return fmt.Errorf("failed to merge %s: not a task file", file)
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.dir, filepath.FromSlash(file)))
+	if err != nil {
+		return fmt.Errorf("failed to merge %s: %w", file, err)
+	}
+	task, err := storage.UnmarshalTask(data)
+	if err != nil {
+		return fmt.Errorf("failed to merge %s: %w", file, err)
+	}
+	deletedAt, err := readTombstone(filepath.Join(r.dir, deletedDir, escaped))
+	if err == nil && !task.UpdatedAt.After(deletedAt) {
+		_, err = r.git("rm", "-q", "--", file)
+		return err
+	}
+	_, err = r.git("add", "--", file)
+	return err
+}
+
+// commit commits every change in the clone, if there are any.
+func (r *Repo) commit(message string) error {
+	attributesPath := filepath.Join(r.dir, ".gitattributes")
+	if _, err := os.Stat(attributesPath); errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(attributesPath, []byte(attributes), 0o644); err != nil {
+			return fmt.Errorf("failed to write .gitattributes: %w", err)
+		}
+	}
+
+	status, err := r.git("status", "--porcelain")
+	if err != nil || status == "" {
+		return err
+	}
+	if _, err := r.git("add", "-A"); err != nil {
+		return err
+	}
+	_, err = r.git("commit", "-q", "-m", message)
+	return err
+}
+
+// push pushes the clone's commits to the branch.
+func (r *Repo) push() error {
+	if _, err := r.git("rev-parse", "-q", "--verify", "HEAD"); err != nil {
+		// Nothing was committed, on this machine or any other.
+		return nil
+	}
+
+	out, err := r.git("push", "--porcelain", "origin", "HEAD:refs/heads/"+r.branch)
+	if err != nil {
+		for _, line := range strings.Split(out, "\n") {
+			if strings.HasPrefix(line, "!") {
+				return ErrPushRejected
+			}
+		}
+	}
+	return err
+}
+
+// git runs a git command in the clone and returns its trimmed output, which
+// is also returned when the command fails.
+func (r *Repo) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err := cmd.Run()
+	out := strings.TrimSpace(stdout.String())
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return out, fmt.Errorf("git %s failed: %s", args[0], message)
+	}
+	return out, nil
+}
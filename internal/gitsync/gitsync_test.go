@@ -0,0 +1,235 @@
+package gitsync
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// mergeDriverEnv makes the test binary act as the merge driver; see
+// TestMergeDriverProcess.
+const mergeDriverEnv = "GITSYNC_TEST_MERGE_DRIVER"
+
+// machine is a database and its clone of the shared repository.
+type machine struct {
+	store *storage.MemoryStorage
+	repo  *Repo
+}
+
+// newRemote creates an empty bare repository, like the one a team would
+// share.
+func newRemote(t *testing.T) string {
+	t.Helper()
+
+	remote := filepath.Join(t.TempDir(), "tasks.git")
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create remote: %v: %s", err, out)
+	}
+	return remote
+}
+
+func newMachine(t *testing.T, remote string) *machine {
+	t.Helper()
+
+	driver := fmt.Sprintf("%s=1 '%s' -test.run='^TestMergeDriverProcess$' --", mergeDriverEnv, os.Args[0])
+	repo, err := Open(filepath.Join(t.TempDir(), "clone"), remote, "main", driver)
+	if err != nil {
+		t.Fatalf("failed to open clone: %v", err)
+	}
+	return &machine{store: storage.NewMemoryStorage(), repo: repo}
+}
+
+func (m *machine) sync(t *testing.T) *Result {
+	t.Helper()
+
+	result, err := m.repo.Sync(m.store)
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	return result
+}
+
+func (m *machine) add(t *testing.T, title string) *todo.Task {
+	t.Helper()
+
+	task, _ := todo.NewTask(title, "", time.Date(2025, 11, 20, 0, 0, 0, 0, time.Local))
+	if err := m.store.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	return task
+}
+
+// find returns the task titled title, or nil.
+func (m *machine) find(t *testing.T, title string) *todo.Task {
+	t.Helper()
+
+	tasks, err := m.store.All()
+	if err != nil {
+		t.Fatalf("failed to list tasks: %v", err)
+	}
+	for _, task := range tasks {
+		if task.Title == title {
+			return task
+		}
+	}
+	return nil
+}
+
+func (m *machine) update(t *testing.T, task *todo.Task) {
+	t.Helper()
+
+	// Keep changes made one after the other apart.
+	time.Sleep(2 * time.Millisecond)
+	task.UpdatedAt = time.Now()
+	if err := m.store.Update(task); err != nil {
+		t.Fatalf("failed to update task: %v", err)
+	}
+}
+
+func TestSync_SharesTasksThroughRepository(t *testing.T) {
+	remote := newRemote(t)
+	alice, bob := newMachine(t, remote), newMachine(t, remote)
+
+	alice.add(t, "Plan sprint")
+	alice.add(t, "Old idea")
+	if result := alice.sync(t); len(result.Exported) != 2 {
+		t.Fatalf("expected 2 exported tasks, got %+v", result)
+	}
+	if result := bob.sync(t); len(result.Imported) != 2 {
+		t.Fatalf("expected 2 imported tasks, got %+v", result)
+	}
+
+	planned := bob.find(t, "Plan sprint")
+	planned.Complete()
+	bob.update(t, planned)
+	if err := bob.store.Delete(bob.find(t, "Old idea").ID); err != nil {
+		t.Fatalf("failed to delete task: %v", err)
+	}
+	bob.add(t, "Review")
+	bob.sync(t)
+
+	alice.sync(t)
+	if task := alice.find(t, "Plan sprint"); task == nil || !task.Completed {
+		t.Errorf("expected the completion to reach alice, got %+v", task)
+	}
+	if alice.find(t, "Old idea") != nil {
+		t.Error("expected the deletion to reach alice")
+	}
+	if alice.find(t, "Review") == nil {
+		t.Error("expected the new task to reach alice")
+	}
+
+	// Both are in line now, so syncing changes nothing.
+	for _, m := range []*machine{alice, bob} {
+		if result := m.sync(t); len(result.Exported)+len(result.Imported) != 0 {
+			t.Errorf("expected nothing left to sync, got %+v", result)
+		}
+	}
+
+	files, err := exec.Command("git", "--git-dir", remote, "ls-tree", "-r", "--name-only", "main").Output()
+	if err != nil {
+		t.Fatalf("failed to list repository: %v", err)
+	}
+	if got := strings.Count(string(files), tasksDir+"/"); got != 2 {
+		t.Errorf("expected 2 task files in the repository, got:\n%s", files)
+	}
+	if !strings.Contains(string(files), deletedDir+"/") {
+		t.Errorf("expected a tombstone in the repository, got:\n%s", files)
+	}
+}
+
+func TestSync_MergesTaskChangedOnBothMachines(t *testing.T) {
+	remote := newRemote(t)
+	alice, bob := newMachine(t, remote), newMachine(t, remote)
+	alice.add(t, "Weekly report")
+	alice.sync(t)
+	bob.sync(t)
+
+	renamed := alice.find(t, "Weekly report")
+	renamed.Title = "Weekly status report"
+	alice.update(t, renamed)
+	completed := bob.find(t, "Weekly report")
+	completed.Complete()
+	bob.update(t, completed)
+
+	alice.sync(t)
+	bob.sync(t)
+	alice.sync(t)
+
+	for name, m := range map[string]*machine{"alice": alice, "bob": bob} {
+		task := m.find(t, "Weekly status report")
+		if task == nil || !task.Completed {
+			t.Errorf("expected %s to have both changes, got %+v", name, task)
+		}
+	}
+}
+
+func TestSync_ChangeAndDeletion(t *testing.T) {
+	remote := newRemote(t)
+	alice, bob := newMachine(t, remote), newMachine(t, remote)
+	alice.add(t, "Deleted then changed")
+	alice.add(t, "Changed then deleted")
+	alice.sync(t)
+	bob.sync(t)
+
+	for _, m := range []*machine{alice, bob} {
+		if m == alice {
+			if err := m.store.Delete(m.find(t, "Deleted then changed").ID); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(2 * time.Millisecond)
+		} else {
+			task := m.find(t, "Deleted then changed")
+			task.Complete()
+			m.update(t, task)
+		}
+	}
+	for _, m := range []*machine{bob, alice} {
+		if m == bob {
+			task := m.find(t, "Changed then deleted")
+			task.Complete()
+			m.update(t, task)
+		} else {
+			time.Sleep(2 * time.Millisecond)
+			if err := m.store.Delete(m.find(t, "Changed then deleted").ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	alice.sync(t)
+	bob.sync(t)
+	alice.sync(t)
+
+	for name, m := range map[string]*machine{"alice": alice, "bob": bob} {
+		if task := m.find(t, "Deleted then changed"); task == nil || !task.Completed {
+			t.Errorf("expected %s to keep the change made after the deletion, got %+v", name, task)
+		}
+		if m.find(t, "Changed then deleted") != nil {
+			t.Errorf("expected %s to keep the deletion made after the change", name)
+		}
+	}
+}
+
+// TestMergeDriverProcess is the merge driver git runs for the clones of the
+// other tests. It does nothing when run as part of the test suite.
+func TestMergeDriverProcess(t *testing.T) {
+	if os.Getenv(mergeDriverEnv) == "" {
+		t.Skip("only run as a merge driver")
+	}
+	args := flag.Args()
+	if len(args) != 4 {
+		t.Fatalf("expected 4 arguments, got %q", args)
+	}
+	if err := RunMergeDriver(args[0], args[1], args[2], args[3]); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,133 @@
+package gitsync
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// MergeFile merges two versions of a file in the clone, ours and theirs,
+// that both changed base, the version they have in common. base is empty if
+// both machines created the file.
+//
+// A task file takes each field from the version that changed it, and from
+// the one changed last if both did; a tombstone file keeps the earlier of
+// the two deletion times.
+func MergeFile(file string, base, ours, theirs []byte) ([]byte, error) {
+	switch path.Dir(file) {
+	case tasksDir:
+		return mergeTaskFile(base, ours, theirs)
+	case deletedDir:
+		return mergeTombstone(ours, theirs)
+	default:
+		return nil, fmt.Errorf("%s is not a task file", file)
+	}
+}
+
+func mergeTaskFile(base, ours, theirs []byte) ([]byte, error) {
+	var versions [3]*todo.Task
+	for i, data := range [][]byte{base, ours, theirs} {
+		if i == 0 && len(data) == 0 {
+			continue
+		}
+		task, err := storage.UnmarshalTask(data)
+		if err != nil {
+			return nil, err
+		}
+		versions[i] = task
+	}
+	return storage.MarshalTask(mergeTasks(versions[0], versions[1], versions[2])), nil
+}
+
+// mergeTasks merges ours and theirs, two versions of base, which is nil
+// if they don't have one in common.
+func mergeTasks(base, ours, theirs *todo.Task) *todo.Task {
+	newer, older := ours, theirs
+	if theirs.UpdatedAt.After(ours.UpdatedAt) {
+		newer, older = theirs, ours
+	}
+	merged := *newer
+	if older.CreatedAt.Before(merged.CreatedAt) {
+		merged.CreatedAt = older.CreatedAt
+	}
+	if base == nil {
+		return &merged
+	}
+
+	// Fields only the older version changed are taken from it.
+	changed := false
+	if older.Title != base.Title && newer.Title == base.Title {
+		merged.Title, changed = older.Title, true
+	}
+	if older.Details != base.Details && newer.Details == base.Details {
+		merged.Details, changed = older.Details, true
+	}
+	if !sameSchedule(older, base) && sameSchedule(newer, base) {
+		merged.Date, changed = older.Date, true
+	}
+	if (older.Completed != base.Completed || older.Skipped != base.Skipped) &&
+		newer.Completed == base.Completed && newer.Skipped == base.Skipped {
+		merged.Completed, merged.Skipped, changed = older.Completed, older.Skipped, true
+	}
+	if older.RecurrencePattern != base.RecurrencePattern && newer.RecurrencePattern == base.RecurrencePattern {
+		merged.RecurrencePattern, changed = older.RecurrencePattern, true
+	}
+	if older.Owner != base.Owner && newer.Owner == base.Owner {
+		merged.Owner, changed = older.Owner, true
+	}
+	if older.Assignee != base.Assignee && newer.Assignee == base.Assignee {
+		merged.Assignee, changed = older.Assignee, true
+	}
+
+	// The merged task is a change of its own, which the next sync takes
+	// over into the database.
+	if changed {
+		merged.UpdatedAt = time.Now()
+	}
+	return &merged
+}
+
+func sameSchedule(a, b *todo.Task) bool {
+	aDate, aClock, aZone := storage.SplitSchedule(a.Date)
+	bDate, bClock, bZone := storage.SplitSchedule(b.Date)
+	return aDate == bDate && aClock == bClock && aZone == bZone
+}
+
+func mergeTombstone(ours, theirs []byte) ([]byte, error) {
+	var earliest time.Time
+	for _, data := range [][]byte{ours, theirs} {
+		deletedAt, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid deletion time: %w", err)
+		}
+		if earliest.IsZero() || deletedAt.Before(earliest) {
+			earliest = deletedAt
+		}
+	}
+	return []byte(earliest.UTC().Format(time.RFC3339Nano) + "\n"), nil
+}
+
+// RunMergeDriver is the merge driver git runs for the files in a clone. It
+// merges the files at ours and theirs, whose common version is at base, into
+// ours; file is the path of the file in the clone.
+func RunMergeDriver(base, ours, theirs, file string) error {
+	var versions [3][]byte
+	for i, p := range []string{base, ours, theirs} {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		versions[i] = data
+	}
+
+	merged, err := MergeFile(file, versions[0], versions[1], versions[2])
+	if err != nil {
+		return fmt.Errorf("failed to merge %s: %w", file, err)
+	}
+	return os.WriteFile(ours, merged, 0o644)
+}
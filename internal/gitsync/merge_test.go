@@ -0,0 +1,91 @@
+package gitsync
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+func TestMergeFile_Task(t *testing.T) {
+	created := time.Date(2025, 11, 17, 9, 0, 0, 0, time.UTC)
+	base := &todo.Task{
+		UID:       "01JCX2M3A8Q0W4E6R8T0Y2V4K6",
+		Title:     "Weekly report",
+		Date:      time.Date(2025, 11, 24, 0, 0, 0, 0, time.Local),
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+
+	ours := *base
+	ours.Title = "Weekly status report"
+	ours.Details = "From the laptop"
+	ours.UpdatedAt = created.Add(time.Hour)
+	theirs := *base
+	theirs.RecurrencePattern = recurrence.Pattern("weekly:monday")
+	theirs.Details = "From the desktop"
+	theirs.Completed = true
+	theirs.UpdatedAt = created.Add(2 * time.Hour)
+
+	data, err := MergeFile("tasks/01JCX2M3A8Q0W4E6R8T0Y2V4K6.md",
+		storage.MarshalTask(base), storage.MarshalTask(&ours), storage.MarshalTask(&theirs))
+	if err != nil {
+		t.Fatalf("failed to merge: %v", err)
+	}
+	merged, err := storage.UnmarshalTask(data)
+	if err != nil {
+		t.Fatalf("failed to read merged task: %v", err)
+	}
+
+	if merged.Title != "Weekly status report" || !merged.Completed || merged.RecurrencePattern != "weekly:monday" {
+		t.Errorf("expected the fields each side changed, got %+v", merged)
+	}
+	if merged.Details != "From the desktop" {
+		t.Errorf("expected the newer details, got %q", merged.Details)
+	}
+	if merged.UID != base.UID || !merged.CreatedAt.Equal(created) || !merged.UpdatedAt.After(theirs.UpdatedAt) {
+		t.Errorf("unexpected identity or timestamps %+v", merged)
+	}
+	if strings.Contains(string(data), "\nid:") {
+		t.Errorf("expected no ID in the merged file:\n%s", data)
+	}
+
+	// Without a common version the newer one is kept as a whole.
+	data, _ = MergeFile("tasks/01JCX2M3A8Q0W4E6R8T0Y2V4K6.md", nil, storage.MarshalTask(&ours), storage.MarshalTask(&theirs))
+	if merged, _ := storage.UnmarshalTask(data); merged.Title != base.Title || merged.Details != "From the desktop" {
+		t.Errorf("expected the newer version, got %+v", merged)
+	}
+}
+
+func TestMergeFile_Tombstone(t *testing.T) {
+	data, err := MergeFile("deleted/event-1%40example.com",
+		[]byte("2025-11-20T10:00:00Z\n"), []byte("2025-11-21T10:00:00Z\n"), []byte("2025-11-20T12:00:00+01:00\n"))
+	if err != nil {
+		t.Fatalf("failed to merge: %v", err)
+	}
+	if string(data) != "2025-11-20T11:00:00Z\n" {
+		t.Errorf("expected the earlier deletion, got %q", data)
+	}
+
+	if _, err := MergeFile("README.md", nil, nil, nil); err == nil {
+		t.Error("expected files other than task files to be refused")
+	}
+}
+
+func TestEscapeUID(t *testing.T) {
+	for uid, want := range map[string]string{
+		"01JCX2M3A8Q0W4E6R8T0Y2V4K6": "01JCX2M3A8Q0W4E6R8T0Y2V4K6",
+		"event-1@example.com":        "event-1@example.com",
+		"a/b:c 100%":                 "a%2Fb%3Ac%20100%25",
+	} {
+		if got := escapeUID(uid); got != want {
+			t.Errorf("escapeUID(%q) = %q, want %q", uid, got, want)
+		}
+		if got := unescapeUID(want); got != uid {
+			t.Errorf("unescapeUID(%q) = %q, want %q", want, got, uid)
+		}
+	}
+}
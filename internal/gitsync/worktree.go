@@ -0,0 +1,254 @@
+package gitsync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// stateFile holds the Replica state of a clone. It is kept in the .git
+// directory, since it belongs to the clone and not to the repository.
+const stateFile = "facienda-sync.json"
+
+// Worktree is the store of the tasks in a clone. Tasks are read when it is
+// opened and written through to their files; their IDs are assigned when
+// it is opened and only mean something for as long as it is open.
+type Worktree struct {
+	*storage.MemoryStorage
+	dir string
+	// deletedAt holds when tasks deleted from the database were deleted,
+	// which is recorded in their tombstone files instead of the time Delete
+	// is called.
+	deletedAt map[string]time.Time
+}
+
+// worktreeState is the content of stateFile.
+type worktreeState struct {
+	ReplicaID string               `json:"replica_id,omitempty"`
+	LastSync  map[string]time.Time `json:"last_sync,omitempty"`
+}
+
+// Tasks opens the tasks in the clone.
+func (r *Repo) Tasks() (*Worktree, error) {
+	w := &Worktree{MemoryStorage: storage.NewMemoryStorage(), dir: r.dir, deletedAt: map[string]time.Time{}}
+
+	entries, err := os.ReadDir(filepath.Join(r.dir, tasksDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	for _, entry := range entries {
+		escaped, ok := strings.CutSuffix(entry.Name(), ".md")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(r.dir, tasksDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read task: %w", err)
+		}
+		task, err := storage.UnmarshalTask(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", tasksDir, entry.Name(), err)
+		}
+		if task.UID == "" {
+			task.UID = unescapeUID(escaped)
+		}
+		if err := w.MemoryStorage.Create(task); err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", tasksDir, entry.Name(), err)
+		}
+	}
+	return w, nil
+}
+
+func (w *Worktree) Create(task *todo.Task) error {
+	if err := w.MemoryStorage.Create(task); err != nil {
+		return err
+	}
+	return w.writeTask(task)
+}
+
+func (w *Worktree) Update(task *todo.Task) error {
+	current, err := w.GetByID(task.ID)
+	if err != nil {
+		return err
+	}
+	if err := w.MemoryStorage.Update(task); err != nil {
+		return err
+	}
+	if current.StableUID() != task.StableUID() {
+		if err := os.Remove(w.taskPath(current.StableUID())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return w.writeTask(task)
+}
+
+// Delete removes the task's file and leaves a tombstone file in its place,
+// which tells the other clones to delete the task as well.
+func (w *Worktree) Delete(id int64) error {
+	task, err := w.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := w.MemoryStorage.Delete(id); err != nil {
+		return err
+	}
+	if err := os.Remove(w.taskPath(task.StableUID())); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(w.dir, deletedDir), 0o755); err != nil {
+		return err
+	}
+	deletedAt, ok := w.deletedAt[task.StableUID()]
+	if !ok {
+		deletedAt = time.Now()
+	}
+	data := deletedAt.UTC().Format(time.RFC3339Nano) + "\n"
+	return os.WriteFile(filepath.Join(w.dir, deletedDir, escapeUID(task.StableUID())), []byte(data), 0o644)
+}
+
+// Tombstones returns the tasks deleted on any of the machines sharing the
+// repository.
+func (w *Worktree) Tombstones() ([]storage.Tombstone, error) {
+	entries, err := os.ReadDir(filepath.Join(w.dir, deletedDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted tasks: %w", err)
+	}
+
+	var tombstones []storage.Tombstone
+	for _, entry := range entries {
+		deletedAt, err := readTombstone(filepath.Join(w.dir, deletedDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		tombstones = append(tombstones, storage.Tombstone{UID: unescapeUID(entry.Name()), DeletedAt: deletedAt})
+	}
+	return tombstones, nil
+}
+
+func (w *Worktree) ReplicaID() (string, error) {
+	state, err := w.readState()
+	if err != nil || state.ReplicaID != "" {
+		return state.ReplicaID, err
+	}
+	if state.ReplicaID, err = w.MemoryStorage.ReplicaID(); err != nil {
+		return "", err
+	}
+	return state.ReplicaID, w.writeState(state)
+}
+
+func (w *Worktree) LastSync(peer string) (time.Time, error) {
+	state, err := w.readState()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return state.LastSync[peer], nil
+}
+
+func (w *Worktree) SetLastSync(peer string, t time.Time) error {
+	state, err := w.readState()
+	if err != nil {
+		return err
+	}
+	if state.LastSync == nil {
+		state.LastSync = map[string]time.Time{}
+	}
+	state.LastSync[peer] = t
+	return w.writeState(state)
+}
+
+func (w *Worktree) readState() (*worktreeState, error) {
+	state := &worktreeState{}
+	data, err := os.ReadFile(filepath.Join(w.dir, ".git", stateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", stateFile, err)
+	}
+	return state, nil
+}
+
+func (w *Worktree) writeState(state *worktreeState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(w.dir, ".git", stateFile), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	return nil
+}
+
+// writeTask writes the task's file without its ID, which differs between
+// the machines sharing the repository.
+func (w *Worktree) writeTask(task *todo.Task) error {
+	if err := os.MkdirAll(filepath.Join(w.dir, tasksDir), 0o755); err != nil {
+		return err
+	}
+	shared := *task
+	shared.ID = 0
+	shared.UID = task.StableUID()
+	return os.WriteFile(w.taskPath(shared.UID), storage.MarshalTask(&shared), 0o644)
+}
+
+func (w *Worktree) taskPath(uid string) string {
+	return filepath.Join(w.dir, tasksDir, escapeUID(uid)+".md")
+}
+
+func readTombstone(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	deletedAt, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s/%s: %w", deletedDir, filepath.Base(path), err)
+	}
+	return deletedAt, nil
+}
+
+// escapeUID turns a UID into a file name that is valid on every platform.
+// ULIDs are left as they are; imported UIDs, such as calendar UIDs, may have
+// other characters, which are written as %XX.
+func escapeUID(uid string) string {
+	var b strings.Builder
+	for i := 0; i < len(uid); i++ {
+		c := uid[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("-_.@", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func unescapeUID(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '%' && i+2 < len(name) {
+			if c, err := strconv.ParseUint(name[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
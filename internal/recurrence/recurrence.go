@@ -320,6 +320,47 @@ func (p Pattern) String() string {
 	}
 }
 
+// Phrase returns the pattern as a phrase ParsePattern accepts, e.g.
+// "every monday" or "15th of each month", for formats that store
+// recurrences as text
+func (p Pattern) Phrase() string {
+	if p == "monthly-last-weekend" {
+		return "last weekend of the month"
+	}
+
+	kind, value, _ := strings.Cut(string(p), ":")
+	switch kind {
+	case "weekly":
+		return "every " + value
+	case "monthly":
+		return dayOrdinal(value) + " of each month"
+	case "monthly-nth-weekday":
+		return getOrdinal(value) + " weekday of the month"
+	default:
+		return string(p)
+	}
+}
+
+// dayOrdinal converts a day of the month to its ordinal form
+func dayOrdinal(day string) string {
+	n, err := strconv.Atoi(day)
+	if err != nil {
+		return day
+	}
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+		return day + "th"
+	case n%10 == 1:
+		return day + "st"
+	case n%10 == 2:
+		return day + "nd"
+	case n%10 == 3:
+		return day + "rd"
+	default:
+		return day + "th"
+	}
+}
+
 // getOrdinal converts a number string to its ordinal form
 func getOrdinal(num string) string {
 	switch num {
@@ -338,6 +379,19 @@ func getOrdinal(num string) string {
 	}
 }
 
+// Validate returns an error if the pattern is not one ParsePattern can
+// produce, e.g. because it was edited by hand in the database
+func (p Pattern) Validate() error {
+	if p == PatternNone {
+		return nil
+	}
+
+	// NextOccurrence rejects every malformed pattern, so a probe date is
+	// enough to check the pattern's syntax and values
+	_, err := p.NextOccurrence(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	return err
+}
+
 // IsRecurring returns true if the pattern represents a recurring task
 func (p Pattern) IsRecurring() bool {
 	return p != PatternNone
@@ -506,6 +506,33 @@ func TestPattern_String(t *testing.T) {
 	}
 }
 
+func TestPattern_Phrase(t *testing.T) {
+	tests := []struct {
+		pattern Pattern
+		want    string
+	}{
+		{"weekly:monday", "every monday"},
+		{"monthly:1", "1st of each month"},
+		{"monthly:12", "12th of each month"},
+		{"monthly:22", "22nd of each month"},
+		{"monthly:31", "31st of each month"},
+		{"monthly-nth-weekday:3", "3rd weekday of the month"},
+		{"monthly-last-weekend", "last weekend of the month"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.pattern), func(t *testing.T) {
+			got := tt.pattern.Phrase()
+			if got != tt.want {
+				t.Errorf("Pattern.Phrase() = %v, want %v", got, tt.want)
+			}
+			if parsed, err := ParsePattern(got); err != nil || parsed != tt.pattern {
+				t.Errorf("ParsePattern(%q) = %v, %v, want %v", got, parsed, err, tt.pattern)
+			}
+		})
+	}
+}
+
 func TestPattern_IsRecurring(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -537,3 +564,55 @@ func TestPattern_IsRecurring(t *testing.T) {
 		})
 	}
 }
+
+func TestPattern_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern Pattern
+		wantErr bool
+	}{
+		{
+			name:    "none pattern",
+			pattern: PatternNone,
+			wantErr: false,
+		},
+		{
+			name:    "weekly pattern",
+			pattern: "weekly:monday",
+			wantErr: false,
+		},
+		{
+			name:    "last weekend pattern",
+			pattern: "monthly-last-weekend",
+			wantErr: false,
+		},
+		{
+			name:    "user phrase instead of pattern",
+			pattern: "every monday",
+			wantErr: true,
+		},
+		{
+			name:    "unknown weekday",
+			pattern: "weekly:funday",
+			wantErr: true,
+		},
+		{
+			name:    "day out of range",
+			pattern: "monthly:32",
+			wantErr: true,
+		},
+		{
+			name:    "nth weekday out of range",
+			pattern: "monthly-nth-weekday:6",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.pattern.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Pattern.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
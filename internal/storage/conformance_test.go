@@ -0,0 +1,621 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// runConformanceTests checks the behaviour every Storage implementation must
+// share. newStore returns an empty store; it is called once per subtest.
+func runConformanceTests(t *testing.T, newStore func(t *testing.T) Storage) {
+	t.Run("CreateAssignsIncreasingIDs", func(t *testing.T) {
+		store := newStore(t)
+
+		var last int64
+		for i := 0; i < 3; i++ {
+			task, _ := todo.NewTask("Task", "", time.Now())
+			if err := store.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+			if task.ID <= last {
+				t.Errorf("expected ID greater than %d, got %d", last, task.ID)
+			}
+			last = task.ID
+		}
+	})
+
+	t.Run("RoundTripsAllFields", func(t *testing.T) {
+		store := newStore(t)
+
+		loc := time.FixedZone("", -5*3600)
+		task := &todo.Task{
+			Title:             "Pay rent",
+			Details:           "Transfer to landlord\nReference: flat 2",
+			Date:              time.Date(2025, 12, 1, 0, 0, 0, 0, loc),
+			Completed:         true,
+			Skipped:           true,
+			RecurrencePattern: recurrence.Pattern("monthly:1"),
+			CreatedAt:         time.Date(2025, 11, 3, 14, 5, 6, 789000000, loc),
+			UpdatedAt:         time.Date(2025, 11, 4, 8, 0, 0, 0, loc),
+			UID:               "pay-rent@example.com",
+			Owner:             "alice",
+			Assignee:          "bob",
+		}
+		if err := store.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+
+		got, err := store.GetByID(task.ID)
+		if err != nil {
+			t.Fatalf("failed to get task: %v", err)
+		}
+		assertSameTask(t, got, task)
+
+		got.UID = "rent-2025@example.com"
+		if err := store.Update(got); err != nil {
+			t.Fatalf("failed to update task: %v", err)
+		}
+		updated, err := store.GetByID(task.ID)
+		if err != nil {
+			t.Fatalf("failed to get task: %v", err)
+		}
+		assertSameTask(t, updated, got)
+	})
+
+	t.Run("CreateAssignsUIDs", func(t *testing.T) {
+		store := newStore(t)
+
+		first, _ := todo.NewTask("First", "", time.Now())
+		second, _ := todo.NewTask("Second", "", time.Now())
+		for _, task := range []*todo.Task{first, second} {
+			if err := store.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+			if len(task.UID) != 26 {
+				t.Errorf("expected a ULID, got %q", task.UID)
+			}
+		}
+		if first.UID == second.UID {
+			t.Errorf("expected different UIDs, both are %s", first.UID)
+		}
+
+		got, err := store.GetByID(first.ID)
+		if err != nil {
+			t.Fatalf("failed to get task: %v", err)
+		}
+		if got.UID != first.UID {
+			t.Errorf("got UID %q, want %q", got.UID, first.UID)
+		}
+	})
+
+	t.Run("RejectsDuplicateUIDs", func(t *testing.T) {
+		store := newStore(t)
+
+		first := &todo.Task{Title: "First", Date: time.Now(), UID: "event-1@example.com"}
+		if err := store.Create(first); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		second := &todo.Task{Title: "Second", Date: time.Now(), UID: first.UID}
+		if err := store.Create(second); !errors.Is(err, todo.ErrDuplicateUID) {
+			t.Errorf("expected ErrDuplicateUID on create, got %v", err)
+		}
+
+		second.UID = ""
+		if err := store.Create(second); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		second.UID = first.UID
+		if err := store.Update(second); !errors.Is(err, todo.ErrDuplicateUID) {
+			t.Errorf("expected ErrDuplicateUID on update, got %v", err)
+		}
+	})
+
+	t.Run("FindByUID", func(t *testing.T) {
+		store := newStore(t)
+
+		var tasks []*todo.Task
+		for _, uid := range []string{"01JD0AAAAA0000000000000000", "01JD0AAAAB0000000000000000", "event-1", "event-10", "100%_done", "12345"} {
+			task := &todo.Task{Title: uid, Date: time.Now(), UID: uid}
+			if err := store.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+			tasks = append(tasks, task)
+		}
+
+		for prefix, want := range map[string]*todo.Task{
+			"01jd0aaaab": tasks[1],
+			"event-1":    tasks[2],
+			"event-10":   tasks[3],
+			"100%":       tasks[4],
+		} {
+			got, err := FindByUID(store, prefix)
+			if err != nil || got.ID != want.ID {
+				t.Errorf("FindByUID(%q) = %v, %v, want task %d", prefix, got, err, want.ID)
+			}
+		}
+		if _, err := FindByUID(store, "01JD0AAAA"); !errors.Is(err, todo.ErrAmbiguousUID) {
+			t.Errorf("expected ErrAmbiguousUID, got %v", err)
+		}
+		for _, prefix := range []string{"01JD0AAAAC", "100_", "%"} {
+			if _, err := FindByUID(store, prefix); !errors.Is(err, todo.ErrNotFound) && prefix != "%" {
+				t.Errorf("FindByUID(%q): expected ErrNotFound, got %v", prefix, err)
+			}
+		}
+
+		// Numbers are IDs, even where they could start a UID.
+		if got, err := FindTask(store, strconv.FormatInt(tasks[2].ID, 10)); err != nil || got.ID != tasks[2].ID {
+			t.Errorf("FindTask by ID = %v, %v, want task %d", got, err, tasks[2].ID)
+		}
+		if got, err := FindTask(store, "event-10"); err != nil || got.ID != tasks[3].ID {
+			t.Errorf("FindTask by UID = %v, %v, want task %d", got, err, tasks[3].ID)
+		}
+		// A number that isn't an ID is looked up as a UID.
+		if got, err := FindTask(store, "12345"); err != nil || got.ID != tasks[5].ID {
+			t.Errorf("FindTask by numeric UID = %v, %v, want task %d", got, err, tasks[5].ID)
+		}
+		if got, err := FindTask(store, "100"); err != nil || got.ID != tasks[4].ID {
+			t.Errorf("FindTask by numeric UID prefix = %v, %v, want task %d", got, err, tasks[4].ID)
+		}
+		if _, err := FindTask(store, "999"); !errors.Is(err, todo.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for 999, got %v", err)
+		}
+	})
+
+	t.Run("Scope", func(t *testing.T) {
+		store := newStore(t)
+		today := time.Now()
+
+		tasks := []*todo.Task{
+			{Title: "Alice's", Date: today, Owner: "alice"},
+			{Title: "Assigned to Alice", Date: today, Owner: "bob", Assignee: "alice"},
+			{Title: "Bob's", Date: today, Owner: "bob"},
+			{Title: "Nobody's", Date: today},
+			{Title: "Alice's skipped", Date: today, Owner: "alice", Skipped: true},
+		}
+		for _, task := range tasks {
+			if err := store.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+		alice := Scope(store, "alice")
+
+		listed, err := alice.List(FilterCurrent)
+		if err != nil {
+			t.Fatalf("failed to list tasks: %v", err)
+		}
+		if got := joinTitles(listed); got != "Alice's,Assigned to Alice" {
+			t.Errorf("List: got %q", got)
+		}
+		all, _ := alice.All()
+		if got := joinTitles(all); got != "Alice's,Assigned to Alice,Alice's skipped" {
+			t.Errorf("All: got %q", got)
+		}
+		if _, err := FindByUID(alice, all[0].UID); err != nil {
+			t.Errorf("expected Alice to find her task by UID, got %v", err)
+		}
+
+		bobs := tasks[2]
+		if _, err := alice.GetByID(bobs.ID); !errors.Is(err, todo.ErrNotFound) {
+			t.Errorf("expected Bob's task to be hidden, got %v", err)
+		}
+		if _, err := FindByUID(alice, bobs.UID); !errors.Is(err, todo.ErrNotFound) {
+			t.Errorf("expected Bob's task to be hidden from UID lookups, got %v", err)
+		}
+		bobs.Title = "Taken over"
+		if err := alice.Update(bobs); !errors.Is(err, todo.ErrNotFound) {
+			t.Errorf("expected updating Bob's task to fail, got %v", err)
+		}
+		if err := alice.Delete(bobs.ID); !errors.Is(err, todo.ErrNotFound) {
+			t.Errorf("expected deleting Bob's task to fail, got %v", err)
+		}
+
+		// An assignee changes the task but doesn't give it away or delete it.
+		assigned := all[1]
+		assigned.Complete()
+		if err := alice.Update(assigned); err != nil {
+			t.Errorf("expected the assignee to complete the task, got %v", err)
+		}
+		assigned.Owner = "alice"
+		if err := alice.Update(assigned); !errors.Is(err, todo.ErrForbidden) {
+			t.Errorf("expected taking over the task to be forbidden, got %v", err)
+		}
+		if err := alice.Delete(assigned.ID); !errors.Is(err, todo.ErrForbidden) {
+			t.Errorf("expected deleting the task to be forbidden, got %v", err)
+		}
+
+		created := &todo.Task{Title: "New", Date: today}
+		if err := alice.Create(created); err != nil || created.Owner != "alice" {
+			t.Errorf("expected the task to be Alice's, got %q, %v", created.Owner, err)
+		}
+		if err := alice.Create(&todo.Task{Title: "For Bob", Date: today, Owner: "bob"}); !errors.Is(err, todo.ErrForbidden) {
+			t.Errorf("expected creating Bob's task to be forbidden, got %v", err)
+		}
+		if err := alice.Delete(created.ID); err != nil {
+			t.Errorf("expected Alice to delete her task, got %v", err)
+		}
+	})
+
+	t.Run("RoundTripsTimeAndZone", func(t *testing.T) {
+		store := newStore(t)
+
+		berlin, err := time.LoadLocation("Europe/Berlin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		dates := []time.Time{
+			time.Date(2025, 12, 1, 14, 30, 0, 0, time.Local),
+			time.Date(2025, 12, 1, 23, 45, 0, 0, berlin),
+			time.Date(2025, 12, 1, 6, 15, 0, 0, time.FixedZone("", 5*3600+30*60)),
+		}
+		for _, date := range dates {
+			task := &todo.Task{Title: "Timed", Date: date, CreatedAt: now, UpdatedAt: now}
+			if err := store.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+
+			got, err := store.GetByID(task.ID)
+			if err != nil {
+				t.Fatalf("failed to get task: %v", err)
+			}
+			if !got.Date.Equal(date) || calendarDay(got.Date) != "2025-12-01" {
+				t.Errorf("got date %v, want %v", got.Date, date)
+			}
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		store := newStore(t)
+
+		if _, err := store.GetByID(42); err != todo.ErrNotFound {
+			t.Errorf("GetByID: expected ErrNotFound, got %v", err)
+		}
+		if err := store.Update(&todo.Task{ID: 42, Title: "Missing", Date: time.Now()}); err != todo.ErrNotFound {
+			t.Errorf("Update: expected ErrNotFound, got %v", err)
+		}
+		if err := store.Delete(42); err != todo.ErrNotFound {
+			t.Errorf("Delete: expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("UpdateAndDelete", func(t *testing.T) {
+		store := newStore(t)
+
+		task, _ := todo.NewTask("Original", "", time.Now())
+		if err := store.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+
+		task.Update("Changed", "With details")
+		task.Complete()
+		if err := store.Update(task); err != nil {
+			t.Fatalf("failed to update task: %v", err)
+		}
+
+		got, _ := store.GetByID(task.ID)
+		if got.Title != "Changed" || got.Details != "With details" || !got.Completed {
+			t.Errorf("update not persisted: %+v", got)
+		}
+
+		if err := store.Delete(task.ID); err != nil {
+			t.Fatalf("failed to delete task: %v", err)
+		}
+		if _, err := store.GetByID(task.ID); err != todo.ErrNotFound {
+			t.Errorf("expected ErrNotFound after delete, got %v", err)
+		}
+	})
+
+	t.Run("DeletedIDsAreNotReused", func(t *testing.T) {
+		store := newStore(t)
+
+		first, _ := todo.NewTask("First", "", time.Now())
+		second, _ := todo.NewTask("Second", "", time.Now())
+		store.Create(first)
+		store.Create(second)
+		store.Delete(second.ID)
+
+		third, _ := todo.NewTask("Third", "", time.Now())
+		if err := store.Create(third); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if third.ID <= second.ID {
+			t.Errorf("expected ID greater than %d, got %d", second.ID, third.ID)
+		}
+	})
+
+	t.Run("ReturnedTasksAreCopies", func(t *testing.T) {
+		store := newStore(t)
+
+		task, _ := todo.NewTask("Original", "", time.Now())
+		store.Create(task)
+		task.Title = "Changed without Update"
+
+		got, _ := store.GetByID(task.ID)
+		got.Title = "Changed again"
+
+		listed, _ := store.List(FilterAll)
+		if len(listed) != 1 || listed[0].Title != "Original" {
+			t.Errorf("stored task was modified without Update: %+v", listed)
+		}
+	})
+
+	t.Run("TimeFilters", func(t *testing.T) {
+		store := newStore(t)
+
+		now := time.Now()
+		for _, task := range []*todo.Task{
+			{Title: "Last week", Date: now.AddDate(0, 0, -7), CreatedAt: now, UpdatedAt: now},
+			{Title: "Yesterday", Date: now.AddDate(0, 0, -1), CreatedAt: now, UpdatedAt: now},
+			{Title: "Start of today", Date: StartOfDay(now), CreatedAt: now, UpdatedAt: now},
+			{Title: "End of today", Date: EndOfDay(now), CreatedAt: now, UpdatedAt: now},
+			{Title: "Tomorrow", Date: StartOfDay(now).AddDate(0, 0, 1), CreatedAt: now, UpdatedAt: now},
+			{Title: "Skipped today", Date: now, Skipped: true, CreatedAt: now, UpdatedAt: now},
+			{Title: "Skipped tomorrow", Date: now.AddDate(0, 0, 1), Skipped: true, CreatedAt: now, UpdatedAt: now},
+		} {
+			if err := store.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		tests := []struct {
+			filter TimeFilter
+			want   string
+		}{
+			{FilterPast, "Last week,Yesterday"},
+			{FilterCurrent, "Start of today,End of today"},
+			{FilterFuture, "Tomorrow"},
+			{FilterAll, "Last week,Yesterday,Start of today,End of today,Tomorrow"},
+		}
+		for _, tt := range tests {
+			tasks, err := store.List(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to list tasks: %v", err)
+			}
+			if got := joinTitles(tasks); got != tt.want {
+				t.Errorf("filter %d: got %s, want %s", tt.filter, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("AllIncludesSkipped", func(t *testing.T) {
+		store := newStore(t)
+
+		now := time.Now()
+		for _, task := range []*todo.Task{
+			{Title: "Tomorrow", Date: now.AddDate(0, 0, 1), CreatedAt: now, UpdatedAt: now},
+			{Title: "Skipped yesterday", Date: now.AddDate(0, 0, -1), Skipped: true, CreatedAt: now, UpdatedAt: now},
+			{Title: "Today", Date: now, Completed: true, CreatedAt: now, UpdatedAt: now},
+		} {
+			if err := store.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		tasks, err := store.All()
+		if err != nil {
+			t.Fatalf("failed to list all tasks: %v", err)
+		}
+		want := "Skipped yesterday,Today,Tomorrow"
+		if got := joinTitles(tasks); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("IterateMatchesList", func(t *testing.T) {
+		store := newStore(t)
+
+		now := time.Now()
+		for i, offset := range []int{-3, -1, 0, 0, 2, 5} {
+			task := &todo.Task{
+				Title:     fmt.Sprintf("Task %d", i),
+				Date:      StartOfDay(now).AddDate(0, 0, offset),
+				Skipped:   i == 3,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			if err := store.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		for _, filter := range []TimeFilter{FilterAll, FilterPast, FilterCurrent, FilterFuture} {
+			listed, err := store.List(filter)
+			if err != nil {
+				t.Fatalf("failed to list tasks: %v", err)
+			}
+
+			var iterated []*todo.Task
+			for task, err := range Iterate(store, filter) {
+				if err != nil {
+					t.Fatalf("failed to iterate tasks: %v", err)
+				}
+				iterated = append(iterated, task)
+			}
+
+			if got, want := joinTitles(iterated), joinTitles(listed); got != want {
+				t.Errorf("filter %d: iterated %s, listed %s", filter, got, want)
+			}
+		}
+
+		// Stopping early must not leak the query or fail later calls.
+		for range Iterate(store, FilterAll) {
+			break
+		}
+		if _, err := store.List(FilterAll); err != nil {
+			t.Errorf("failed to list tasks after stopping iteration: %v", err)
+		}
+	})
+
+	t.Run("OrderByDateThenCreation", func(t *testing.T) {
+		store := newStore(t)
+
+		day := StartOfDay(time.Now()).AddDate(0, 0, 3)
+		created := time.Now()
+		for _, task := range []*todo.Task{
+			{Title: "Later day", Date: day.AddDate(0, 0, 1), CreatedAt: created.Add(-time.Hour), UpdatedAt: created},
+			{Title: "Created second", Date: day, CreatedAt: created, UpdatedAt: created},
+			{Title: "Created first", Date: day, CreatedAt: created.Add(-time.Minute), UpdatedAt: created},
+			{Title: "Created second too", Date: day, CreatedAt: created, UpdatedAt: created},
+		} {
+			if err := store.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		tasks, err := store.List(FilterFuture)
+		if err != nil {
+			t.Fatalf("failed to list tasks: %v", err)
+		}
+		want := "Created first,Created second,Created second too,Later day"
+		if got := joinTitles(tasks); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("DeleteLeavesTombstone", func(t *testing.T) {
+		store := newStore(t)
+		replica := store.(Replica)
+
+		imported, _ := todo.NewTask("Imported", "", time.Now())
+		imported.UID = "event-1@example.com"
+		local, _ := todo.NewTask("Local", "", time.Now())
+		kept, _ := todo.NewTask("Kept", "", time.Now())
+		for _, task := range []*todo.Task{imported, local, kept} {
+			if err := store.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		before := time.Now().Add(-time.Second)
+		for _, task := range []*todo.Task{imported, local} {
+			if err := store.Delete(task.ID); err != nil {
+				t.Fatalf("failed to delete task: %v", err)
+			}
+		}
+
+		tombstones, err := replica.Tombstones()
+		if err != nil {
+			t.Fatalf("failed to list tombstones: %v", err)
+		}
+		if len(tombstones) != 2 {
+			t.Fatalf("expected 2 tombstones, got %+v", tombstones)
+		}
+		uids := map[string]bool{}
+		for _, tombstone := range tombstones {
+			uids[tombstone.UID] = true
+			if tombstone.DeletedAt.Before(before) {
+				t.Errorf("tombstone %s deleted at %v, before the delete", tombstone.UID, tombstone.DeletedAt)
+			}
+		}
+		if !uids[imported.UID] || !uids[local.StableUID()] {
+			t.Errorf("expected tombstones for %s and %s, got %+v", imported.UID, local.StableUID(), tombstones)
+		}
+	})
+
+	t.Run("ReplicaState", func(t *testing.T) {
+		store := newStore(t)
+		replica := store.(Replica)
+
+		id, err := replica.ReplicaID()
+		if err != nil || id == "" {
+			t.Fatalf("failed to get replica ID: %q, %v", id, err)
+		}
+		if again, _ := replica.ReplicaID(); again != id {
+			t.Errorf("replica ID changed from %s to %s", id, again)
+		}
+
+		if last, err := replica.LastSync("peer"); err != nil || !last.IsZero() {
+			t.Errorf("expected no last sync, got %v, %v", last, err)
+		}
+		synced := time.Date(2025, 11, 20, 8, 30, 0, 0, time.UTC)
+		if err := replica.SetLastSync("peer", synced); err != nil {
+			t.Fatalf("failed to record last sync: %v", err)
+		}
+		if last, err := replica.LastSync("peer"); err != nil || !last.Equal(synced) {
+			t.Errorf("expected last sync %v, got %v, %v", synced, last, err)
+		}
+		if last, _ := replica.LastSync("other"); !last.IsZero() {
+			t.Errorf("expected no last sync with another peer, got %v", last)
+		}
+	})
+}
+
+func assertSameTask(t *testing.T, got, want *todo.Task) {
+	t.Helper()
+
+	if got.ID != want.ID || got.Title != want.Title || got.Details != want.Details {
+		t.Errorf("got %d %q %q, want %d %q %q", got.ID, got.Title, got.Details, want.ID, want.Title, want.Details)
+	}
+	if got.Completed != want.Completed || got.Skipped != want.Skipped {
+		t.Errorf("got completed=%t skipped=%t, want completed=%t skipped=%t",
+			got.Completed, got.Skipped, want.Completed, want.Skipped)
+	}
+	if got.RecurrencePattern != want.RecurrencePattern {
+		t.Errorf("got pattern %q, want %q", got.RecurrencePattern, want.RecurrencePattern)
+	}
+	if got.UID != want.UID {
+		t.Errorf("got UID %q, want %q", got.UID, want.UID)
+	}
+	if got.Owner != want.Owner || got.Assignee != want.Assignee {
+		t.Errorf("got owner %q and assignee %q, want %q and %q", got.Owner, got.Assignee, want.Owner, want.Assignee)
+	}
+	gotDate, gotClock, gotZone := SplitSchedule(got.Date)
+	wantDate, wantClock, wantZone := SplitSchedule(want.Date)
+	if gotDate != wantDate || gotClock != wantClock || gotZone != wantZone {
+		t.Errorf("got schedule %s %s %s, want %s %s %s", gotDate, gotClock, gotZone, wantDate, wantClock, wantZone)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("got timestamps %v/%v, want %v/%v", got.CreatedAt, got.UpdatedAt, want.CreatedAt, want.UpdatedAt)
+	}
+}
+
+func joinTitles(tasks []*todo.Task) string {
+	titles := make([]string, len(tasks))
+	for i, task := range tasks {
+		titles[i] = task.Title
+	}
+	return strings.Join(titles, ",")
+}
+
+func TestSQLiteStorage_Conformance(t *testing.T) {
+	runConformanceTests(t, func(t *testing.T) Storage {
+		store, cleanup := setupTestDB(t)
+		t.Cleanup(cleanup)
+		return store
+	})
+}
+
+func TestMarkdownStorage_Conformance(t *testing.T) {
+	runConformanceTests(t, func(t *testing.T) Storage {
+		store, err := NewMarkdownStorage(t.TempDir())
+		if err != nil {
+			t.Fatalf("failed to create storage: %v", err)
+		}
+		return store
+	})
+}
+
+func TestMemoryStorage_Conformance(t *testing.T) {
+	runConformanceTests(t, func(t *testing.T) Storage {
+		return NewMemoryStorage()
+	})
+}
+
+// Compile-time checks that every backend implements Storage and Replica.
+var (
+	_ Storage = (*SQLiteStorage)(nil)
+	_ Storage = (*MarkdownStorage)(nil)
+	_ Storage = (*MemoryStorage)(nil)
+
+	_ Replica = (*SQLiteStorage)(nil)
+	_ Replica = (*MarkdownStorage)(nil)
+	_ Replica = (*MemoryStorage)(nil)
+)
@@ -0,0 +1,566 @@
+package storage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+const (
+	markdownSeqFile  = ".facienda-seq"
+	markdownLockFile = ".facienda.lock"
+	// markdownSyncFile holds the Replica state of the directory.
+	markdownSyncFile = ".facienda-sync.json"
+
+	// lockRetryInterval and lockTimeout bound how long a writer waits for
+	// another writer to release the directory lock.
+	lockRetryInterval = 10 * time.Millisecond
+	lockTimeout       = 10 * time.Second
+
+	// lockStaleAfter is the age after which a lock file left behind by a
+	// crashed process is removed.
+	lockStaleAfter = 30 * time.Second
+)
+
+// markdownFileRegex matches task file names. Files that don't match, such as
+// a README.md kept alongside the tasks, are ignored.
+var markdownFileRegex = regexp.MustCompile(`^(\d+)\.md$`)
+
+var errMalformedTaskFile = errors.New("malformed task file")
+
+// MarkdownStorage keeps one Markdown file per task in a directory. Each file
+// starts with a YAML frontmatter block holding the task's fields, followed by
+// the task details as the Markdown body. Files are named after the task ID
+// (000042.md) so that edits never rename them and diffs stay reviewable.
+//
+// Writers serialise on a lock file in the directory and replace task files
+// atomically, so several processes can share one directory.
+type MarkdownStorage struct {
+	dir string
+}
+
+func NewMarkdownStorage(dir string) (*MarkdownStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create task directory: %w", err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open task directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("failed to open task directory: %s is not a directory", dir)
+	}
+
+	return &MarkdownStorage{dir: dir}, nil
+}
+
+func (s *MarkdownStorage) Create(task *todo.Task) error {
+	return s.withLock(func() error {
+		if task.UID != "" {
+			if err := s.checkUID(task.UID, 0); err != nil {
+				return err
+			}
+		}
+		assignUID(task)
+
+		id, err := s.nextID()
+		if err != nil {
+			return fmt.Errorf("failed to create task: %w", err)
+		}
+
+		stored := *task
+		stored.ID = id
+		if err := s.writeTask(&stored); err != nil {
+			return fmt.Errorf("failed to create task: %w", err)
+		}
+		if err := writeFileAtomic(s.path(markdownSeqFile), []byte(strconv.FormatInt(id, 10)+"\n")); err != nil {
+			return fmt.Errorf("failed to record task sequence: %w", err)
+		}
+
+		task.ID = id
+		return nil
+	})
+}
+
+func (s *MarkdownStorage) GetByID(id int64) (*todo.Task, error) {
+	task, err := s.readTask(s.taskPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, todo.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	return task, nil
+}
+
+func (s *MarkdownStorage) List(filter TimeFilter) ([]*todo.Task, error) {
+	all, err := s.readAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	now := time.Now()
+	var tasks []*todo.Task
+	for _, task := range all {
+		if matchesFilter(task, filter, now) {
+			tasks = append(tasks, task)
+		}
+	}
+
+	sortTasks(tasks)
+	return tasks, nil
+}
+
+func (s *MarkdownStorage) All() ([]*todo.Task, error) {
+	tasks, err := s.readAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	sortTasks(tasks)
+	return tasks, nil
+}
+
+func (s *MarkdownStorage) Update(task *todo.Task) error {
+	return s.withLock(func() error {
+		current, err := s.readTask(s.taskPath(task.ID))
+		if errors.Is(err, os.ErrNotExist) {
+			return todo.ErrNotFound
+		}
+		// A file that can't be parsed may still be overwritten.
+		if err != nil || current.StableUID() != task.StableUID() {
+			if err := s.checkUID(task.StableUID(), task.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := s.writeTask(task); err != nil {
+			return fmt.Errorf("failed to update task: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *MarkdownStorage) Delete(id int64) error {
+	return s.withLock(func() error {
+		// A file that can't be parsed is still deleted, but leaves no
+		// tombstone, since its UID can't be known.
+		task, readErr := s.readTask(s.taskPath(id))
+
+		err := os.Remove(s.taskPath(id))
+		if errors.Is(err, os.ErrNotExist) {
+			return todo.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete task: %w", err)
+		}
+
+		if readErr == nil {
+			if err := s.addTombstone(task.StableUID(), time.Now()); err != nil {
+				return fmt.Errorf("failed to record deleted task: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *MarkdownStorage) Close() error {
+	return nil
+}
+
+// checkUID returns todo.ErrDuplicateUID if a task other than the one with
+// ID except has uid. The caller holds the directory lock.
+func (s *MarkdownStorage) checkUID(uid string, except int64) error {
+	tasks, err := s.readAll()
+	if err != nil {
+		return fmt.Errorf("failed to check UID: %w", err)
+	}
+	for _, task := range tasks {
+		if task.ID != except && task.StableUID() == uid {
+			return todo.ErrDuplicateUID
+		}
+	}
+	return nil
+}
+
+func (s *MarkdownStorage) path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+func (s *MarkdownStorage) taskPath(id int64) string {
+	return s.path(fmt.Sprintf("%06d.md", id))
+}
+
+// nextID returns the next task ID. Like SQLite's AUTOINCREMENT, IDs of
+// deleted tasks are never reused: the highest ID handed out is recorded in
+// the sequence file, and task files already present are taken into account
+// in case the sequence file was lost or never committed.
+func (s *MarkdownStorage) nextID() (int64, error) {
+	var last int64
+
+	data, err := os.ReadFile(s.path(markdownSeqFile))
+	switch {
+	case err == nil:
+		last, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid sequence file: %w", err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return 0, err
+	}
+
+	ids, err := s.taskIDs()
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if id > last {
+			last = id
+		}
+	}
+
+	return last + 1, nil
+}
+
+func (s *MarkdownStorage) taskIDs() ([]int64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := markdownFileRegex.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		id, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (s *MarkdownStorage) readAll() ([]*todo.Task, error) {
+	ids, err := s.taskIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*todo.Task, 0, len(ids))
+	for _, id := range ids {
+		task, err := s.readTask(s.taskPath(id))
+		if errors.Is(err, os.ErrNotExist) {
+			// Deleted by another process since the directory was read.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func (s *MarkdownStorage) readTask(path string) (*todo.Task, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := decodeMarkdownTask(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return task, nil
+}
+
+func (s *MarkdownStorage) writeTask(task *todo.Task) error {
+	return writeFileAtomic(s.taskPath(task.ID), encodeMarkdownTask(task))
+}
+
+// withLock runs fn while holding the directory lock. The lock is a file
+// created with O_EXCL, which works across processes and platforms.
+func (s *MarkdownStorage) withLock(fn func() error) error {
+	lockPath := s.path(markdownLockFile)
+	deadline := time.Now().Add(lockTimeout)
+
+	var held os.FileInfo
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			held, err = f.Stat()
+			f.Close()
+			if err != nil {
+				os.Remove(lockPath)
+				return fmt.Errorf("failed to lock task directory: %w", err)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("failed to lock task directory: %w", err)
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > lockStaleAfter {
+			removeLock(lockPath, info)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("failed to lock task directory: timed out waiting for %s", lockPath)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+	// Another writer may have taken the lock over as stale if fn ran for
+	// long enough, and its lock mustn't be removed.
+	defer removeLock(lockPath, held)
+
+	return fn()
+}
+
+// removeLock removes the lock file at path if it is still the file
+// described by info. Another process may replace the file at any moment,
+// so it is first renamed aside, which is atomic, and compared there: a
+// lock that turns out to be a different one is put back.
+func removeLock(path string, info os.FileInfo) {
+	aside := fmt.Sprintf("%s.%d-%d", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, aside); err != nil {
+		return
+	}
+	if current, err := os.Stat(aside); err == nil && (!os.SameFile(info, current) || !current.ModTime().Equal(info.ModTime())) {
+		// Link fails if yet another lock was taken meanwhile, which then
+		// stays the lock.
+		os.Link(aside, path)
+	}
+	os.Remove(aside)
+}
+
+// writeFileAtomic replaces path with data so that readers never observe a
+// partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, 0o644); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// MarshalTask renders a task in the file format of the markdown backend. A
+// task without an ID is written without one, for task files kept outside a
+// task directory, where IDs mean nothing.
+func MarshalTask(task *todo.Task) []byte {
+	return encodeMarkdownTask(task)
+}
+
+// UnmarshalTask parses a file written by MarshalTask. The task's ID is zero
+// if the file has none.
+func UnmarshalTask(data []byte) (*todo.Task, error) {
+	return parseMarkdownTask(data, "title", "date")
+}
+
+// encodeMarkdownTask renders a task as YAML frontmatter followed by its
+// details. Keys are always written in the same order so that diffs only show
+// fields that actually changed.
+func encodeMarkdownTask(task *todo.Task) []byte {
+	var b strings.Builder
+
+	b.WriteString("---\n")
+	if task.ID != 0 {
+		fmt.Fprintf(&b, "id: %d\n", task.ID)
+	}
+	fmt.Fprintf(&b, "title: %s\n", quoteYAML(task.Title))
+	date, clock, zone := SplitSchedule(task.Date)
+	fmt.Fprintf(&b, "date: %s\n", date)
+	if clock != "" {
+		fmt.Fprintf(&b, "time: %s\n", quoteYAML(clock))
+	}
+	if zone != "" {
+		fmt.Fprintf(&b, "zone: %s\n", quoteYAML(zone))
+	}
+	fmt.Fprintf(&b, "completed: %t\n", task.Completed)
+	fmt.Fprintf(&b, "skipped: %t\n", task.Skipped)
+	fmt.Fprintf(&b, "recurrence: %s\n", quoteYAML(string(task.RecurrencePattern)))
+	fmt.Fprintf(&b, "created_at: %s\n", task.CreatedAt.Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "updated_at: %s\n", task.UpdatedAt.Format(time.RFC3339Nano))
+	if task.UID != "" {
+		fmt.Fprintf(&b, "uid: %s\n", quoteYAML(task.UID))
+	}
+	if task.Owner != "" {
+		fmt.Fprintf(&b, "owner: %s\n", quoteYAML(task.Owner))
+	}
+	if task.Assignee != "" {
+		fmt.Fprintf(&b, "assignee: %s\n", quoteYAML(task.Assignee))
+	}
+	b.WriteString("---\n")
+
+	if task.Details != "" {
+		b.WriteString(task.Details)
+		b.WriteString("\n")
+	}
+
+	return []byte(b.String())
+}
+
+// decodeMarkdownTask parses a file written by encodeMarkdownTask. Hand-edited
+// files are accepted as long as the frontmatter keeps one "key: value" pair
+// per line; unknown keys are ignored.
+func decodeMarkdownTask(data []byte) (*todo.Task, error) {
+	return parseMarkdownTask(data, "id", "title", "date")
+}
+
+// parseMarkdownTask parses a task file that has to have the required keys.
+func parseMarkdownTask(data []byte, required ...string) (*todo.Task, error) {
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(content, "---\n") {
+		return nil, fmt.Errorf("%w: missing frontmatter", errMalformedTaskFile)
+	}
+
+	rest := content[len("---\n"):]
+	end := strings.Index(rest, "\n---\n")
+	var frontmatter, body string
+	switch {
+	case end >= 0:
+		frontmatter, body = rest[:end], rest[end+len("\n---\n"):]
+	case strings.HasSuffix(rest, "\n---"):
+		frontmatter = strings.TrimSuffix(rest, "\n---")
+	default:
+		return nil, fmt.Errorf("%w: unterminated frontmatter", errMalformedTaskFile)
+	}
+
+	task := &todo.Task{}
+	seen := map[string]bool{}
+	var date, clock, zone string
+
+	scanner := bufio.NewScanner(strings.NewReader(frontmatter))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid frontmatter line %q", errMalformedTaskFile, line)
+		}
+		key = strings.TrimSpace(key)
+		value, err := unquoteYAML(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", errMalformedTaskFile, key, err)
+		}
+		seen[key] = true
+
+		switch key {
+		case "id":
+			task.ID, err = strconv.ParseInt(value, 10, 64)
+		case "title":
+			task.Title = value
+		case "date":
+			date = value
+		case "time":
+			clock = value
+		case "zone":
+			zone = value
+		case "completed":
+			task.Completed, err = strconv.ParseBool(value)
+		case "skipped":
+			task.Skipped, err = strconv.ParseBool(value)
+		case "recurrence":
+			task.RecurrencePattern = recurrence.Pattern(value)
+		case "created_at":
+			task.CreatedAt, err = time.Parse(time.RFC3339Nano, value)
+		case "updated_at":
+			task.UpdatedAt, err = time.Parse(time.RFC3339Nano, value)
+		case "uid":
+			task.UID = value
+		case "owner":
+			task.Owner = value
+		case "assignee":
+			task.Assignee = value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", errMalformedTaskFile, key, err)
+		}
+	}
+
+	for _, key := range required {
+		if !seen[key] {
+			return nil, fmt.Errorf("%w: missing %q", errMalformedTaskFile, key)
+		}
+	}
+
+	// Files written before dates were stored as calendar dates hold an
+	// RFC 3339 instant.
+	if legacy, err := legacyCalendarDate(date); err == nil && len(date) > len(dateLayout) {
+		date, clock, zone = legacy, "", ""
+	}
+	var err error
+	task.Date, err = JoinSchedule(date, clock, zone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errMalformedTaskFile, err)
+	}
+
+	task.Details = strings.TrimSuffix(body, "\n")
+	return task, nil
+}
+
+// quoteYAML renders s as a YAML double-quoted scalar. Go's escape sequences
+// for quoted strings are a subset of YAML's, so strconv does the work.
+func quoteYAML(s string) string {
+	return strconv.Quote(s)
+}
+
+// unquoteYAML parses a scalar written by quoteYAML, a single-quoted scalar or
+// a plain scalar.
+func unquoteYAML(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	default:
+		return s, nil
+	}
+}
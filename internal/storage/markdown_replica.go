@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// markdownSyncState is the content of markdownSyncFile.
+type markdownSyncState struct {
+	ReplicaID  string               `json:"replica_id,omitempty"`
+	LastSync   map[string]time.Time `json:"last_sync,omitempty"`
+	Tombstones []Tombstone          `json:"tombstones,omitempty"`
+}
+
+func (s *MarkdownStorage) ReplicaID() (string, error) {
+	var id string
+	err := s.withLock(func() error {
+		return s.updateSyncState(func(state *markdownSyncState) (bool, error) {
+			if state.ReplicaID != "" {
+				id = state.ReplicaID
+				return false, nil
+			}
+			var err error
+			id, err = newReplicaID()
+			state.ReplicaID = id
+			return true, err
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read replica ID: %w", err)
+	}
+	return id, nil
+}
+
+func (s *MarkdownStorage) Tombstones() ([]Tombstone, error) {
+	state, err := s.readSyncState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted tasks: %w", err)
+	}
+	return state.Tombstones, nil
+}
+
+func (s *MarkdownStorage) LastSync(peer string) (time.Time, error) {
+	state, err := s.readSyncState()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read last sync: %w", err)
+	}
+	return state.LastSync[peer], nil
+}
+
+func (s *MarkdownStorage) SetLastSync(peer string, t time.Time) error {
+	err := s.withLock(func() error {
+		return s.updateSyncState(func(state *markdownSyncState) (bool, error) {
+			if state.LastSync == nil {
+				state.LastSync = map[string]time.Time{}
+			}
+			state.LastSync[peer] = t
+			return true, nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record last sync: %w", err)
+	}
+	return nil
+}
+
+// addTombstone records the deletion of the task with uid. The caller holds
+// the directory lock.
+func (s *MarkdownStorage) addTombstone(uid string, deletedAt time.Time) error {
+	return s.updateSyncState(func(state *markdownSyncState) (bool, error) {
+		for i, t := range state.Tombstones {
+			if t.UID == uid {
+				state.Tombstones[i].DeletedAt = deletedAt
+				return true, nil
+			}
+		}
+		state.Tombstones = append(state.Tombstones, Tombstone{UID: uid, DeletedAt: deletedAt})
+		return true, nil
+	})
+}
+
+func (s *MarkdownStorage) readSyncState() (*markdownSyncState, error) {
+	state := &markdownSyncState{}
+	data, err := os.ReadFile(s.path(markdownSyncFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", markdownSyncFile, err)
+	}
+	return state, nil
+}
+
+// updateSyncState reads the sync file, lets fn change it and writes it
+// back if fn reports a change. The caller holds the directory lock.
+func (s *MarkdownStorage) updateSyncState(fn func(*markdownSyncState) (bool, error)) error {
+	state, err := s.readSyncState()
+	if err != nil {
+		return err
+	}
+	changed, err := fn(state)
+	if err != nil || !changed {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(markdownSyncFile), append(data, '\n'))
+}
@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+func setupTestDir(t *testing.T) (*MarkdownStorage, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	store, err := NewMarkdownStorage(dir)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	return store, dir
+}
+
+func TestMarkdown_TaskLifecycle(t *testing.T) {
+	store, dir := setupTestDir(t)
+
+	task, _ := todo.NewTask("Buy groceries", "Milk, eggs, bread", time.Now())
+	if err := store.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if task.ID != 1 {
+		t.Errorf("expected first task ID to be 1, got %d", task.ID)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "000001.md")); err != nil {
+		t.Errorf("expected task file to exist: %v", err)
+	}
+
+	retrieved, err := store.GetByID(task.ID)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if retrieved.Title != task.Title || retrieved.Details != task.Details {
+		t.Errorf("got %q/%q, want %q/%q", retrieved.Title, retrieved.Details, task.Title, task.Details)
+	}
+
+	retrieved.Complete()
+	if err := store.Update(retrieved); err != nil {
+		t.Fatalf("failed to update task: %v", err)
+	}
+
+	updated, _ := store.GetByID(task.ID)
+	if !updated.Completed {
+		t.Error("expected task to be completed")
+	}
+
+	if err := store.Delete(task.ID); err != nil {
+		t.Fatalf("failed to delete task: %v", err)
+	}
+	if _, err := store.GetByID(task.ID); err != todo.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := store.Update(task); err != todo.ErrNotFound {
+		t.Errorf("expected ErrNotFound when updating deleted task, got: %v", err)
+	}
+	if err := store.Delete(task.ID); err != todo.ErrNotFound {
+		t.Errorf("expected ErrNotFound when deleting twice, got: %v", err)
+	}
+}
+
+func TestMarkdown_IDsAreNotReused(t *testing.T) {
+	store, _ := setupTestDir(t)
+
+	first, _ := todo.NewTask("First", "", time.Now())
+	second, _ := todo.NewTask("Second", "", time.Now())
+	store.Create(first)
+	store.Create(second)
+
+	if err := store.Delete(second.ID); err != nil {
+		t.Fatalf("failed to delete task: %v", err)
+	}
+
+	third, _ := todo.NewTask("Third", "", time.Now())
+	if err := store.Create(third); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if third.ID != 3 {
+		t.Errorf("expected ID 3 after deleting ID 2, got %d", third.ID)
+	}
+}
+
+func TestMarkdown_RoundTrip(t *testing.T) {
+	store, _ := setupTestDir(t)
+
+	date := time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC)
+	task := &todo.Task{
+		Title:             `Quote "this" and 'that': ok`,
+		Details:           "- [ ] first\n- [ ] second\n\n---\nnot frontmatter\n",
+		Date:              date,
+		Skipped:           true,
+		RecurrencePattern: recurrence.Pattern("weekly:monday"),
+		CreatedAt:         time.Date(2025, 12, 1, 9, 30, 15, 123456789, time.FixedZone("", 2*3600)),
+		UpdatedAt:         time.Date(2025, 12, 2, 10, 0, 0, 0, time.UTC),
+	}
+	if err := store.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	got, err := store.GetByID(task.ID)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+
+	if got.Title != task.Title {
+		t.Errorf("title: got %q, want %q", got.Title, task.Title)
+	}
+	if got.Details != task.Details {
+		t.Errorf("details: got %q, want %q", got.Details, task.Details)
+	}
+	if calendarDay(got.Date) != "2025-12-15" || !got.CreatedAt.Equal(task.CreatedAt) || !got.UpdatedAt.Equal(task.UpdatedAt) {
+		t.Errorf("timestamps changed: got %v/%v/%v", got.Date, got.CreatedAt, got.UpdatedAt)
+	}
+	if !got.Skipped || got.Completed {
+		t.Errorf("flags: got completed=%t skipped=%t", got.Completed, got.Skipped)
+	}
+	if got.RecurrencePattern != task.RecurrencePattern {
+		t.Errorf("pattern: got %q, want %q", got.RecurrencePattern, task.RecurrencePattern)
+	}
+}
+
+func TestMarkdown_FileFormat(t *testing.T) {
+	store, dir := setupTestDir(t)
+
+	date := time.Date(2025, 11, 20, 0, 0, 0, 0, time.UTC)
+	stamp := time.Date(2025, 11, 1, 8, 0, 0, 0, time.UTC)
+	task := &todo.Task{Title: "Meeting", Details: "Discuss Q4 results", Date: date, CreatedAt: stamp, UpdatedAt: stamp}
+	if err := store.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "000001.md"))
+	if err != nil {
+		t.Fatalf("failed to read task file: %v", err)
+	}
+
+	want := `---
+id: 1
+title: "Meeting"
+date: 2025-11-20
+completed: false
+skipped: false
+recurrence: ""
+created_at: 2025-11-01T08:00:00Z
+updated_at: 2025-11-01T08:00:00Z
+uid: "` + task.UID + `"
+---
+Discuss Q4 results
+`
+	if string(data) != want {
+		t.Errorf("unexpected file contents:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestMarkdown_HandEditedFiles(t *testing.T) {
+	store, dir := setupTestDir(t)
+
+	content := "---\r\nid: 7\r\ntitle: 'It''s done'\r\ndate: 2025-01-02T00:00:00Z\r\ncompleted: true\r\npriority: high\r\n---\r\nNotes\r\n"
+	if err := os.WriteFile(filepath.Join(dir, "000007.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Team tasks\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := store.List(FilterAll)
+	if err != nil {
+		t.Fatalf("failed to list tasks: %v", err)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("expected 1 task, got %d", len(tasks))
+	}
+	if tasks[0].Title != "It's done" || !tasks[0].Completed || tasks[0].Details != "Notes" || calendarDay(tasks[0].Date) != "2025-01-02" {
+		t.Errorf("unexpected task: %+v", tasks[0])
+	}
+
+	next, _ := todo.NewTask("Next", "", time.Now())
+	if err := store.Create(next); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if next.ID != 8 {
+		t.Errorf("expected ID after hand-added file to be 8, got %d", next.ID)
+	}
+}
+
+func TestMarkdown_MalformedFile(t *testing.T) {
+	store, dir := setupTestDir(t)
+
+	if err := os.WriteFile(filepath.Join(dir, "000003.md"), []byte("no frontmatter here\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := store.GetByID(3)
+	if err == nil || !strings.Contains(err.Error(), "000003.md") {
+		t.Errorf("expected error naming the file, got: %v", err)
+	}
+}
+
+func TestMarkdown_TimeFiltersAndOrder(t *testing.T) {
+	store, _ := setupTestDir(t)
+
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+
+	tasks := []*todo.Task{
+		{Title: "Future task", Date: now.AddDate(0, 0, 2), CreatedAt: now, UpdatedAt: now},
+		{Title: "Current second", Date: now, CreatedAt: now, UpdatedAt: now},
+		{Title: "Past task", Date: now.AddDate(0, 0, -1), CreatedAt: now, UpdatedAt: now},
+		{Title: "Current first", Date: now, CreatedAt: earlier, UpdatedAt: earlier},
+		{Title: "Skipped task", Date: now, Skipped: true, CreatedAt: now, UpdatedAt: now},
+	}
+	for _, task := range tasks {
+		if err := store.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	titles := func(filter TimeFilter) []string {
+		list, err := store.List(filter)
+		if err != nil {
+			t.Fatalf("failed to list tasks: %v", err)
+		}
+		var out []string
+		for _, task := range list {
+			out = append(out, task.Title)
+		}
+		return out
+	}
+
+	if got := strings.Join(titles(FilterPast), ","); got != "Past task" {
+		t.Errorf("past: got %s", got)
+	}
+	if got := strings.Join(titles(FilterCurrent), ","); got != "Current first,Current second" {
+		t.Errorf("current: got %s", got)
+	}
+	if got := strings.Join(titles(FilterFuture), ","); got != "Future task" {
+		t.Errorf("future: got %s", got)
+	}
+	if got := len(titles(FilterAll)); got != 4 {
+		t.Errorf("all: expected 4 tasks, got %d", got)
+	}
+}
+
+func TestMarkdown_ConcurrentCreates(t *testing.T) {
+	_, dir := setupTestDir(t)
+
+	const writers = 8
+	const perWriter = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each goroutine opens its own handle, as separate processes would.
+			store, err := NewMarkdownStorage(dir)
+			if err != nil {
+				errs <- err
+				return
+			}
+			for i := 0; i < perWriter; i++ {
+				task, _ := todo.NewTask("Concurrent", "", time.Now())
+				if err := store.Create(task); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent create failed: %v", err)
+	}
+
+	store, _ := NewMarkdownStorage(dir)
+	tasks, err := store.List(FilterAll)
+	if err != nil {
+		t.Fatalf("failed to list tasks: %v", err)
+	}
+	if len(tasks) != writers*perWriter {
+		t.Errorf("expected %d tasks, got %d", writers*perWriter, len(tasks))
+	}
+
+	seen := map[int64]bool{}
+	for _, task := range tasks {
+		if seen[task.ID] {
+			t.Errorf("duplicate task ID %d", task.ID)
+		}
+		seen[task.ID] = true
+	}
+}
+
+func TestMarkdown_StaleLock(t *testing.T) {
+	store, dir := setupTestDir(t)
+	lockPath := filepath.Join(dir, markdownLockFile)
+
+	// A lock left behind by a writer that crashed is taken over.
+	if err := os.WriteFile(lockPath, []byte("1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * lockStaleAfter)
+	os.Chtimes(lockPath, old, old)
+	stale, _ := os.Stat(lockPath)
+
+	task, _ := todo.NewTask("After a crash", "", time.Now())
+	if err := store.Create(task); err != nil {
+		t.Fatalf("expected the stale lock to be taken over: %v", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("expected the lock to be released, got %v", err)
+	}
+
+	// A fresh lock that replaced the stale one after it was found stale
+	// is kept.
+	if err := os.WriteFile(lockPath, []byte("2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	removeLock(lockPath, stale)
+	if data, err := os.ReadFile(lockPath); err != nil || string(data) != "2\n" {
+		t.Errorf("expected the fresh lock to be kept, got %q, %v", data, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), markdownLockFile+".") {
+			t.Errorf("unexpected file %s left behind", entry.Name())
+		}
+	}
+}
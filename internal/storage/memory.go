@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// MemoryStorage keeps tasks in memory. It has the same filtering and
+// ordering semantics as SQLiteStorage and needs no cgo, which makes it
+// suitable for tests and for programs embedding facienda.
+//
+// Tasks are copied on the way in and out, so callers can't modify stored
+// tasks without going through Update. Dates are normalised the way the other
+// backends store them, to a calendar date with an optional time and zone.
+type MemoryStorage struct {
+	mu     sync.RWMutex
+	tasks  map[int64]*todo.Task
+	lastID int64
+
+	replicaID  string
+	tombstones []Tombstone
+	lastSync   map[string]time.Time
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{tasks: make(map[int64]*todo.Task), lastSync: make(map[string]time.Time)}
+}
+
+func (s *MemoryStorage) Create(task *todo.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if task.UID != "" && s.uidTaken(task.UID, 0) {
+		return todo.ErrDuplicateUID
+	}
+	assignUID(task)
+
+	s.lastID++
+	task.ID = s.lastID
+
+	stored := *task
+	stored.Date = normalizeSchedule(stored.Date)
+	s.tasks[stored.ID] = &stored
+	return nil
+}
+
+func (s *MemoryStorage) GetByID(id int64) (*todo.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, ok := s.tasks[id]
+	if !ok {
+		return nil, todo.ErrNotFound
+	}
+
+	task := *stored
+	return &task, nil
+}
+
+func (s *MemoryStorage) List(filter TimeFilter) ([]*todo.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var tasks []*todo.Task
+	for _, stored := range s.tasks {
+		if matchesFilter(stored, filter, now) {
+			task := *stored
+			tasks = append(tasks, &task)
+		}
+	}
+
+	sortTasks(tasks)
+	return tasks, nil
+}
+
+func (s *MemoryStorage) All() ([]*todo.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := make([]*todo.Task, 0, len(s.tasks))
+	for _, stored := range s.tasks {
+		task := *stored
+		tasks = append(tasks, &task)
+	}
+
+	sortTasks(tasks)
+	return tasks, nil
+}
+
+func (s *MemoryStorage) Update(task *todo.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[task.ID]; !ok {
+		return todo.ErrNotFound
+	}
+	if s.uidTaken(task.StableUID(), task.ID) {
+		return todo.ErrDuplicateUID
+	}
+
+	stored := *task
+	stored.Date = normalizeSchedule(stored.Date)
+	s.tasks[stored.ID] = &stored
+	return nil
+}
+
+func (s *MemoryStorage) Delete(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tasks[id]
+	if !ok {
+		return todo.ErrNotFound
+	}
+
+	delete(s.tasks, id)
+	s.tombstones = append(s.tombstones, Tombstone{UID: stored.StableUID(), DeletedAt: time.Now()})
+	return nil
+}
+
+// uidTaken reports whether a task other than the one with ID except has
+// uid. The caller holds the lock.
+func (s *MemoryStorage) uidTaken(uid string, except int64) bool {
+	for id, task := range s.tasks {
+		if id != except && task.StableUID() == uid {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+func (s *MemoryStorage) ReplicaID() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.replicaID == "" {
+		id, err := newReplicaID()
+		if err != nil {
+			return "", fmt.Errorf("failed to create replica ID: %w", err)
+		}
+		s.replicaID = id
+	}
+	return s.replicaID, nil
+}
+
+func (s *MemoryStorage) Tombstones() ([]Tombstone, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]Tombstone(nil), s.tombstones...), nil
+}
+
+func (s *MemoryStorage) LastSync(peer string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.lastSync[peer], nil
+}
+
+func (s *MemoryStorage) SetLastSync(peer string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastSync[peer] = t
+	return nil
+}
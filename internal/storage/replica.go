@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// newReplicaID returns a random replica ID. Stores are told apart by it
+// rather than by their path, which differs between the machines that sync
+// the same store.
+func newReplicaID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts of the stored schedule fields.
+const (
+	dateLayout  = "2006-01-02"
+	clockLayout = "15:04"
+)
+
+// SplitSchedule breaks a task's date into the fields the backends and export
+// formats store: a calendar date, an optional time of day and an optional
+// time zone.
+//
+// The calendar date is the one in the time's own location, so a task added
+// for 2025-11-20 stays on 2025-11-20 whatever zone it is later read in. A
+// time at midnight means the task is for the whole day and is stored as a
+// date only. Times in the local zone are stored without a zone and read back
+// in the reader's local zone; other zones are stored by IANA name, or as a
+// UTC offset when the location has no loadable name.
+func SplitSchedule(t time.Time) (date, clock, zone string) {
+	date = t.Format(dateLayout)
+	if t.Equal(StartOfDay(t)) {
+		return date, "", ""
+	}
+
+	clock = t.Format(clockLayout)
+	loc := t.Location()
+	switch {
+	case loc == time.Local:
+	case loc.String() != "" && loc.String() != "Local" && isLoadableZone(loc.String()):
+		zone = loc.String()
+	default:
+		zone = t.Format("-07:00")
+	}
+	return date, clock, zone
+}
+
+// JoinSchedule is the inverse of SplitSchedule. Whole-day tasks are returned
+// as midnight in the local zone.
+func JoinSchedule(date, clock, zone string) (time.Time, error) {
+	day, err := time.ParseInLocation(dateLayout, date, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: %w", date, err)
+	}
+	if clock == "" {
+		return day, nil
+	}
+
+	at, err := time.Parse(clockLayout, clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: %w", clock, err)
+	}
+
+	loc := time.Local
+	if zone != "" {
+		if loc, err = loadZone(zone); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	year, month, d := day.Date()
+	return time.Date(year, month, d, at.Hour(), at.Minute(), 0, 0, loc), nil
+}
+
+// normalizeSchedule returns t as it would read back after being stored, so
+// backends that keep tasks in memory behave like the ones that don't.
+func normalizeSchedule(t time.Time) time.Time {
+	normalized, err := JoinSchedule(SplitSchedule(t))
+	if err != nil {
+		return t
+	}
+	return normalized
+}
+
+// calendarDay returns the calendar date a task is scheduled on.
+func calendarDay(t time.Time) string {
+	return t.Format(dateLayout)
+}
+
+func isLoadableZone(name string) bool {
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// loadZone parses a zone written by SplitSchedule: an IANA name or a UTC
+// offset such as "+05:30".
+func loadZone(zone string) (*time.Location, error) {
+	if strings.HasPrefix(zone, "+") || strings.HasPrefix(zone, "-") {
+		hours, minutes, ok := strings.Cut(zone[1:], ":")
+		h, errH := strconv.Atoi(hours)
+		m, errM := strconv.Atoi(minutes)
+		if !ok || errH != nil || errM != nil {
+			return nil, fmt.Errorf("invalid time zone offset %q", zone)
+		}
+		offset := h*3600 + m*60
+		if zone[0] == '-' {
+			offset = -offset
+		}
+		return time.FixedZone("", offset), nil
+	}
+
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", zone, err)
+	}
+	return loc, nil
+}
+
+// legacyCalendarDate converts a date stored as an instant by older versions
+// into a calendar date. The date is taken in the offset the instant was
+// stored with, which is the zone it was created in; any time of day is
+// dropped, since older versions only ever meant whole days.
+func legacyCalendarDate(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(dateLayout), nil
+	case []byte:
+		return legacyCalendarDate(string(v))
+	case string:
+		s := strings.TrimSpace(v)
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05.999999999"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.Format(dateLayout), nil
+			}
+		}
+		if len(s) >= len(dateLayout) {
+			if _, err := time.Parse(dateLayout, s[:len(dateLayout)]); err == nil {
+				return s[:len(dateLayout)], nil
+			}
+		}
+		return "", fmt.Errorf("unrecognised date %q", v)
+	default:
+		return "", fmt.Errorf("unrecognised date %v", value)
+	}
+}
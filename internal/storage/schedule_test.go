@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// testZones are local time zone settings the filters are run under: both
+// sides of UTC, up to the extremes where the local date differs from the
+// UTC date for most of the day.
+var testZones = []*time.Location{
+	time.UTC,
+	time.FixedZone("UTC-11", -11*3600),
+	time.FixedZone("UTC-5", -5*3600),
+	time.FixedZone("UTC+5:30", 5*3600+30*60),
+	time.FixedZone("UTC+14", 14*3600),
+}
+
+// withLocalZone runs fn with time.Local set to loc.
+func withLocalZone(t *testing.T, loc *time.Location, fn func(t *testing.T)) {
+	t.Helper()
+
+	saved := time.Local
+	time.Local = loc
+	defer func() { time.Local = saved }()
+
+	t.Run(loc.String(), fn)
+}
+
+func TestTimeFilters_AcrossTimeZones(t *testing.T) {
+	backends := map[string]func(t *testing.T) Storage{
+		"sqlite": func(t *testing.T) Storage {
+			store, cleanup := setupTestDB(t)
+			t.Cleanup(cleanup)
+			return store
+		},
+		"markdown": func(t *testing.T) Storage {
+			store, err := NewMarkdownStorage(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to create storage: %v", err)
+			}
+			return store
+		},
+		"memory": func(t *testing.T) Storage {
+			return NewMemoryStorage()
+		},
+	}
+
+	for name, newStore := range backends {
+		t.Run(name, func(t *testing.T) {
+			for _, loc := range testZones {
+				withLocalZone(t, loc, func(t *testing.T) {
+					store := newStore(t)
+
+					now := time.Now()
+					year, month, day := now.Date()
+					// Dates parsed from YYYY-MM-DD without a zone are UTC
+					// midnight, which is a different instant from local
+					// midnight everywhere but UTC.
+					utcDay := func(offset int) time.Time {
+						return time.Date(year, month, day+offset, 0, 0, 0, 0, time.UTC)
+					}
+					for _, task := range []*todo.Task{
+						{Title: "Yesterday", Date: utcDay(-1)},
+						{Title: "Today", Date: utcDay(0)},
+						{Title: "Late today", Date: time.Date(year, month, day, 23, 30, 0, 0, time.Local)},
+						{Title: "Tomorrow", Date: utcDay(1)},
+					} {
+						task.CreatedAt, task.UpdatedAt = now, now
+						if err := store.Create(task); err != nil {
+							t.Fatalf("failed to create task: %v", err)
+						}
+					}
+
+					tests := []struct {
+						filter TimeFilter
+						want   string
+					}{
+						{FilterPast, "Yesterday"},
+						{FilterCurrent, "Today,Late today"},
+						{FilterFuture, "Tomorrow"},
+					}
+					for _, tt := range tests {
+						tasks, err := store.List(tt.filter)
+						if err != nil {
+							t.Fatalf("failed to list tasks: %v", err)
+						}
+						if got := joinTitles(tasks); got != tt.want {
+							t.Errorf("filter %d: got %s, want %s", tt.filter, got, tt.want)
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestSchedule_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name                string
+		date                time.Time
+		wantDate, wantClock string
+		wantZone            string
+	}{
+		{"whole day in UTC", time.Date(2025, 3, 9, 0, 0, 0, 0, time.UTC), "2025-03-09", "", ""},
+		{"whole day east of UTC", time.Date(2025, 3, 9, 0, 0, 0, 0, time.FixedZone("", 9*3600)), "2025-03-09", "", ""},
+		{"local time", time.Date(2025, 3, 9, 18, 5, 0, 0, time.Local), "2025-03-09", "18:05", ""},
+		{"named zone", time.Date(2025, 3, 9, 7, 0, 0, 0, mustLoadLocation(t, "America/New_York")), "2025-03-09", "07:00", "America/New_York"},
+		{"fixed offset", time.Date(2025, 3, 9, 23, 59, 0, 0, time.FixedZone("", -(3*3600+30*60))), "2025-03-09", "23:59", "-03:30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, clock, zone := SplitSchedule(tt.date)
+			if date != tt.wantDate || clock != tt.wantClock || zone != tt.wantZone {
+				t.Fatalf("SplitSchedule() = %q %q %q, want %q %q %q", date, clock, zone, tt.wantDate, tt.wantClock, tt.wantZone)
+			}
+
+			got, err := JoinSchedule(date, clock, zone)
+			if err != nil {
+				t.Fatalf("JoinSchedule() error = %v", err)
+			}
+			if calendarDay(got) != tt.wantDate {
+				t.Errorf("JoinSchedule() date = %s, want %s", calendarDay(got), tt.wantDate)
+			}
+			if clock != "" && !got.Equal(tt.date) {
+				t.Errorf("JoinSchedule() = %v, want %v", got, tt.date)
+			}
+		})
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestMigrate_CalendarDates(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "instants.db")
+
+	// A database from before calendar dates, holding the instants older
+	// versions wrote: UTC midnight from --date, and local times from tasks
+	// added without one.
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE tasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		details TEXT,
+		date DATETIME NOT NULL,
+		completed BOOLEAN NOT NULL DEFAULT 0,
+		skipped BOOLEAN NOT NULL DEFAULT 0,
+		recurrence_pattern TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+	CREATE INDEX idx_tasks_date ON tasks(date);
+	INSERT INTO tasks (title, details, date, created_at, updated_at) VALUES
+		('From --date', '', '2025-01-02 00:00:00+00:00', '2025-01-01 00:00:00+00:00', '2025-01-01 00:00:00+00:00'),
+		('Evening west', '', '2025-01-02 23:30:00-05:00', '2025-01-01 00:00:00+00:00', '2025-01-01 00:00:00+00:00'),
+		('Morning east', '', '2025-01-02 06:15:00.123+13:00', '2025-01-01 00:00:00+00:00', '2025-01-01 00:00:00+00:00');
+	PRAGMA user_version = 1;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	for _, loc := range testZones {
+		withLocalZone(t, loc, func(t *testing.T) {
+			store, err := NewSQLiteStorage(dbPath)
+			if err != nil {
+				t.Fatalf("failed to open database: %v", err)
+			}
+			defer store.Close()
+
+			tasks, err := store.All()
+			if err != nil {
+				t.Fatalf("failed to list tasks: %v", err)
+			}
+			if len(tasks) != 3 {
+				t.Fatalf("expected 3 tasks, got %d", len(tasks))
+			}
+			for _, task := range tasks {
+				if calendarDay(task.Date) != "2025-01-02" || !task.Date.Equal(StartOfDay(task.Date)) {
+					t.Errorf("%s: got date %v, want the whole day 2025-01-02", task.Title, task.Date)
+				}
+			}
+
+			problems, err := store.IntegrityCheck()
+			if err != nil {
+				t.Fatalf("failed to check integrity: %v", err)
+			}
+			if len(problems) != 0 {
+				t.Errorf("expected no problems, got %v", problems)
+			}
+		})
+	}
+}
+
+func TestIntegrityCheck_MalformedSchedule(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	task, _ := todo.NewTask("Hand edited", "", time.Now())
+	if err := store.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if _, err := store.db.Exec(`UPDATE tasks SET scheduled_date = 'next week' WHERE id = ?`, task.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := store.IntegrityCheck()
+	if err != nil {
+		t.Fatalf("failed to check integrity: %v", err)
+	}
+	if len(problems) != 1 {
+		t.Errorf("expected one problem, got %v", problems)
+	}
+}
+
+func TestList_SkipsMalformedSchedule(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+	var reported []error
+	WithUnreadableTasks(func(err error) { reported = append(reported, err) })(store)
+
+	for _, title := range []string{"Hand edited", "Pay rent"} {
+		task, _ := todo.NewTask(title, "", time.Now())
+		if err := store.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+	if _, err := store.db.Exec(`UPDATE tasks SET scheduled_date = 'next week' WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		tasks, err := store.All()
+		if err != nil {
+			t.Fatalf("expected the unreadable task to be skipped, got %v", err)
+		}
+		if len(tasks) != 1 || tasks[0].Title != "Pay rent" {
+			t.Errorf("unexpected tasks %+v", tasks)
+		}
+	}
+	for task, err := range store.Iter(FilterAll) {
+		if err != nil || task.Title != "Pay rent" {
+			t.Errorf("unexpected task %+v, %v", task, err)
+		}
+	}
+	if len(reported) != 1 {
+		t.Errorf("expected the task to be reported once, got %v", reported)
+	}
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// VisibleLister is implemented by storage backends that can restrict a
+// listing to the tasks one user can see in the query itself, instead of
+// reading every task and filtering them.
+type VisibleLister interface {
+	// ListVisible returns the tasks List(filter) would return that user
+	// owns or is assigned to.
+	ListVisible(user string, filter TimeFilter) ([]*todo.Task, error)
+	// AllVisible returns the tasks All would return that user owns or is
+	// assigned to.
+	AllVisible(user string) ([]*todo.Task, error)
+}
+
+// ScopedStorage is the part of a Storage one user of the server mode has
+// access to: the tasks they own or are assigned to. Other tasks don't exist
+// as far as it is concerned. A user changes the tasks they can see but
+// gives away or deletes only the tasks they own, and tasks they create are
+// their own.
+//
+// ScopedStorage deliberately doesn't implement Wrapper, so that As can't
+// reach past it to a backend interface, such as UIDFinder, that would
+// return tasks the user can't see.
+type ScopedStorage struct {
+	inner Storage
+	user  string
+
+	mu sync.Mutex
+	// owners are the owners of the tasks last read through this store,
+	// which the checks of Update and Delete go by.
+	owners map[int64]string
+}
+
+// Scope restricts s to the tasks user can see.
+func Scope(s Storage, user string) *ScopedStorage {
+	return &ScopedStorage{inner: s, user: user, owners: make(map[int64]string)}
+}
+
+// User returns the user the store is restricted to.
+func (s *ScopedStorage) User() string {
+	return s.user
+}
+
+func (s *ScopedStorage) visible(task *todo.Task) bool {
+	return task.Owner == s.user || task.Assignee == s.user
+}
+
+func (s *ScopedStorage) remember(tasks ...*todo.Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, task := range tasks {
+		s.owners[task.ID] = task.Owner
+	}
+}
+
+// owner returns the owner of task id as this store last read it, reading
+// the task if it hasn't. Tasks the user can't see are not found.
+func (s *ScopedStorage) owner(id int64) (string, error) {
+	s.mu.Lock()
+	owner, ok := s.owners[id]
+	s.mu.Unlock()
+	if ok {
+		return owner, nil
+	}
+
+	task, err := s.GetByID(id)
+	if err != nil {
+		return "", err
+	}
+	return task.Owner, nil
+}
+
+func (s *ScopedStorage) Create(task *todo.Task) error {
+	if task.Owner == "" {
+		task.Owner = s.user
+	}
+	if task.Owner != s.user {
+		return todo.ErrForbidden
+	}
+	if err := s.inner.Create(task); err != nil {
+		return err
+	}
+	s.remember(task)
+	return nil
+}
+
+func (s *ScopedStorage) GetByID(id int64) (*todo.Task, error) {
+	task, err := s.inner.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.visible(task) {
+		return nil, todo.ErrNotFound
+	}
+	s.remember(task)
+	return task, nil
+}
+
+func (s *ScopedStorage) List(filter TimeFilter) ([]*todo.Task, error) {
+	if lister, ok := As[VisibleLister](s.inner); ok {
+		tasks, err := lister.ListVisible(s.user, filter)
+		if err != nil {
+			return nil, err
+		}
+		s.remember(tasks...)
+		return tasks, nil
+	}
+	tasks, err := s.inner.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.filter(tasks), nil
+}
+
+func (s *ScopedStorage) All() ([]*todo.Task, error) {
+	if lister, ok := As[VisibleLister](s.inner); ok {
+		tasks, err := lister.AllVisible(s.user)
+		if err != nil {
+			return nil, err
+		}
+		s.remember(tasks...)
+		return tasks, nil
+	}
+	tasks, err := s.inner.All()
+	if err != nil {
+		return nil, err
+	}
+	return s.filter(tasks), nil
+}
+
+func (s *ScopedStorage) filter(tasks []*todo.Task) []*todo.Task {
+	var visible []*todo.Task
+	for _, task := range tasks {
+		if s.visible(task) {
+			visible = append(visible, task)
+		}
+	}
+	s.remember(visible...)
+	return visible
+}
+
+// Update changes a task the user can see. Only its owner may give it to
+// someone else.
+func (s *ScopedStorage) Update(task *todo.Task) error {
+	owner, err := s.owner(task.ID)
+	if err != nil {
+		return err
+	}
+	if task.Owner != owner && owner != s.user {
+		return todo.ErrForbidden
+	}
+	if err := s.inner.Update(task); err != nil {
+		return err
+	}
+	if s.visible(task) {
+		s.remember(task)
+	} else {
+		s.forget(task.ID)
+	}
+	return nil
+}
+
+// Delete deletes a task the user owns.
+func (s *ScopedStorage) Delete(id int64) error {
+	owner, err := s.owner(id)
+	if err != nil {
+		return err
+	}
+	if owner != s.user {
+		return todo.ErrForbidden
+	}
+	if err := s.inner.Delete(id); err != nil {
+		return err
+	}
+	s.forget(id)
+	return nil
+}
+
+func (s *ScopedStorage) forget(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.owners, id)
+}
+
+// Close does nothing: the underlying storage is shared with other users
+// and closed by whoever opened it.
+func (s *ScopedStorage) Close() error {
+	return nil
+}
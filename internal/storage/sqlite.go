@@ -2,92 +2,193 @@ package storage
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"iter"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/johnmirolha/facienda/internal/recurrence"
 	"github.com/johnmirolha/facienda/internal/todo"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
+const (
+	// busyTimeout is how long SQLite itself waits for a lock held by another
+	// connection before reporting SQLITE_BUSY.
+	busyTimeout = 5 * time.Second
+
+	// busyRetries and busyRetryDelay control the retries on top of the busy
+	// timeout, for the cases where SQLite reports SQLITE_BUSY without waiting
+	// (e.g. when a read transaction can't be upgraded to a write).
+	busyRetries    = 5
+	busyRetryDelay = 50 * time.Millisecond
+)
+
+// SQLiteStorage stores tasks in a SQLite database. The database is opened in
+// WAL mode so that readers never block the writer, and several processes can
+// use the same file at once.
+//
+// Update uses optimistic concurrency: it only succeeds if the task's
+// updated_at in the database is still the one this SQLiteStorage last read
+// or wrote, and returns todo.ErrConflict otherwise. Tasks the handle has never
+// seen are updated unconditionally.
 type SQLiteStorage struct {
-	db *sql.DB
+	db   *sql.DB
+	stmt *sqliteStatements
+
+	// migrationBackup, if set, returns the path an existing database is
+	// copied to before schema migrations are applied to it.
+	migrationBackup func() (string, error)
+
+	// unreadable, if set, is told about tasks that reads skip because
+	// their row can't be turned into a task.
+	unreadable func(err error)
+
+	mu       sync.Mutex
+	versions map[int64]time.Time
+	reported map[int64]bool
 }
 
-func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+// SQLiteOption configures optional behaviour of a SQLiteStorage.
+type SQLiteOption func(*SQLiteStorage)
+
+// WithMigrationBackup makes NewSQLiteStorage take a snapshot of an existing
+// database before upgrading its schema. dest is only called when a
+// migration is actually pending.
+func WithMigrationBackup(dest func() (string, error)) SQLiteOption {
+	return func(s *SQLiteStorage) {
+		s.migrationBackup = dest
+	}
+}
+
+// WithUnreadableTasks makes the storage call report for each task it skips
+// while listing because its row can't be read, such as one whose date was
+// edited by hand. Each task is reported once per handle; IntegrityCheck
+// reports them too.
+func WithUnreadableTasks(report func(err error)) SQLiteOption {
+	return func(s *SQLiteStorage) {
+		s.unreadable = report
+	}
+}
+
+func NewSQLiteStorage(dbPath string, opts ...SQLiteOption) (*SQLiteStorage, error) {
+	db, err := sql.Open("sqlite3", sqliteDSN(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := retryBusy(db.Ping); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	s := &SQLiteStorage{db: db}
-	if err := s.migrate(); err != nil {
+	s := &SQLiteStorage{db: db, versions: make(map[int64]time.Time)}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := retryBusy(s.migrate); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	if s.stmt, err = prepareStatements(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	return s, nil
 }
 
-func (s *SQLiteStorage) migrate() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS tasks (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT NOT NULL,
-		details TEXT,
-		date DATETIME NOT NULL,
-		completed BOOLEAN NOT NULL DEFAULT 0,
-		skipped BOOLEAN NOT NULL DEFAULT 0,
-		recurrence_pattern TEXT NOT NULL DEFAULT '',
-		created_at DATETIME NOT NULL,
-		updated_at DATETIME NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_tasks_date ON tasks(date);
-	CREATE INDEX IF NOT EXISTS idx_tasks_completed ON tasks(completed);
-	CREATE INDEX IF NOT EXISTS idx_tasks_skipped ON tasks(skipped);
-	`
-
-	if _, err := s.db.Exec(query); err != nil {
-		return err
+// sqliteDSN adds the connection parameters facienda relies on to dbPath:
+// WAL journaling, a busy timeout, and write locks taken at the start of each
+// transaction so that read-modify-write transactions can't deadlock.
+func sqliteDSN(dbPath string) string {
+	params := fmt.Sprintf("_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=%d&_txlock=immediate",
+		busyTimeout.Milliseconds())
+	if strings.Contains(dbPath, "?") {
+		return dbPath + "&" + params
 	}
+	return dbPath + "?" + params
+}
 
-	// Add recurrence_pattern column if it doesn't exist (for existing databases)
-	alterQuery := `
-	ALTER TABLE tasks ADD COLUMN recurrence_pattern TEXT NOT NULL DEFAULT '';
-	`
-	// This will fail if the column already exists, which is fine
-	s.db.Exec(alterQuery)
+// retryBusy calls fn until it succeeds, fails with an error other than
+// SQLITE_BUSY/SQLITE_LOCKED, or runs out of retries.
+func retryBusy(fn func() error) error {
+	delay := busyRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isBusy(err) || attempt == busyRetries {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
 
-	// Add skipped column if it doesn't exist (for existing databases)
-	alterSkippedQuery := `
-	ALTER TABLE tasks ADD COLUMN skipped BOOLEAN NOT NULL DEFAULT 0;
-	`
-	// This will fail if the column already exists, which is fine
-	s.db.Exec(alterSkippedQuery)
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
 
-	return nil
+// isUniqueViolation reports whether err comes from a UNIQUE index, which
+// only the uid column has.
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
 }
 
-func (s *SQLiteStorage) Create(task *todo.Task) error {
-	query := `
-	INSERT INTO tasks (title, details, date, completed, skipped, recurrence_pattern, created_at, updated_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`
+// remember records the updated_at value this handle last saw for a task.
+func (s *SQLiteStorage) remember(task *todo.Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.versions[task.ID] = task.UpdatedAt
+}
 
-	result, err := s.db.Exec(query,
-		task.Title,
-		task.Details,
-		task.Date,
-		task.Completed,
-		task.Skipped,
-		string(task.RecurrencePattern),
-		task.CreatedAt,
-		task.UpdatedAt,
-	)
+func (s *SQLiteStorage) forget(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.versions, id)
+}
+
+func (s *SQLiteStorage) version(id int64) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.versions[id]
+	return v, ok
+}
+
+func (s *SQLiteStorage) Create(task *todo.Task) error {
+	assignUID(task)
+	date, clock, zone := SplitSchedule(task.Date)
+	var result sql.Result
+	err := retryBusy(func() error {
+		var err error
+		result, err = s.stmt.insert.Exec(
+			task.Title,
+			task.Details,
+			date,
+			clock,
+			zone,
+			task.Completed,
+			task.Skipped,
+			string(task.RecurrencePattern),
+			task.CreatedAt,
+			task.UpdatedAt,
+			task.UID,
+			task.Owner,
+			task.Assignee,
+		)
+		return err
+	})
+	if isUniqueViolation(err) {
+		return todo.ErrDuplicateUID
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create task: %w", err)
 	}
@@ -98,67 +199,179 @@ func (s *SQLiteStorage) Create(task *todo.Task) error {
 	}
 
 	task.ID = id
+	s.remember(task)
 	return nil
 }
 
 func (s *SQLiteStorage) GetByID(id int64) (*todo.Task, error) {
-	query := `
-	SELECT id, title, details, date, completed, skipped, recurrence_pattern, created_at, updated_at
-	FROM tasks
-	WHERE id = ?
-	`
+	var task *todo.Task
+	err := retryBusy(func() error {
+		var err error
+		task, err = scanTask(s.stmt.get.QueryRow(id))
+		return err
+	})
+	if err == sql.ErrNoRows {
+		return nil, todo.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	s.remember(task)
+	return task, nil
+}
+
+func (s *SQLiteStorage) List(filter TimeFilter) ([]*todo.Task, error) {
+	stmt, args := s.listStatement(filter)
+	return s.queryTasks(stmt, args...)
+}
+
+func (s *SQLiteStorage) All() ([]*todo.Task, error) {
+	return s.queryTasks(s.stmt.all)
+}
+
+// listStatement returns the prepared query behind List for filter and its
+// arguments.
+func (s *SQLiteStorage) listStatement(filter TimeFilter) (*sql.Stmt, []interface{}) {
+	switch filter {
+	case FilterPast, FilterCurrent, FilterFuture:
+		return s.stmt.list[filter], []interface{}{calendarDay(time.Now())}
+	default:
+		return s.stmt.list[FilterAll], nil
+	}
+}
+
+// ListVisible returns the tasks List(filter) would return that user owns or
+// is assigned to, selecting them in the query.
+func (s *SQLiteStorage) ListVisible(user string, filter TimeFilter) ([]*todo.Task, error) {
+	_, args := s.listStatement(filter)
+	return s.queryTasks(s.stmt.visible[filter], append([]interface{}{user, user}, args...)...)
+}
+
+// AllVisible returns the tasks All would return that user owns or is
+// assigned to.
+func (s *SQLiteStorage) AllVisible(user string) ([]*todo.Task, error) {
+	return s.queryTasks(s.stmt.allVisible, user, user)
+}
+
+// Iter streams the tasks List would return, reading them from the database
+// one at a time instead of loading them all into memory. Iteration stops at
+// the first error, which is yielded with a nil task; unreadable tasks are
+// skipped, as in List.
+//
+// Tasks read through Iter are not tracked for optimistic concurrency, so
+// Update treats them like tasks this handle has never seen.
+func (s *SQLiteStorage) Iter(filter TimeFilter) iter.Seq2[*todo.Task, error] {
+	return func(yield func(*todo.Task, error) bool) {
+		stmt, args := s.listStatement(filter)
+
+		var rows *sql.Rows
+		err := retryBusy(func() error {
+			var err error
+			rows, err = stmt.Query(args...)
+			return err
+		})
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to list tasks: %w", err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			task, err := scanTask(rows)
+			if s.skipUnreadable(err) {
+				continue
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("failed to scan task: %w", err))
+				return
+			}
+			if !yield(task, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(nil, fmt.Errorf("error iterating tasks: %w", err))
+		}
+	}
+}
 
+// scanTask reads a row selected with taskColumns.
+func scanTask(row interface {
+	Scan(dest ...interface{}) error
+}) (*todo.Task, error) {
 	task := &todo.Task{}
-	var recurrencePattern string
-	err := s.db.QueryRow(query, id).Scan(
+	var date, clock, zone, recurrencePattern string
+	err := row.Scan(
 		&task.ID,
 		&task.Title,
 		&task.Details,
-		&task.Date,
+		&date,
+		&clock,
+		&zone,
 		&task.Completed,
 		&task.Skipped,
 		&recurrencePattern,
 		&task.CreatedAt,
 		&task.UpdatedAt,
+		&task.UID,
+		&task.Owner,
+		&task.Assignee,
 	)
-	if err == sql.ErrNoRows {
-		return nil, todo.ErrNotFound
-	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get task: %w", err)
+		return nil, err
 	}
 
+	task.Date, err = JoinSchedule(date, clock, zone)
+	if err != nil {
+		return nil, &unreadableTaskError{id: task.ID, err: err}
+	}
 	task.RecurrencePattern = recurrence.Pattern(recurrencePattern)
 	return task, nil
 }
 
-func (s *SQLiteStorage) List(filter TimeFilter) ([]*todo.Task, error) {
-	query := `
-	SELECT id, title, details, date, completed, skipped, recurrence_pattern, created_at, updated_at
-	FROM tasks
-	WHERE skipped = 0
-	`
+// unreadableTaskError is returned by scanTask for a row that was read but
+// doesn't hold a valid task.
+type unreadableTaskError struct {
+	id  int64
+	err error
+}
 
-	args := []interface{}{}
-	now := time.Now()
-	today := StartOfDay(now)
+func (e *unreadableTaskError) Error() string { return fmt.Sprintf("task %d: %v", e.id, e.err) }
+func (e *unreadableTaskError) Unwrap() error { return e.err }
 
-	switch filter {
-	case FilterPast:
-		query += " AND date < ?"
-		args = append(args, today)
-	case FilterCurrent:
-		query += " AND date >= ? AND date <= ?"
-		args = append(args, today, EndOfDay(now))
-	case FilterFuture:
-		tomorrow := today.AddDate(0, 0, 1)
-		query += " AND date >= ?"
-		args = append(args, tomorrow)
+// skipUnreadable reports whether err is about a single unreadable task,
+// which listings skip rather than failing as a whole, and reports the task
+// the first time it is skipped.
+func (s *SQLiteStorage) skipUnreadable(err error) bool {
+	var unreadable *unreadableTaskError
+	if !errors.As(err, &unreadable) {
+		return false
 	}
 
-	query += " ORDER BY date ASC, created_at ASC"
+	s.mu.Lock()
+	first := !s.reported[unreadable.id]
+	if s.reported == nil {
+		s.reported = map[int64]bool{}
+	}
+	s.reported[unreadable.id] = true
+	s.mu.Unlock()
+
+	if first && s.unreadable != nil {
+		s.unreadable(err)
+	}
+	return true
+}
 
-	rows, err := s.db.Query(query, args...)
+// queryTasks runs a prepared list query and returns the tasks it selects,
+// skipping unreadable ones.
+func (s *SQLiteStorage) queryTasks(stmt *sql.Stmt, args ...interface{}) ([]*todo.Task, error) {
+	var rows *sql.Rows
+	err := retryBusy(func() error {
+		var err error
+		rows, err = stmt.Query(args...)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tasks: %w", err)
 	}
@@ -166,23 +379,14 @@ func (s *SQLiteStorage) List(filter TimeFilter) ([]*todo.Task, error) {
 
 	var tasks []*todo.Task
 	for rows.Next() {
-		task := &todo.Task{}
-		var recurrencePattern string
-		err := rows.Scan(
-			&task.ID,
-			&task.Title,
-			&task.Details,
-			&task.Date,
-			&task.Completed,
-			&task.Skipped,
-			&recurrencePattern,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-		)
+		task, err := scanTask(rows)
+		if s.skipUnreadable(err) {
+			continue
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan task: %w", err)
 		}
-		task.RecurrencePattern = recurrence.Pattern(recurrencePattern)
+		s.remember(task)
 		tasks = append(tasks, task)
 	}
 
@@ -193,57 +397,168 @@ func (s *SQLiteStorage) List(filter TimeFilter) ([]*todo.Task, error) {
 	return tasks, nil
 }
 
+// FindByUIDPrefix returns the tasks whose UID starts with prefix. LIKE
+// ignores the case of ASCII letters, so ULIDs match however they are typed.
+func (s *SQLiteStorage) FindByUIDPrefix(prefix string) ([]*todo.Task, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+	return s.queryTasks(s.stmt.byUID, escaped+"%")
+}
+
+// IntegrityCheck runs SQLite's integrity check and returns the problems it
+// reports, followed by any rows whose schedule columns can't be read (which
+// only happens when the database was edited by hand). An empty result means
+// the database file is sound.
+func (s *SQLiteStorage) IntegrityCheck() ([]string, error) {
+	rows, err := s.db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check database integrity: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("failed to check database integrity: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check database integrity: %w", err)
+	}
+
+	schedules, err := s.db.Query(`SELECT id, scheduled_date, scheduled_time, scheduled_zone FROM tasks`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check task dates: %w", err)
+	}
+	defer schedules.Close()
+
+	for schedules.Next() {
+		var (
+			id                int64
+			date, clock, zone string
+		)
+		if err := schedules.Scan(&id, &date, &clock, &zone); err != nil {
+			return nil, fmt.Errorf("failed to check task dates: %w", err)
+		}
+		if _, err := JoinSchedule(date, clock, zone); err != nil {
+			problems = append(problems, fmt.Sprintf("task %d: %v", id, err))
+		}
+	}
+	if err := schedules.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check task dates: %w", err)
+	}
+
+	return problems, nil
+}
+
 func (s *SQLiteStorage) Update(task *todo.Task) error {
-	query := `
-	UPDATE tasks
-	SET title = ?, details = ?, date = ?, completed = ?, skipped = ?, recurrence_pattern = ?, updated_at = ?
-	WHERE id = ?
-	`
+	expected, checkVersion := s.version(task.ID)
+
+	err := retryBusy(func() error {
+		return s.update(task, expected, checkVersion)
+	})
+	if errors.Is(err, todo.ErrNotFound) || errors.Is(err, todo.ErrConflict) {
+		return err
+	}
+	if isUniqueViolation(err) {
+		return todo.ErrDuplicateUID
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update task: %w", err)
+	}
 
-	result, err := s.db.Exec(query,
+	s.remember(task)
+	return nil
+}
+
+// update writes task inside a transaction, first checking that the row's
+// updated_at still matches expected when checkVersion is set.
+func (s *SQLiteStorage) update(task *todo.Task, expected time.Time, checkVersion bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current time.Time
+	err = tx.Stmt(s.stmt.version).QueryRow(task.ID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return todo.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if checkVersion && !current.Equal(expected) {
+		return todo.ErrConflict
+	}
+
+	date, clock, zone := SplitSchedule(task.Date)
+	_, err = tx.Stmt(s.stmt.update).Exec(
 		task.Title,
 		task.Details,
-		task.Date,
+		date,
+		clock,
+		zone,
 		task.Completed,
 		task.Skipped,
 		string(task.RecurrencePattern),
 		task.UpdatedAt,
+		task.UID,
+		task.Owner,
+		task.Assignee,
 		task.ID,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
-	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rows == 0 {
-		return todo.ErrNotFound
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 func (s *SQLiteStorage) Delete(id int64) error {
-	query := `DELETE FROM tasks WHERE id = ?`
-
-	result, err := s.db.Exec(query, id)
+	err := retryBusy(func() error {
+		return s.delete(id)
+	})
+	if errors.Is(err, todo.ErrNotFound) {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete task: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
+	s.forget(id)
+	return nil
+}
+
+// delete removes a task and records its tombstone in one transaction.
+func (s *SQLiteStorage) delete(id int64) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return err
 	}
-	if rows == 0 {
+	defer tx.Rollback()
+
+	deleted := todo.Task{ID: id}
+	err = tx.Stmt(s.stmt.identity).QueryRow(id).Scan(&deleted.UID, &deleted.CreatedAt)
+	if err == sql.ErrNoRows {
 		return todo.ErrNotFound
 	}
+	if err != nil {
+		return err
+	}
 
-	return nil
+	if _, err := tx.Stmt(s.stmt.remove).Exec(id); err != nil {
+		return err
+	}
+	if _, err := tx.Stmt(s.stmt.tombstone).Exec(deleted.StableUID(), time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (s *SQLiteStorage) Close() error {
-	return s.db.Close()
+	return errors.Join(s.stmt.Close(), s.db.Close())
 }
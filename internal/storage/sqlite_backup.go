@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// backupPagesPerStep is how many pages are copied per step of the online
+// backup. Between steps the source database is unlocked, so other processes
+// can keep writing while a backup or restore is running.
+const backupPagesPerStep = 256
+
+// Backup writes a consistent snapshot of the database to destPath using
+// SQLite's online backup API. It is safe to call while other connections
+// and processes are using the database. An existing file at destPath is
+// overwritten.
+func (s *SQLiteStorage) Backup(destPath string) error {
+	dest, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer dest.Close()
+
+	if err := copyDatabase(dest, s.db); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	// Snapshots are standalone files: switch them out of WAL mode so that no
+	// -wal or -shm files are needed next to them.
+	if _, err := dest.Exec("PRAGMA journal_mode = DELETE"); err != nil {
+		return fmt.Errorf("failed to finalize backup file: %w", err)
+	}
+
+	return nil
+}
+
+// Restore replaces the contents of the database with the snapshot at
+// srcPath, then brings the restored schema up to date. Other processes with
+// the database open see the restored data on their next query.
+func (s *SQLiteStorage) Restore(srcPath string) error {
+	if _, err := os.Stat(srcPath); err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+
+	src, err := sql.Open("sqlite3", "file:"+srcPath+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer src.Close()
+
+	var tables int
+	err = src.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'tasks'`).Scan(&tables)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if tables == 0 {
+		return fmt.Errorf("%s is not a facienda database", srcPath)
+	}
+
+	if err := retryBusy(func() error { return copyDatabase(s.db, src) }); err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	if _, err := s.db.Exec("PRAGMA journal_mode = WAL"); err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	s.mu.Lock()
+	s.versions = make(map[int64]time.Time)
+	s.mu.Unlock()
+
+	if err := retryBusy(s.migrate); err != nil {
+		return fmt.Errorf("failed to migrate restored database: %w", err)
+	}
+	return nil
+}
+
+// copyDatabase copies the main database of src into dest with the online
+// backup API.
+func copyDatabase(dest, src *sql.DB) error {
+	ctx := context.Background()
+
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			destSQLite, ok := destDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", destDriverConn)
+			}
+			srcSQLite, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", srcDriverConn)
+			}
+
+			backup, err := destSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+
+			for {
+				done, err := backup.Step(backupPagesPerStep)
+				if err != nil {
+					backup.Finish()
+					return err
+				}
+				if done {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			return backup.Finish()
+		})
+	})
+}
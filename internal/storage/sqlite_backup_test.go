@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+func TestBackup_SnapshotWhileInUse(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSQLiteStorage(filepath.Join(dir, "facienda.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	task, _ := todo.NewTask("Before backup", "", time.Now())
+	if err := store.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	snapshotPath := filepath.Join(dir, "snapshot.db")
+	if err := store.Backup(snapshotPath); err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+
+	// The snapshot is a standalone file that doesn't need WAL side files.
+	if _, err := os.Stat(snapshotPath + "-wal"); !os.IsNotExist(err) {
+		t.Errorf("expected no WAL file next to snapshot, got %v", err)
+	}
+
+	snapshot, err := NewSQLiteStorage(snapshotPath)
+	if err != nil {
+		t.Fatalf("failed to open snapshot: %v", err)
+	}
+	defer snapshot.Close()
+
+	got, err := snapshot.GetByID(task.ID)
+	if err != nil {
+		t.Fatalf("failed to read task from snapshot: %v", err)
+	}
+	if got.Title != "Before backup" {
+		t.Errorf("got %q, want %q", got.Title, "Before backup")
+	}
+}
+
+func TestBackup_Restore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSQLiteStorage(filepath.Join(dir, "facienda.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	kept, _ := todo.NewTask("Kept", "", time.Now())
+	store.Create(kept)
+
+	snapshotPath := filepath.Join(dir, "snapshot.db")
+	if err := store.Backup(snapshotPath); err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+
+	lost, _ := todo.NewTask("Added after backup", "", time.Now())
+	store.Create(lost)
+	kept.Complete()
+	store.Update(kept)
+
+	if err := store.Restore(snapshotPath); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+
+	tasks, err := store.List(FilterAll)
+	if err != nil {
+		t.Fatalf("failed to list tasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].Title != "Kept" || tasks[0].Completed {
+		t.Errorf("expected only the incomplete task from the snapshot, got %+v", tasks)
+	}
+
+	// Versions read before the restore must not cause spurious conflicts.
+	tasks[0].Complete()
+	if err := store.Update(tasks[0]); err != nil {
+		t.Errorf("failed to update restored task: %v", err)
+	}
+}
+
+func TestBackup_RestoreRejectsNonDatabase(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	other := filepath.Join(t.TempDir(), "other.db")
+	db, err := sql.Open("sqlite3", other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE notes (body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if err := store.Restore(other); err == nil {
+		t.Error("expected error restoring from a non-facienda database")
+	}
+	if err := store.Restore(filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Error("expected error restoring from a missing file")
+	}
+}
+
+func TestMigrate_LegacyDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "legacy.db")
+
+	// A database from before the recurrence and skip features.
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE tasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		details TEXT,
+		date DATETIME NOT NULL,
+		completed BOOLEAN NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+	INSERT INTO tasks (title, details, date, completed, created_at, updated_at)
+	VALUES ('Old task', '', '2025-01-02 00:00:00+00:00', 1, '2025-01-01 00:00:00+00:00', '2025-01-01 00:00:00+00:00');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	backupPath := filepath.Join(dir, "pre-migrate.db")
+	calls := 0
+	store, err := NewSQLiteStorage(dbPath, WithMigrationBackup(func() (string, error) {
+		calls++
+		return backupPath, nil
+	}))
+	if err != nil {
+		t.Fatalf("failed to open legacy database: %v", err)
+	}
+
+	tasks, err := store.List(FilterAll)
+	if err != nil {
+		t.Fatalf("failed to list tasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].Title != "Old task" || tasks[0].Skipped || tasks[0].IsRecurring() {
+		t.Errorf("unexpected tasks after migration: %+v", tasks)
+	}
+	// The task keeps the UID it was exported and synced with.
+	legacy := todo.Task{ID: 1, CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if len(tasks) == 1 && tasks[0].UID != legacy.StableUID() {
+		t.Errorf("expected UID %q, got %q", legacy.StableUID(), tasks[0].UID)
+	}
+	store.Close()
+
+	if calls != 1 {
+		t.Errorf("expected one pre-migration backup, got %d", calls)
+	}
+
+	// The backup holds the database as it was before migrating.
+	backupDB, err := sql.Open("sqlite3", backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backupDB.Close()
+	var columns int
+	if err := backupDB.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('tasks') WHERE name = 'skipped'`).Scan(&columns); err != nil {
+		t.Fatal(err)
+	}
+	if columns != 0 {
+		t.Error("expected the backup to have the unmigrated schema")
+	}
+
+	// Reopening an up-to-date database doesn't migrate or back up again.
+	store, err = NewSQLiteStorage(dbPath, WithMigrationBackup(func() (string, error) {
+		calls++
+		return backupPath, nil
+	}))
+	if err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	store.Close()
+	if calls != 1 {
+		t.Errorf("expected no further backups, got %d", calls)
+	}
+}
+
+func TestMigrate_NewDatabaseSkipsBackup(t *testing.T) {
+	called := false
+	store, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "new.db"), WithMigrationBackup(func() (string, error) {
+		called = true
+		return "", nil
+	}))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	store.Close()
+
+	if called {
+		t.Error("expected no backup of a freshly created database")
+	}
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// benchTasks returns the number of tasks the benchmarks seed the database
+// with. The default keeps `go test -bench .` quick; set FACIENDA_BENCH_TASKS
+// to measure large databases, e.g.
+//
+//	FACIENDA_BENCH_TASKS=1000000 go test -run '^$' -bench SQLite ./internal/storage
+func benchTasks(b *testing.B) int {
+	b.Helper()
+
+	n := 10_000
+	if v := os.Getenv("FACIENDA_BENCH_TASKS"); v != "" {
+		var err error
+		if n, err = strconv.Atoi(v); err != nil || n <= 0 {
+			b.Fatalf("invalid FACIENDA_BENCH_TASKS %q", v)
+		}
+	}
+	return n
+}
+
+// seedBenchDB creates a database holding n tasks shaped like years of daily
+// recurring tasks: a few per day, mostly in the past, some completed or
+// skipped.
+func seedBenchDB(b *testing.B, n int) string {
+	b.Helper()
+
+	dbPath := filepath.Join(b.TempDir(), "bench.db")
+	store, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		b.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		b.Fatal(err)
+	}
+	stmt, err := tx.Prepare(insertTaskSQL)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	const perDay = 5
+	today := StartOfDay(time.Now())
+	first := today.AddDate(0, 0, -(n/perDay)+30)
+	for i := 0; i < n; i++ {
+		day := first.AddDate(0, 0, i/perDay)
+		date, clock, zone := SplitSchedule(day)
+		_, err := stmt.Exec("Task "+strconv.Itoa(i), "", date, clock, zone,
+			day.Before(today) && i%3 != 0, i%20 == 0, "daily", day, day, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	return dbPath
+}
+
+func openBenchDB(b *testing.B) *SQLiteStorage {
+	b.Helper()
+
+	store, err := NewSQLiteStorage(seedBenchDB(b, benchTasks(b)))
+	if err != nil {
+		b.Fatalf("failed to open storage: %v", err)
+	}
+	b.Cleanup(func() { store.Close() })
+	return store
+}
+
+func BenchmarkSQLite_Open(b *testing.B) {
+	dbPath := seedBenchDB(b, benchTasks(b))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store, err := NewSQLiteStorage(dbPath)
+		if err != nil {
+			b.Fatal(err)
+		}
+		store.Close()
+	}
+}
+
+func BenchmarkSQLite_ListCurrent(b *testing.B) {
+	store := openBenchDB(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := store.List(FilterCurrent); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSQLite_ListPast(b *testing.B) {
+	store := openBenchDB(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := store.List(FilterPast); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSQLite_IterPast(b *testing.B) {
+	store := openBenchDB(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, err := range store.Iter(FilterPast) {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkSQLite_GetByID(b *testing.B) {
+	store := openBenchDB(b)
+	n := int64(benchTasks(b))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := store.GetByID(int64(i)%n + 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSQLite_CompleteTask(b *testing.B) {
+	store := openBenchDB(b)
+	n := int64(benchTasks(b))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		task, err := store.GetByID(int64(i)%n + 1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		task.Complete()
+		if err := store.Update(task); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestQueryPlans_UseIndexes(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	task, _ := todo.NewTask("Task", "", time.Now())
+	store.Create(task)
+	if _, err := store.db.Exec("ANALYZE"); err != nil {
+		t.Fatal(err)
+	}
+
+	queries := map[string][]interface{}{
+		allTasksSQL:                 nil,
+		listTasksSQL(FilterAll):     nil,
+		listTasksSQL(FilterPast):    {"2025-01-01"},
+		listTasksSQL(FilterCurrent): {"2025-01-01"},
+		listTasksSQL(FilterFuture):  {"2025-01-01"},
+	}
+	for query, args := range queries {
+		rows, err := store.db.Query("EXPLAIN QUERY PLAN "+query, args...)
+		if err != nil {
+			t.Fatalf("failed to explain %s: %v", query, err)
+		}
+
+		var plan []string
+		for rows.Next() {
+			var id, parent, notUsed int
+			var detail string
+			if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+				t.Fatal(err)
+			}
+			plan = append(plan, detail)
+		}
+		rows.Close()
+
+		joined := strings.Join(plan, "; ")
+		if !strings.Contains(joined, "USING INDEX idx_tasks_") || strings.Contains(joined, "TEMP B-TREE") {
+			t.Errorf("expected %s to read tasks in index order, got plan %q", query, joined)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+const (
+	hammerDBEnv     = "FACIENDA_HAMMER_DB"
+	hammerWorkerEnv = "FACIENDA_HAMMER_WORKER"
+	hammerTasks     = 40
+)
+
+func TestConcurrency_OptimisticUpdateConflict(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "facienda.db")
+
+	first, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open first handle: %v", err)
+	}
+	defer first.Close()
+
+	second, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open second handle: %v", err)
+	}
+	defer second.Close()
+
+	task, _ := todo.NewTask("Shared task", "", time.Now())
+	if err := first.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	mine, _ := first.GetByID(task.ID)
+	theirs, _ := second.GetByID(task.ID)
+
+	// Ensure the two edits get distinct timestamps.
+	time.Sleep(time.Millisecond)
+	theirs.Update("Edited elsewhere", "")
+	if err := second.Update(theirs); err != nil {
+		t.Fatalf("failed to update from second handle: %v", err)
+	}
+
+	mine.Complete()
+	if err := first.Update(mine); !errors.Is(err, todo.ErrConflict) {
+		t.Fatalf("expected ErrConflict for stale update, got %v", err)
+	}
+
+	// Reloading picks up the other edit and makes the update succeed.
+	mine, _ = first.GetByID(task.ID)
+	mine.Complete()
+	if err := first.Update(mine); err != nil {
+		t.Fatalf("failed to update after reload: %v", err)
+	}
+
+	got, _ := second.GetByID(task.ID)
+	if got.Title != "Edited elsewhere" || !got.Completed {
+		t.Errorf("expected both edits to survive, got %+v", got)
+	}
+}
+
+func TestConcurrency_UpdateUnseenTask(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "facienda.db")
+
+	first, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open first handle: %v", err)
+	}
+	defer first.Close()
+
+	task, _ := todo.NewTask("Task", "", time.Now())
+	if err := first.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	second, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open second handle: %v", err)
+	}
+	defer second.Close()
+
+	// The second handle has never read the task, so it can't know which
+	// version it is replacing and the update goes through unconditionally.
+	task.Complete()
+	if err := second.Update(task); err != nil {
+		t.Fatalf("expected update of unseen task to succeed, got %v", err)
+	}
+}
+
+func TestConcurrency_GoroutinesHammerOneDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "facienda.db")
+
+	setup, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	counter, _ := todo.NewTask("Counter", "", time.Now())
+	if err := setup.Create(counter); err != nil {
+		t.Fatalf("failed to create counter task: %v", err)
+	}
+	setup.Close()
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			errs <- hammer(dbPath, worker, counter.ID)
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	verifyHammer(t, dbPath, workers, counter.ID)
+}
+
+func TestConcurrency_ProcessesHammerOneDatabase(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping multi-process test in short mode")
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "facienda.db")
+
+	setup, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	counter, _ := todo.NewTask("Counter", "", time.Now())
+	if err := setup.Create(counter); err != nil {
+		t.Fatalf("failed to create counter task: %v", err)
+	}
+	setup.Close()
+
+	const workers = 4
+	cmds := make([]*exec.Cmd, workers)
+	outputs := make([]*strings.Builder, workers)
+	for w := 0; w < workers; w++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestConcurrency_HammerWorkerProcess$")
+		cmd.Env = append(os.Environ(),
+			hammerDBEnv+"="+dbPath,
+			hammerWorkerEnv+"="+strconv.Itoa(w),
+		)
+		outputs[w] = &strings.Builder{}
+		cmd.Stdout = outputs[w]
+		cmd.Stderr = outputs[w]
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("failed to start worker process: %v", err)
+		}
+		cmds[w] = cmd
+	}
+
+	for w, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("worker process %d failed: %v\n%s", w, err, outputs[w])
+		}
+	}
+
+	verifyHammer(t, dbPath, workers, counter.ID)
+}
+
+// TestConcurrency_HammerWorkerProcess is the body of each worker process
+// started by TestConcurrency_ProcessesHammerOneDatabase. It does nothing when
+// run as part of the normal test suite.
+func TestConcurrency_HammerWorkerProcess(t *testing.T) {
+	dbPath := os.Getenv(hammerDBEnv)
+	if dbPath == "" {
+		t.Skip("only runs as a worker process")
+	}
+
+	worker, err := strconv.Atoi(os.Getenv(hammerWorkerEnv))
+	if err != nil {
+		t.Fatalf("invalid worker number: %v", err)
+	}
+
+	// The counter task is always the first one created in the database.
+	if err := hammer(dbPath, worker, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// hammer opens its own handle on dbPath, creates hammerTasks tasks, reads
+// them back, and appends its worker number to the counter task's details
+// once per task using read-modify-write with retries on ErrConflict.
+func hammer(dbPath string, worker int, counterID int64) error {
+	store, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		return fmt.Errorf("worker %d: open: %w", worker, err)
+	}
+	defer store.Close()
+
+	for i := 0; i < hammerTasks; i++ {
+		task, _ := todo.NewTask(fmt.Sprintf("worker %d task %d", worker, i), "", time.Now())
+		if err := store.Create(task); err != nil {
+			return fmt.Errorf("worker %d: create: %w", worker, err)
+		}
+
+		if _, err := store.List(FilterCurrent); err != nil {
+			return fmt.Errorf("worker %d: list: %w", worker, err)
+		}
+
+		for {
+			counter, err := store.GetByID(counterID)
+			if err != nil {
+				return fmt.Errorf("worker %d: get counter: %w", worker, err)
+			}
+			counter.Update(counter.Title, counter.Details+fmt.Sprintf("%d,", worker))
+			err = store.Update(counter)
+			if errors.Is(err, todo.ErrConflict) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("worker %d: update counter: %w", worker, err)
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+func verifyHammer(t *testing.T, dbPath string, workers int, counterID int64) {
+	t.Helper()
+
+	store, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to reopen storage: %v", err)
+	}
+	defer store.Close()
+
+	tasks, err := store.List(FilterAll)
+	if err != nil {
+		t.Fatalf("failed to list tasks: %v", err)
+	}
+	if want := workers*hammerTasks + 1; len(tasks) != want {
+		t.Errorf("expected %d tasks, got %d", want, len(tasks))
+	}
+
+	counter, err := store.GetByID(counterID)
+	if err != nil {
+		t.Fatalf("failed to get counter task: %v", err)
+	}
+
+	// Every increment must have survived: no update was lost to a
+	// concurrent writer.
+	perWorker := map[string]int{}
+	for _, worker := range strings.Split(strings.TrimSuffix(counter.Details, ","), ",") {
+		perWorker[worker]++
+	}
+	for w := 0; w < workers; w++ {
+		if got := perWorker[strconv.Itoa(w)]; got != hammerTasks {
+			t.Errorf("expected %d increments from worker %d, got %d", hammerTasks, w, got)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// sqliteMigrations upgrade the schema one step at a time. The database's
+// PRAGMA user_version records how many of them have been applied, so each
+// migration runs exactly once per database. Append new migrations to the
+// end; never edit or reorder existing ones.
+var sqliteMigrations = []func(tx *sql.Tx) error{
+	migrateInitialSchema,
+	migrateCalendarDates,
+	migrateListIndexes,
+	migrateTaskUIDs,
+	migrateSyncState,
+	migrateUniqueUIDs,
+	migrateTaskOwners,
+}
+
+// migrateInitialSchema creates the tasks table. Databases created before
+// schema versioning may already have the table without the columns that
+// were added later, so those are added if missing.
+func migrateInitialSchema(tx *sql.Tx) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS tasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		details TEXT,
+		date DATETIME NOT NULL,
+		completed BOOLEAN NOT NULL DEFAULT 0,
+		skipped BOOLEAN NOT NULL DEFAULT 0,
+		recurrence_pattern TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+	`
+	if _, err := tx.Exec(query); err != nil {
+		return err
+	}
+
+	if err := ensureColumn(tx, "tasks", "recurrence_pattern", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "tasks", "skipped", "BOOLEAN NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	indexes := `
+	CREATE INDEX IF NOT EXISTS idx_tasks_date ON tasks(date);
+	CREATE INDEX IF NOT EXISTS idx_tasks_completed ON tasks(completed);
+	CREATE INDEX IF NOT EXISTS idx_tasks_skipped ON tasks(skipped);
+	`
+	_, err := tx.Exec(indexes)
+	return err
+}
+
+// migrateCalendarDates replaces the date column, which held an instant, with
+// a calendar date plus an optional time of day and zone (see SplitSchedule).
+// Existing rows keep the calendar date in the offset they were stored with
+// and lose their time of day: older versions only scheduled whole days, and
+// any time they recorded was an accident of how the date was entered.
+//
+// Dates that can't be parsed are copied unchanged so that nothing is lost;
+// IntegrityCheck reports them.
+func migrateCalendarDates(tx *sql.Tx) error {
+	columns := []struct{ name, definition string }{
+		{"scheduled_date", "TEXT NOT NULL DEFAULT ''"},
+		{"scheduled_time", "TEXT NOT NULL DEFAULT ''"},
+		{"scheduled_zone", "TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, c := range columns {
+		if err := ensureColumn(tx, "tasks", c.name, c.definition); err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.Query(`SELECT id, date FROM tasks`)
+	if err != nil {
+		return err
+	}
+	dates := map[int64]string{}
+	for rows.Next() {
+		var (
+			id    int64
+			value interface{}
+		)
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		date, err := legacyCalendarDate(value)
+		if err != nil {
+			date = fmt.Sprint(value)
+		}
+		dates[id] = date
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, date := range dates {
+		if _, err := tx.Exec(`UPDATE tasks SET scheduled_date = ? WHERE id = ?`, date, id); err != nil {
+			return err
+		}
+	}
+
+	statements := `
+	DROP INDEX IF EXISTS idx_tasks_date;
+	ALTER TABLE tasks DROP COLUMN date;
+	CREATE INDEX IF NOT EXISTS idx_tasks_scheduled_date ON tasks(scheduled_date);
+	`
+	_, err = tx.Exec(statements)
+	return err
+}
+
+// migrateListIndexes replaces the single-column indexes, which no query
+// could use for both filtering and ordering, with composite indexes shaped
+// like the list queries (see listTasksSQL and allTasksSQL): one for the
+// filtered listings, which skip skipped tasks, and one for All.
+func migrateListIndexes(tx *sql.Tx) error {
+	statements := `
+	DROP INDEX IF EXISTS idx_tasks_scheduled_date;
+	DROP INDEX IF EXISTS idx_tasks_completed;
+	DROP INDEX IF EXISTS idx_tasks_skipped;
+	CREATE INDEX IF NOT EXISTS idx_tasks_list ON tasks(skipped, scheduled_date, scheduled_time, created_at);
+	CREATE INDEX IF NOT EXISTS idx_tasks_order ON tasks(scheduled_date, scheduled_time, created_at);
+	`
+	_, err := tx.Exec(statements)
+	return err
+}
+
+// migrateTaskUIDs adds the uid column, which holds the identifier a task
+// was imported with (see todo.Task.UID), and an index for looking tasks up
+// by it.
+func migrateTaskUIDs(tx *sql.Tx) error {
+	if err := ensureColumn(tx, "tasks", "uid", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_tasks_uid ON tasks(uid)`)
+	return err
+}
+
+// migrateSyncState adds the tables behind Replica: tombstones for deleted
+// tasks, the time of the last sync with each peer, and sync_meta for the
+// replica ID.
+func migrateSyncState(tx *sql.Tx) error {
+	statements := `
+	CREATE TABLE IF NOT EXISTS tombstones (
+		uid TEXT PRIMARY KEY,
+		deleted_at DATETIME NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS sync_peers (
+		peer TEXT PRIMARY KEY,
+		last_sync DATETIME NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS sync_meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	`
+	_, err := tx.Exec(statements)
+	return err
+}
+
+// migrateUniqueUIDs gives every task a UID and makes the uid column unique.
+// Tasks without one get the identifier todo.Task.StableUID derived for
+// them, so that earlier exports and syncs still match them. Of tasks
+// imported more than once with the same UID, all but the first get a new
+// ULID.
+func migrateUniqueUIDs(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, uid, created_at FROM tasks ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	uids := map[int64]string{}
+	for rows.Next() {
+		var (
+			task    todo.Task
+			created interface{}
+		)
+		if err := rows.Scan(&task.ID, &task.UID, &created); err != nil {
+			rows.Close()
+			return err
+		}
+		// An unreadable creation time, which IntegrityCheck reports,
+		// can't be part of an identifier.
+		createdAt, ok := created.(time.Time)
+		if ok {
+			task.CreatedAt = createdAt
+		}
+
+		uid := task.StableUID()
+		if seen[uid] || (task.UID == "" && !ok) {
+			uid = newULID(time.Now())
+		}
+		seen[uid] = true
+		if uid != task.UID {
+			uids[task.ID] = uid
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, uid := range uids {
+		if _, err := tx.Exec(`UPDATE tasks SET uid = ? WHERE id = ?`, uid, id); err != nil {
+			return err
+		}
+	}
+
+	statements := `
+	DROP INDEX IF EXISTS idx_tasks_uid;
+	CREATE UNIQUE INDEX idx_tasks_uid ON tasks(uid);
+	`
+	_, err = tx.Exec(statements)
+	return err
+}
+
+// migrateTaskOwners adds the owner and assignee columns of the server
+// mode's users (see todo.Task.Owner), with indexes for the listings
+// restricted to one user's tasks.
+func migrateTaskOwners(tx *sql.Tx) error {
+	if err := ensureColumn(tx, "tasks", "owner", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "tasks", "assignee", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	statements := `
+	CREATE INDEX IF NOT EXISTS idx_tasks_owner ON tasks(owner);
+	CREATE INDEX IF NOT EXISTS idx_tasks_assignee ON tasks(assignee);
+	`
+	_, err := tx.Exec(statements)
+	return err
+}
+
+// ensureColumn adds a column to table unless it already exists.
+func ensureColumn(tx *sql.Tx, table, column, definition string) error {
+	exists, err := hasColumn(tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+func hasColumn(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid          int
+			name, ctype  string
+			notNull, pk  int
+			defaultValue sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &ctype, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// schemaVersion returns the number of migrations applied to the database.
+func (s *SQLiteStorage) schemaVersion() (int, error) {
+	var version int
+	err := s.db.QueryRow("PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+// hasTasks reports whether the database already holds a tasks table, i.e.
+// whether it is an existing database rather than a freshly created file.
+func (s *SQLiteStorage) hasTasks() (bool, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'tasks'`).Scan(&count)
+	return count > 0, err
+}
+
+// migrate applies all pending migrations in a single transaction. When
+// another process migrates the same database concurrently, the immediate
+// transaction makes one of them wait and then find nothing left to do.
+func (s *SQLiteStorage) migrate() error {
+	version, err := s.schemaVersion()
+	if err != nil {
+		return err
+	}
+	if version >= len(sqliteMigrations) {
+		return nil
+	}
+
+	if s.migrationBackup != nil {
+		existing, err := s.hasTasks()
+		if err != nil {
+			return err
+		}
+		if existing {
+			dest, err := s.migrationBackup()
+			if err != nil {
+				return fmt.Errorf("failed to prepare pre-migration backup: %w", err)
+			}
+			if err := s.Backup(dest); err != nil {
+				return fmt.Errorf("failed to back up before migrating: %w", err)
+			}
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		if err := sqliteMigrations[i](tx); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(sqliteMigrations))); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ReplicaID returns the ID stored in sync_meta, creating it first if the
+// database has none yet. A copy of the database file shares its ID, which
+// is harmless: the copies record their syncs with each other under it.
+func (s *SQLiteStorage) ReplicaID() (string, error) {
+	id, err := newReplicaID()
+	if err != nil {
+		return "", fmt.Errorf("failed to create replica ID: %w", err)
+	}
+
+	err = retryBusy(func() error {
+		if _, err := s.db.Exec(`INSERT OR IGNORE INTO sync_meta (key, value) VALUES ('replica_id', ?)`, id); err != nil {
+			return err
+		}
+		return s.db.QueryRow(`SELECT value FROM sync_meta WHERE key = 'replica_id'`).Scan(&id)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read replica ID: %w", err)
+	}
+	return id, nil
+}
+
+func (s *SQLiteStorage) Tombstones() ([]Tombstone, error) {
+	rows, err := s.db.Query(`SELECT uid, deleted_at FROM tombstones ORDER BY deleted_at, uid`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tombstones []Tombstone
+	for rows.Next() {
+		var t Tombstone
+		if err := rows.Scan(&t.UID, &t.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to read deleted task: %w", err)
+		}
+		tombstones = append(tombstones, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list deleted tasks: %w", err)
+	}
+	return tombstones, nil
+}
+
+func (s *SQLiteStorage) LastSync(peer string) (time.Time, error) {
+	var last time.Time
+	err := s.db.QueryRow(`SELECT last_sync FROM sync_peers WHERE peer = ?`, peer).Scan(&last)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read last sync: %w", err)
+	}
+	return last, nil
+}
+
+func (s *SQLiteStorage) SetLastSync(peer string, t time.Time) error {
+	err := retryBusy(func() error {
+		_, err := s.db.Exec(`INSERT OR REPLACE INTO sync_peers (peer, last_sync) VALUES (?, ?)`, peer, t)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record last sync: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// taskColumns are the columns scanTask expects, in order.
+const taskColumns = `id, title, details, scheduled_date, scheduled_time, scheduled_zone, completed, skipped, recurrence_pattern, created_at, updated_at, uid, owner, assignee`
+
+// listOrder is the order tasks are listed in. It matches the trailing
+// columns of idx_tasks_list and idx_tasks_order (the rowid, id, is the
+// implicit last column of every index), so SQLite reads rows in index order
+// instead of sorting them.
+const listOrder = ` ORDER BY scheduled_date ASC, scheduled_time ASC, created_at ASC, id ASC`
+
+const (
+	insertTaskSQL = `
+	INSERT INTO tasks (title, details, scheduled_date, scheduled_time, scheduled_zone, completed, skipped, recurrence_pattern, created_at, updated_at, uid, owner, assignee)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	getTaskSQL = `SELECT ` + taskColumns + ` FROM tasks WHERE id = ?`
+
+	taskVersionSQL = `SELECT updated_at FROM tasks WHERE id = ?`
+
+	updateTaskSQL = `
+	UPDATE tasks
+	SET title = ?, details = ?, scheduled_date = ?, scheduled_time = ?, scheduled_zone = ?,
+		completed = ?, skipped = ?, recurrence_pattern = ?, updated_at = ?, uid = ?,
+		owner = ?, assignee = ?
+	WHERE id = ?`
+
+	deleteTaskSQL = `DELETE FROM tasks WHERE id = ?`
+
+	// taskIdentitySQL reads what todo.Task.StableUID needs, for the
+	// tombstone of a deleted task.
+	taskIdentitySQL = `SELECT uid, created_at FROM tasks WHERE id = ?`
+
+	insertTombstoneSQL = `INSERT OR REPLACE INTO tombstones (uid, deleted_at) VALUES (?, ?)`
+
+	allTasksSQL = `SELECT ` + taskColumns + ` FROM tasks` + listOrder
+
+	tasksByUIDSQL = `SELECT ` + taskColumns + ` FROM tasks WHERE uid LIKE ? ESCAPE '\'` + listOrder
+)
+
+// listTasksSQL returns the query behind List for filter. Every variant but
+// FilterAll takes today's calendar date as its only argument.
+func listTasksSQL(filter TimeFilter) string {
+	where := ` WHERE skipped = 0`
+	switch filter {
+	case FilterPast:
+		where += ` AND scheduled_date < ?`
+	case FilterCurrent:
+		where += ` AND scheduled_date = ?`
+	case FilterFuture:
+		where += ` AND scheduled_date > ?`
+	}
+	return `SELECT ` + taskColumns + ` FROM tasks` + where + listOrder
+}
+
+// visibleTasksSQL returns the query behind ListVisible for filter, which is
+// listTasksSQL restricted to the tasks a user owns or is assigned to. It
+// takes the user twice, followed by the arguments of listTasksSQL.
+func visibleTasksSQL(filter TimeFilter) string {
+	query := listTasksSQL(filter)
+	return strings.Replace(query, ` WHERE `, ` WHERE (owner = ? OR assignee = ?) AND `, 1)
+}
+
+// allVisibleTasksSQL is allTasksSQL restricted as visibleTasksSQL is.
+const allVisibleTasksSQL = `SELECT ` + taskColumns + ` FROM tasks WHERE owner = ? OR assignee = ?` + listOrder
+
+// sqliteStatements are the statements SQLiteStorage runs for every command,
+// prepared once when the database is opened.
+type sqliteStatements struct {
+	insert     *sql.Stmt
+	get        *sql.Stmt
+	version    *sql.Stmt
+	update     *sql.Stmt
+	remove     *sql.Stmt
+	identity   *sql.Stmt
+	tombstone  *sql.Stmt
+	all        *sql.Stmt
+	byUID      *sql.Stmt
+	list       map[TimeFilter]*sql.Stmt
+	visible    map[TimeFilter]*sql.Stmt
+	allVisible *sql.Stmt
+}
+
+func prepareStatements(db *sql.DB) (*sqliteStatements, error) {
+	st := &sqliteStatements{list: make(map[TimeFilter]*sql.Stmt), visible: make(map[TimeFilter]*sql.Stmt)}
+
+	// prepare does nothing once a statement has failed, so only the first
+	// error is reported.
+	var err error
+	prepare := func(query string) *sql.Stmt {
+		if err != nil {
+			return nil
+		}
+		var stmt *sql.Stmt
+		stmt, err = db.Prepare(query)
+		return stmt
+	}
+
+	st.insert = prepare(insertTaskSQL)
+	st.get = prepare(getTaskSQL)
+	st.version = prepare(taskVersionSQL)
+	st.update = prepare(updateTaskSQL)
+	st.remove = prepare(deleteTaskSQL)
+	st.identity = prepare(taskIdentitySQL)
+	st.tombstone = prepare(insertTombstoneSQL)
+	st.all = prepare(allTasksSQL)
+	st.byUID = prepare(tasksByUIDSQL)
+	for _, filter := range []TimeFilter{FilterAll, FilterPast, FilterCurrent, FilterFuture} {
+		st.list[filter] = prepare(listTasksSQL(filter))
+		st.visible[filter] = prepare(visibleTasksSQL(filter))
+	}
+	st.allVisible = prepare(allVisibleTasksSQL)
+
+	if err != nil {
+		st.Close()
+		return nil, fmt.Errorf("failed to prepare statements: %w", err)
+	}
+	return st, nil
+}
+
+func (st *sqliteStatements) Close() error {
+	stmts := []*sql.Stmt{st.insert, st.get, st.version, st.update, st.remove, st.identity, st.tombstone, st.all, st.byUID, st.allVisible}
+	for _, stmt := range st.list {
+		stmts = append(stmts, stmt)
+	}
+	for _, stmt := range st.visible {
+		stmts = append(stmts, stmt)
+	}
+
+	var errs []error
+	for _, stmt := range stmts {
+		if stmt != nil {
+			errs = append(errs, stmt.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -1,7 +1,7 @@
 package storage
 
 import (
-	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -11,21 +11,17 @@ import (
 func setupTestDB(t *testing.T) (*SQLiteStorage, func()) {
 	t.Helper()
 
-	tmpFile, err := os.CreateTemp("", "facienda_test_*.db")
-	if err != nil {
-		t.Fatalf("failed to create temp db: %v", err)
-	}
-	tmpFile.Close()
+	// A fresh directory per test also cleans up the WAL and shared-memory
+	// files SQLite keeps next to the database.
+	dbPath := filepath.Join(t.TempDir(), "facienda_test.db")
 
-	store, err := NewSQLiteStorage(tmpFile.Name())
+	store, err := NewSQLiteStorage(dbPath)
 	if err != nil {
-		os.Remove(tmpFile.Name())
 		t.Fatalf("failed to create storage: %v", err)
 	}
 
 	cleanup := func() {
 		store.Close()
-		os.Remove(tmpFile.Name())
 	}
 
 	return store, cleanup
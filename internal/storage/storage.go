@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"fmt"
+	"iter"
+	"sort"
 	"time"
 
 	"github.com/johnmirolha/facienda/internal/todo"
@@ -10,11 +13,134 @@ type Storage interface {
 	Create(task *todo.Task) error
 	GetByID(id int64) (*todo.Task, error)
 	List(filter TimeFilter) ([]*todo.Task, error)
+	// All returns every task, including skipped ones, in the same order as
+	// List.
+	All() ([]*todo.Task, error)
 	Update(task *todo.Task) error
 	Delete(id int64) error
 	Close() error
 }
 
+// IntegrityChecker is implemented by storage backends that can verify the
+// consistency of their underlying files. IntegrityCheck returns the problems
+// found; an empty result means no problems.
+type IntegrityChecker interface {
+	IntegrityCheck() ([]string, error)
+}
+
+// Snapshotter is implemented by storage backends that can copy their data to
+// and from a standalone snapshot file while in use.
+type Snapshotter interface {
+	Backup(destPath string) error
+	Restore(srcPath string) error
+}
+
+// Replica is implemented by storage backends that can be synced with other
+// stores. Deleting a task leaves a Tombstone behind, so that a sync removes
+// the task from the other store instead of copying it back, and the time of
+// the last sync with each peer is recorded, so that a sync can tell which
+// side changed a task since.
+type Replica interface {
+	// ReplicaID identifies the store among the stores it is synced with. It
+	// is created the first time it is asked for.
+	ReplicaID() (string, error)
+	// Tombstones returns the tasks deleted from the store.
+	Tombstones() ([]Tombstone, error)
+	// LastSync returns when the store was last synced with peer, or the
+	// zero time if it never was.
+	LastSync(peer string) (time.Time, error)
+	// SetLastSync records that the store was synced with peer at t.
+	SetLastSync(peer string, t time.Time) error
+}
+
+// Tombstone records the deletion of the task with the given StableUID.
+type Tombstone struct {
+	UID       string    `json:"uid"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// Iterator is implemented by storage backends that can stream a listing
+// instead of loading it into memory.
+type Iterator interface {
+	Iter(filter TimeFilter) iter.Seq2[*todo.Task, error]
+}
+
+// Iterate streams the tasks List(filter) would return, in the same order. It
+// uses the backend's Iterator when it has one and falls back to List
+// otherwise. Iteration stops at the first error, which is yielded with a nil
+// task.
+func Iterate(s Storage, filter TimeFilter) iter.Seq2[*todo.Task, error] {
+	if it, ok := As[Iterator](s); ok {
+		return it.Iter(filter)
+	}
+
+	return func(yield func(*todo.Task, error) bool) {
+		tasks, err := s.List(filter)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, task := range tasks {
+			if !yield(task, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Wrapper is implemented by storage decorators that add behaviour on top of
+// another Storage.
+type Wrapper interface {
+	Unwrap() Storage
+}
+
+// As finds the first storage in the chain of wrappers starting at s that
+// implements T, such as IntegrityChecker or Snapshotter. It is the storage
+// counterpart of errors.As.
+func As[T any](s Storage) (T, bool) {
+	for s != nil {
+		if found, ok := s.(T); ok {
+			return found, true
+		}
+		wrapper, ok := s.(Wrapper)
+		if !ok {
+			break
+		}
+		s = wrapper.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
+
+// Backend names accepted by Open.
+const (
+	BackendSQLite   = "sqlite"
+	BackendMarkdown = "markdown"
+)
+
+// Open opens the storage backend identified by name. For the SQLite backend
+// path is the database file; for the Markdown backend it is the directory
+// holding one file per task. sqliteOpts only apply to the SQLite backend.
+func Open(backend, path string, sqliteOpts ...SQLiteOption) (Storage, error) {
+	switch backend {
+	case "", BackendSQLite:
+		s, err := NewSQLiteStorage(path, sqliteOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case BackendMarkdown:
+		s, err := NewMarkdownStorage(path)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (use %q or %q)", backend, BackendSQLite, BackendMarkdown)
+	}
+}
+
 type TimeFilter int
 
 const (
@@ -33,3 +159,47 @@ func EndOfDay(t time.Time) time.Time {
 	year, month, day := t.Date()
 	return time.Date(year, month, day, 23, 59, 59, 999999999, t.Location())
 }
+
+// matchesFilter reports whether task belongs in a listing for filter. It
+// mirrors the WHERE clause used by SQLiteStorage.List so that every backend
+// shows the same tasks for the same filter: tasks are compared by calendar
+// date against today's date in the local zone.
+func matchesFilter(task *todo.Task, filter TimeFilter, now time.Time) bool {
+	if task.Skipped {
+		return false
+	}
+
+	day, today := calendarDay(task.Date), calendarDay(now)
+
+	switch filter {
+	case FilterPast:
+		return day < today
+	case FilterCurrent:
+		return day == today
+	case FilterFuture:
+		return day > today
+	default:
+		return true
+	}
+}
+
+// sortTasks orders tasks by calendar date, then time of day (whole-day tasks
+// first), then creation time, then ID, matching the ORDER BY clause used by
+// SQLiteStorage.List.
+func sortTasks(tasks []*todo.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		aDate, aClock, _ := SplitSchedule(a.Date)
+		bDate, bClock, _ := SplitSchedule(b.Date)
+		if aDate != bDate {
+			return aDate < bDate
+		}
+		if aClock != bClock {
+			return aClock < bClock
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// crockford is the base32 alphabet of ULIDs, which leaves out I, L, O and U
+// so that IDs can be read out and typed without confusion.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID (https://github.com/ulid/spec) for a task created
+// at t: 48 bits of milliseconds since the epoch followed by 80 random bits,
+// in 26 characters that sort by creation time.
+func newULID(t time.Time) string {
+	var b [16]byte
+	ms := uint64(t.UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (8 * (5 - i)))
+	}
+	if _, err := rand.Read(b[6:]); err != nil {
+		// crypto/rand doesn't fail on supported platforms.
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return encodeULID(b)
+}
+
+// encodeULID writes the 128 bits of b as 26 base32 digits, the first of
+// which only holds the top 3 bits.
+func encodeULID(b [16]byte) string {
+	var out [26]byte
+	for i := range out {
+		var v byte
+		for j := 0; j < 5; j++ {
+			bit := 5*i + j - 2
+			v <<= 1
+			if bit >= 0 && b[bit/8]>>(7-bit%8)&1 == 1 {
+				v |= 1
+			}
+		}
+		out[i] = crockford[v]
+	}
+	return string(out[:])
+}
+
+// assignUID gives a task that is about to be created a ULID, unless it
+// already has a UID, such as one it was imported with.
+func assignUID(task *todo.Task) {
+	if task.UID != "" {
+		return
+	}
+	created := task.CreatedAt
+	if created.Before(time.UnixMilli(0)) {
+		created = time.Now()
+	}
+	task.UID = newULID(created)
+}
+
+// UIDFinder is implemented by storage backends that can look tasks up by a
+// UID prefix without reading every task.
+type UIDFinder interface {
+	// FindByUIDPrefix returns the tasks whose UID starts with prefix,
+	// ignoring letter case.
+	FindByUIDPrefix(prefix string) ([]*todo.Task, error)
+}
+
+// FindByUID returns the task whose StableUID is uid, or else the only task
+// whose StableUID starts with it. Letter case is ignored, as it is for
+// ULIDs. It uses the backend's UIDFinder when it has one and reads every
+// task otherwise.
+func FindByUID(s Storage, uid string) (*todo.Task, error) {
+	if uid == "" {
+		return nil, todo.ErrNotFound
+	}
+
+	var candidates []*todo.Task
+	if finder, ok := As[UIDFinder](s); ok {
+		var err error
+		if candidates, err = finder.FindByUIDPrefix(uid); err != nil {
+			return nil, err
+		}
+	} else {
+		tasks, err := s.All()
+		if err != nil {
+			return nil, err
+		}
+		prefix := strings.ToLower(uid)
+		for _, task := range tasks {
+			if strings.HasPrefix(strings.ToLower(task.StableUID()), prefix) {
+				candidates = append(candidates, task)
+			}
+		}
+	}
+
+	for _, task := range candidates {
+		if strings.EqualFold(task.StableUID(), uid) {
+			return task, nil
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, todo.ErrNotFound
+	case 1:
+		return candidates[0], nil
+	default:
+		return nil, fmt.Errorf("%w: %q is the start of %d task UIDs", todo.ErrAmbiguousUID, uid, len(candidates))
+	}
+}
+
+// FindTask returns the task ref refers to: the short numeric ID shown by
+// list, or the task's UID or the start of it as FindByUID takes it. ULIDs
+// begin with a zero until the year 3084, so a number without leading zeros
+// is taken as an ID first, and as a UID, such as an imported calendar's
+// "12345", only if no task has that ID.
+func FindTask(s Storage, ref string) (*todo.Task, error) {
+	if !isTaskID(ref) {
+		return FindByUID(s, ref)
+	}
+	id, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil {
+		// Too long to be an ID.
+		return FindByUID(s, ref)
+	}
+	task, err := s.GetByID(id)
+	if errors.Is(err, todo.ErrNotFound) {
+		return FindByUID(s, ref)
+	}
+	return task, err
+}
+
+func isTaskID(ref string) bool {
+	if ref == "" || ref[0] == '0' {
+		return false
+	}
+	for _, c := range ref {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewULID(t *testing.T) {
+	// The timestamp of the example in the ULID spec.
+	created := time.UnixMilli(1469918176385)
+	uid := newULID(created)
+	if len(uid) != 26 || !strings.HasPrefix(uid, "01ARYZ6S41") {
+		t.Errorf("expected a ULID starting with 01ARYZ6S41, got %q", uid)
+	}
+	if strings.ContainsAny(uid[10:], "ILOU") {
+		t.Errorf("expected only Crockford base32 digits, got %q", uid)
+	}
+	if newULID(created) == uid {
+		t.Error("expected ULIDs created in the same millisecond to differ")
+	}
+	if later := newULID(created.Add(time.Millisecond)); later[:10] <= uid[:10] {
+		t.Errorf("expected %q to sort after %q", later, uid)
+	}
+}
+
+func TestEncodeULID(t *testing.T) {
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	if got := encodeULID(max); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("got %q for the largest ULID", got)
+	}
+	if got := encodeULID([16]byte{}); got != strings.Repeat("0", 26) {
+		t.Errorf("got %q for the smallest ULID", got)
+	}
+}
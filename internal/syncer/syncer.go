@@ -0,0 +1,385 @@
+// Package syncer merges two task stores, such as the databases on a laptop
+// and a desktop, so that the changes made on either end up on both.
+//
+// Tasks are matched by their StableUID; copies get the UID of the task they
+// were copied from, so they keep matching. A task only one store has is
+// copied to the other, unless the other deleted it (see storage.Tombstone),
+// in which case it is deleted. A task that differs between the stores is
+// taken from the store that changed it since the last sync. When both
+// stores changed it, or one changed it after the other deleted it, the
+// change is a Conflict for the caller to resolve.
+//
+// Completing a recurring task creates its next occurrence with a new UID,
+// so a task completed in both stores before a sync leaves each with its own
+// next occurrence. Such twins, open tasks only one store has each, with the
+// same content, dated on the next occurrence of a task both stores have
+// completed or skipped, are merged: the remote one takes the UID of the
+// local one instead of being copied. Twins edited differently before the
+// sync no longer match and are both kept.
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+var ErrNotReplica = errors.New("storage backend can't be synced")
+
+// Side is one of the two stores being synced.
+type Side int
+
+const (
+	Local Side = iota
+	Remote
+)
+
+func (s Side) String() string {
+	if s == Local {
+		return "local"
+	}
+	return "remote"
+}
+
+// Action is what a Change does to a task.
+type Action int
+
+const (
+	Create Action = iota
+	Update
+	Delete
+)
+
+// Change is a write to one of the stores.
+type Change struct {
+	Action Action
+	// Task is the task to create, the new version of the task to update,
+	// with the ID it has in that store, or the task to delete.
+	Task *todo.Task
+}
+
+// Conflict is a task both stores changed since they were last synced.
+// Local or Remote is nil if that store deleted the task, at DeletedAt.
+type Conflict struct {
+	UID           string
+	Local, Remote *todo.Task
+	DeletedAt     time.Time
+}
+
+// Newer returns the side with the latest change, counting a deletion as a
+// change at the time it was made.
+func (c Conflict) Newer() Side {
+	local, remote := c.DeletedAt, c.DeletedAt
+	if c.Local != nil {
+		local = c.Local.UpdatedAt
+	}
+	if c.Remote != nil {
+		remote = c.Remote.UpdatedAt
+	}
+	if remote.After(local) {
+		return Remote
+	}
+	return Local
+}
+
+// Plan is what a sync would change in each store.
+type Plan struct {
+	// Local and Remote are the changes to make to each store.
+	Local, Remote []Change
+	// Conflicts are the tasks that need to be resolved; see Resolve.
+	Conflicts []Conflict
+	// LastSync is when the stores were last synced, or the zero time if
+	// they never were.
+	LastSync time.Time
+
+	stores   [2]storage.Storage
+	replicas [2]storage.Replica
+	ids      [2]string
+	started  time.Time
+	resolved map[string]bool
+}
+
+// replica is what NewPlan reads from one store.
+type replica struct {
+	tasks      map[string]*todo.Task
+	order      []string
+	tombstones map[string]time.Time
+}
+
+// NewPlan compares the two stores and plans the changes that bring them in
+// line. Both have to implement storage.Replica.
+func NewPlan(local, remote storage.Storage) (*Plan, error) {
+	p := &Plan{
+		stores:   [2]storage.Storage{local, remote},
+		started:  time.Now(),
+		resolved: map[string]bool{},
+	}
+
+	var sides [2]*replica
+	for i, s := range p.stores {
+		r, ok := storage.As[storage.Replica](s)
+		if !ok {
+			return nil, fmt.Errorf("%w: the %s store keeps no sync state", ErrNotReplica, Side(i))
+		}
+		p.replicas[i] = r
+
+		var err error
+		if p.ids[i], err = r.ReplicaID(); err != nil {
+			return nil, err
+		}
+		if sides[i], err = readReplica(s, r); err != nil {
+			return nil, err
+		}
+	}
+
+	// The stores record the sync at the same time, so their last syncs
+	// only differ if recording it failed on one; the earlier one is safe.
+	for i, r := range p.replicas {
+		last, err := r.LastSync(p.ids[1-i])
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 || last.Before(p.LastSync) {
+			p.LastSync = last
+		}
+	}
+
+	l, r := sides[Local], sides[Remote]
+	twins := findTwins(l, r)
+	merged := make(map[string]bool, len(twins))
+	for _, local := range twins {
+		merged[local.StableUID()] = true
+	}
+
+	for _, uid := range l.order {
+		switch remoteTask, ok := r.tasks[uid]; {
+		case ok:
+			p.compare(uid, l.tasks[uid], remoteTask)
+		case !merged[uid]:
+			p.single(Local, uid, l.tasks[uid], r.tombstones)
+		}
+	}
+	for _, uid := range r.order {
+		if _, ok := l.tasks[uid]; ok {
+			continue
+		}
+		if local, ok := twins[uid]; ok {
+			task := *r.tasks[uid]
+			task.UID = local.StableUID()
+			p.Remote = append(p.Remote, Change{Action: Update, Task: &task})
+			continue
+		}
+		p.single(Remote, uid, r.tasks[uid], l.tombstones)
+	}
+	return p, nil
+}
+
+// findTwins finds the next occurrences each store created for a recurring
+// task completed or skipped in both, and returns the local twin of each
+// remote one by the remote one's UID.
+func findTwins(l, r *replica) map[string]*todo.Task {
+	key := func(task *todo.Task) string {
+		return task.Title + "\x00" + string(task.RecurrencePattern) + "\x00" + task.Date.Format("2006-01-02")
+	}
+	finished := func(task *todo.Task) bool { return task.Completed || task.Skipped }
+
+	successors := map[string]bool{}
+	for uid, local := range l.tasks {
+		remote, ok := r.tasks[uid]
+		if !ok || !local.IsRecurring() || !finished(local) || !finished(remote) {
+			continue
+		}
+		for _, task := range []*todo.Task{local, remote} {
+			if next, err := task.GenerateNextInstance(); err == nil && next != nil {
+				successors[key(next)] = true
+			}
+		}
+	}
+	if len(successors) == 0 {
+		return nil
+	}
+
+	// candidates returns the open successors only rep has, by key.
+	candidates := func(rep, other *replica) map[string]*todo.Task {
+		found := map[string]*todo.Task{}
+		for _, uid := range rep.order {
+			task := rep.tasks[uid]
+			_, shared := other.tasks[uid]
+			_, deleted := other.tombstones[uid]
+			if shared || deleted || finished(task) || !successors[key(task)] {
+				continue
+			}
+			if _, ok := found[key(task)]; !ok {
+				found[key(task)] = task
+			}
+		}
+		return found
+	}
+
+	twins := map[string]*todo.Task{}
+	locals := candidates(l, r)
+	for k, remote := range candidates(r, l) {
+		if local, ok := locals[k]; ok && sameContent(local, remote) {
+			twins[remote.StableUID()] = local
+		}
+	}
+	return twins
+}
+
+func readReplica(s storage.Storage, r storage.Replica) (*replica, error) {
+	tasks, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	tombstones, err := r.Tombstones()
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &replica{tasks: map[string]*todo.Task{}, tombstones: map[string]time.Time{}}
+	for _, task := range tasks {
+		uid := task.StableUID()
+		rep.tasks[uid] = task
+		rep.order = append(rep.order, uid)
+	}
+	for _, tombstone := range tombstones {
+		rep.tombstones[tombstone.UID] = tombstone.DeletedAt
+	}
+	return rep, nil
+}
+
+// compare plans the sync of a task both stores have.
+func (p *Plan) compare(uid string, local, remote *todo.Task) {
+	if sameContent(local, remote) {
+		return
+	}
+
+	localChanged, remoteChanged := local.UpdatedAt.After(p.LastSync), remote.UpdatedAt.After(p.LastSync)
+	switch {
+	case localChanged && remoteChanged:
+		p.Conflicts = append(p.Conflicts, Conflict{UID: uid, Local: local, Remote: remote})
+	case localChanged || (!remoteChanged && local.UpdatedAt.After(remote.UpdatedAt)):
+		p.write(Remote, local, remote)
+	default:
+		p.write(Local, remote, local)
+	}
+}
+
+// single plans the sync of a task only the store on side has: it is copied
+// to the other store, or deleted if the other store deleted it, unless it
+// was changed after that.
+func (p *Plan) single(side Side, uid string, task *todo.Task, deleted map[string]time.Time) {
+	other := 1 - side
+	deletedAt, ok := deleted[uid]
+	switch {
+	case !ok:
+		p.write(other, task, nil)
+	case task.UpdatedAt.After(deletedAt):
+		c := Conflict{UID: uid, DeletedAt: deletedAt}
+		if side == Local {
+			c.Local = task
+		} else {
+			c.Remote = task
+		}
+		p.Conflicts = append(p.Conflicts, c)
+	default:
+		p.write(side, nil, task)
+	}
+}
+
+// write plans making target, the task in the store on side, match src, the
+// version from the other store: creating it if target is nil, deleting it
+// if src is nil, and updating it otherwise.
+func (p *Plan) write(side Side, src, target *todo.Task) {
+	changes := &p.Local
+	if side == Remote {
+		changes = &p.Remote
+	}
+
+	switch {
+	case src == nil:
+		*changes = append(*changes, Change{Action: Delete, Task: target})
+	case target == nil:
+		task := *src
+		task.ID = 0
+		task.UID = src.StableUID()
+		*changes = append(*changes, Change{Action: Create, Task: &task})
+	default:
+		task := *src
+		task.ID = target.ID
+		task.UID = target.UID
+		*changes = append(*changes, Change{Action: Update, Task: &task})
+	}
+}
+
+// Resolve settles a conflict by keeping the version of the store on side,
+// which is written to the other store.
+func (p *Plan) Resolve(c Conflict, keep Side) {
+	if keep == Local {
+		p.write(Remote, c.Local, c.Remote)
+	} else {
+		p.write(Local, c.Remote, c.Local)
+	}
+	p.resolved[c.UID] = true
+}
+
+// Unresolved returns the conflicts Resolve hasn't been called for.
+func (p *Plan) Unresolved() []Conflict {
+	var conflicts []Conflict
+	for _, c := range p.Conflicts {
+		if !p.resolved[c.UID] {
+			conflicts = append(conflicts, c)
+		}
+	}
+	return conflicts
+}
+
+// Apply makes the planned changes. Once every conflict is resolved, both
+// stores record the sync, so that the next sync only looks at the changes
+// made after this one; with unresolved conflicts the sync isn't recorded,
+// and the next one finds them again.
+func (p *Plan) Apply() error {
+	for i, changes := range [][]Change{p.Local, p.Remote} {
+		for _, change := range changes {
+			if err := apply(p.stores[i], change); err != nil {
+				return fmt.Errorf("failed to sync %q to the %s store: %w", change.Task.Title, Side(i), err)
+			}
+		}
+	}
+
+	if len(p.Unresolved()) > 0 {
+		return nil
+	}
+	for i, r := range p.replicas {
+		if err := r.SetLastSync(p.ids[1-i], p.started); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func apply(s storage.Storage, change Change) error {
+	switch change.Action {
+	case Create:
+		return s.Create(change.Task)
+	case Update:
+		return s.Update(change.Task)
+	default:
+		return s.Delete(change.Task.ID)
+	}
+}
+
+// sameContent reports whether two versions of a task agree on everything
+// but their ID, UID and timestamps.
+func sameContent(a, b *todo.Task) bool {
+	aDate, aClock, aZone := storage.SplitSchedule(a.Date)
+	bDate, bClock, bZone := storage.SplitSchedule(b.Date)
+	return a.Title == b.Title && a.Details == b.Details &&
+		aDate == bDate && aClock == bClock && aZone == bZone &&
+		a.Completed == b.Completed && a.Skipped == b.Skipped &&
+		a.RecurrencePattern == b.RecurrencePattern &&
+		a.Owner == b.Owner && a.Assignee == b.Assignee
+}
@@ -0,0 +1,287 @@
+package syncer
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+// openStores opens a laptop and a desktop database in a fresh directory.
+func openStores(t *testing.T) (laptop, desktop *storage.SQLiteStorage) {
+	t.Helper()
+
+	dir := t.TempDir()
+	var stores [2]*storage.SQLiteStorage
+	for i, name := range []string{"laptop.db", "desktop.db"} {
+		store, err := storage.NewSQLiteStorage(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", name, err)
+		}
+		t.Cleanup(func() { store.Close() })
+		stores[i] = store
+	}
+	return stores[0], stores[1]
+}
+
+func createTask(t *testing.T, store storage.Storage, title string) *todo.Task {
+	t.Helper()
+
+	task, _ := todo.NewTask(title, "", time.Date(2025, 11, 20, 0, 0, 0, 0, time.Local))
+	if err := store.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	return task
+}
+
+// findTask returns the task titled title, or nil.
+func findTask(t *testing.T, store storage.Storage, title string) *todo.Task {
+	t.Helper()
+
+	tasks, err := store.All()
+	if err != nil {
+		t.Fatalf("failed to list tasks: %v", err)
+	}
+	for _, task := range tasks {
+		if task.Title == title {
+			return task
+		}
+	}
+	return nil
+}
+
+func sync(t *testing.T, local, remote storage.Storage) *Plan {
+	t.Helper()
+
+	plan, err := NewPlan(local, remote)
+	if err != nil {
+		t.Fatalf("failed to plan sync: %v", err)
+	}
+	if err := plan.Apply(); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	return plan
+}
+
+func TestSync_CopiesBothWays(t *testing.T) {
+	laptop, desktop := openStores(t)
+	createTask(t, laptop, "Written on the laptop")
+
+	createTask(t, desktop, "Written on the desktop")
+
+	plan := sync(t, laptop, desktop)
+	if len(plan.Local) != 1 || len(plan.Remote) != 1 || len(plan.Conflicts) != 0 {
+		t.Fatalf("expected one copy each way, got %+v", plan)
+	}
+	for _, store := range []storage.Storage{laptop, desktop} {
+		if findTask(t, store, "Written on the laptop") == nil || findTask(t, store, "Written on the desktop") == nil {
+			t.Fatalf("expected both tasks in both stores")
+		}
+	}
+
+	again := sync(t, desktop, laptop)
+	if len(again.Local)+len(again.Remote)+len(again.Conflicts) != 0 {
+		t.Errorf("expected nothing left to sync, got %+v", again)
+	}
+	if again.LastSync.IsZero() {
+		t.Error("expected the first sync to be recorded")
+	}
+}
+
+func TestSync_PropagatesEditsAndDeletes(t *testing.T) {
+	laptop, desktop := openStores(t)
+	edited := createTask(t, laptop, "Call the bank")
+	deleted := createTask(t, laptop, "Old idea")
+	sync(t, laptop, desktop)
+
+	edited.Complete()
+	if err := laptop.Update(edited); err != nil {
+		t.Fatalf("failed to update task: %v", err)
+	}
+	copied := findTask(t, desktop, "Old idea")
+	if err := desktop.Delete(copied.ID); err != nil {
+		t.Fatalf("failed to delete task: %v", err)
+	}
+
+	plan := sync(t, laptop, desktop)
+	if len(plan.Conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %+v", plan.Conflicts)
+	}
+	if task := findTask(t, desktop, "Call the bank"); task == nil || !task.Completed {
+		t.Errorf("expected the completion to reach the desktop, got %+v", task)
+	}
+	if findTask(t, laptop, "Old idea") != nil {
+		t.Error("expected the deletion to reach the laptop")
+	}
+	if _, err := laptop.GetByID(deleted.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Errorf("expected the deleted task to be gone, got %v", err)
+	}
+
+	// The deletion isn't undone by syncing in the other direction.
+	if again := sync(t, desktop, laptop); len(again.Local)+len(again.Remote) != 0 {
+		t.Errorf("expected nothing left to sync, got %+v", again)
+	}
+}
+
+func TestSync_Conflicts(t *testing.T) {
+	laptop, desktop := openStores(t)
+	createTask(t, laptop, "Weekly report")
+	createTask(t, laptop, "Dentist")
+	sync(t, laptop, desktop)
+	synced, _ := NewPlan(laptop, desktop)
+
+	for _, edit := range []struct {
+		store   storage.Storage
+		details string
+	}{{laptop, "From the laptop"}, {desktop, "From the desktop"}} {
+		task := findTask(t, edit.store, "Weekly report")
+		if err := task.Update(task.Title, edit.details); err != nil {
+			t.Fatal(err)
+		}
+		if err := edit.store.Update(task); err != nil {
+			t.Fatalf("failed to update task: %v", err)
+		}
+	}
+	dentist := findTask(t, laptop, "Dentist")
+	if err := laptop.Delete(dentist.ID); err != nil {
+		t.Fatalf("failed to delete task: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	dentist = findTask(t, desktop, "Dentist")
+	dentist.Complete()
+	if err := desktop.Update(dentist); err != nil {
+		t.Fatalf("failed to update task: %v", err)
+	}
+
+	plan, err := NewPlan(laptop, desktop)
+	if err != nil {
+		t.Fatalf("failed to plan sync: %v", err)
+	}
+	if len(plan.Conflicts) != 2 || len(plan.Local)+len(plan.Remote) != 0 {
+		t.Fatalf("expected 2 conflicts and no changes, got %+v", plan)
+	}
+
+	// Unresolved conflicts leave the stores as they are and the sync
+	// unrecorded, so the next sync finds them again.
+	if err := plan.Apply(); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	plan, _ = NewPlan(laptop, desktop)
+	if len(plan.Unresolved()) != 2 || !plan.LastSync.Equal(synced.LastSync) {
+		t.Fatalf("expected the conflicts to remain, got %+v", plan)
+	}
+
+	for _, c := range plan.Conflicts {
+		switch {
+		case c.Local == nil:
+			if c.Newer() != Remote {
+				t.Errorf("expected the completion after the deletion to be newer")
+			}
+			plan.Resolve(c, Local)
+		default:
+			plan.Resolve(c, Remote)
+		}
+	}
+	if err := plan.Apply(); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	if task := findTask(t, laptop, "Weekly report"); task.Details != "From the desktop" {
+		t.Errorf("expected the desktop's version, got %q", task.Details)
+	}
+	if findTask(t, desktop, "Dentist") != nil {
+		t.Error("expected the deletion to be kept")
+	}
+	if again := sync(t, laptop, desktop); len(again.Conflicts)+len(again.Local)+len(again.Remote) != 0 {
+		t.Errorf("expected nothing left to sync, got %+v", again)
+	}
+}
+
+func TestSync_FirstSyncOfCopies(t *testing.T) {
+	laptop, desktop := openStores(t)
+	task := createTask(t, laptop, "Shared")
+	copied := *task
+	copied.ID = 0
+	copied.UID = task.StableUID()
+	if err := desktop.Create(&copied); err != nil {
+		t.Fatal(err)
+	}
+
+	// Never synced, so a task that differs can't be told apart from
+	// one changed on both sides.
+	copied.Complete()
+	if err := desktop.Update(&copied); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := NewPlan(laptop, desktop)
+	if err != nil {
+		t.Fatalf("failed to plan sync: %v", err)
+	}
+	if !plan.LastSync.IsZero() || len(plan.Conflicts) != 1 || plan.Conflicts[0].Newer() != Remote {
+		t.Errorf("expected one conflict with the desktop newer, got %+v", plan)
+	}
+}
+
+func TestSync_MergesTwinOccurrences(t *testing.T) {
+	laptop, desktop := openStores(t)
+	plants, _ := todo.NewRecurringTask("Water plants", "", recurrence.Pattern("weekly:monday"))
+	plants.Date = time.Date(2025, 11, 24, 0, 0, 0, 0, time.Local)
+	if err := laptop.Create(plants); err != nil {
+		t.Fatal(err)
+	}
+	sync(t, laptop, desktop)
+
+	// Completed on both machines before they are synced again, each
+	// creating its own next occurrence.
+	for _, store := range []storage.Storage{laptop, desktop} {
+		task := findTask(t, store, "Water plants")
+		task.Complete()
+		next, _ := task.GenerateNextInstance()
+		if err := store.Update(task); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Create(next); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan := sync(t, laptop, desktop)
+	if len(plan.Local) != 0 || len(plan.Remote) != 1 || plan.Remote[0].Action != Update {
+		t.Fatalf("expected the desktop's occurrence to be merged into the laptop's, got %+v", plan)
+	}
+	var uids [2]map[string]bool
+	for i, store := range []storage.Storage{laptop, desktop} {
+		tasks, _ := store.All()
+		uids[i] = map[string]bool{}
+		for _, task := range tasks {
+			uids[i][task.StableUID()] = true
+		}
+		if len(tasks) != 2 {
+			t.Errorf("expected the completed task and one next occurrence, got %+v", tasks)
+		}
+	}
+	for uid := range uids[0] {
+		if !uids[1][uid] {
+			t.Errorf("expected both stores to have task %s", uid)
+		}
+	}
+	if again := sync(t, desktop, laptop); len(again.Local)+len(again.Remote)+len(again.Conflicts) != 0 {
+		t.Errorf("expected nothing left to sync, got %+v", again)
+	}
+}
+
+func TestNewPlan_NeedsReplicas(t *testing.T) {
+	laptop, _ := openStores(t)
+	if _, err := NewPlan(laptop, plainStorage{storage.NewMemoryStorage()}); !errors.Is(err, ErrNotReplica) {
+		t.Errorf("expected ErrNotReplica, got %v", err)
+	}
+}
+
+// plainStorage hides everything but the Storage methods of a backend.
+type plainStorage struct {
+	storage.Storage
+}
@@ -2,6 +2,7 @@ package todo
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/johnmirolha/facienda/internal/recurrence"
@@ -10,6 +11,11 @@ import (
 var (
 	ErrEmptyTitle = errors.New("task title cannot be empty")
 	ErrNotFound   = errors.New("task not found")
+	ErrConflict   = errors.New("task was modified by another process; reload and try again")
+
+	ErrDuplicateUID = errors.New("another task already has this UID")
+	ErrAmbiguousUID = errors.New("more than one task matches")
+	ErrForbidden    = errors.New("not allowed to change this task")
 )
 
 type Task struct {
@@ -22,6 +28,17 @@ type Task struct {
 	RecurrencePattern recurrence.Pattern
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
+	// UID identifies the task across databases, files and other
+	// applications. Storage assigns a ULID to new tasks that don't have
+	// one; imported tasks keep the identifier they came with, such as the
+	// UID of a calendar entry. No two tasks in a store share a UID.
+	UID string
+	// Owner is the user who created the task and Assignee the user who is
+	// to do it, both names of users of the server mode. They are empty for
+	// tasks nobody owns or is assigned, such as those added on the command
+	// line.
+	Owner    string
+	Assignee string
 }
 
 func NewTask(title, details string, date time.Time) (*Task, error) {
@@ -105,6 +122,11 @@ func (t *Task) GenerateNextInstance() (*Task, error) {
 	if err != nil {
 		return nil, err
 	}
+	if t.HasTime() {
+		year, month, day := nextDate.Date()
+		hour, minute, _ := t.Date.Clock()
+		nextDate = time.Date(year, month, day, hour, minute, 0, 0, t.Date.Location())
+	}
 
 	now := time.Now()
 	return &Task{
@@ -115,9 +137,28 @@ func (t *Task) GenerateNextInstance() (*Task, error) {
 		RecurrencePattern: t.RecurrencePattern,
 		CreatedAt:         now,
 		UpdatedAt:         now,
+		Owner:             t.Owner,
+		Assignee:          t.Assignee,
 	}, nil
 }
 
+// StableUID returns the task's UID or, for a task stored before every task
+// had one, an identifier derived from its ID and creation time, which stays
+// the same across exports of the same database
+func (t *Task) StableUID() string {
+	if t.UID != "" {
+		return t.UID
+	}
+	return fmt.Sprintf("%d-%d@facienda", t.ID, t.CreatedAt.Unix())
+}
+
+// HasTime returns true if the task is scheduled at a time of day rather
+// than for the whole day
+func (t *Task) HasTime() bool {
+	hour, minute, second := t.Date.Clock()
+	return hour != 0 || minute != 0 || second != 0 || t.Date.Nanosecond() != 0
+}
+
 // IsRecurring returns true if the task has a recurrence pattern
 func (t *Task) IsRecurring() bool {
 	return t.RecurrencePattern.IsRecurring()
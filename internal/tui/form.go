@@ -0,0 +1,100 @@
+package tui
+
+// field is a line of text being edited in a form.
+type field struct {
+	label  string
+	value  []rune
+	cursor int
+}
+
+// form is a set of fields shown at the bottom of the screen, for adding or
+// editing a task. Enter submits it and Esc cancels it.
+type form struct {
+	title  string
+	fields []field
+	focus  int
+	// submit saves the values of the fields, in order. The form stays open
+	// if it returns an error.
+	submit func(values []string) error
+}
+
+type formResult int
+
+const (
+	formEditing formResult = iota
+	formSubmitted
+	formCancelled
+)
+
+func newForm(title string, submit func(values []string) error, labels ...string) *form {
+	f := &form{title: title, submit: submit}
+	for _, label := range labels {
+		f.fields = append(f.fields, field{label: label})
+	}
+	return f
+}
+
+// set fills in the field with label.
+func (f *form) set(label, value string) {
+	for i := range f.fields {
+		if f.fields[i].label == label {
+			f.fields[i].value = []rune(value)
+			f.fields[i].cursor = len(f.fields[i].value)
+		}
+	}
+}
+
+func (f *form) values() []string {
+	values := make([]string, len(f.fields))
+	for i, field := range f.fields {
+		values[i] = string(field.value)
+	}
+	return values
+}
+
+// handleKey edits the form and says whether it was submitted or cancelled.
+func (f *form) handleKey(key string) formResult {
+	current := &f.fields[f.focus]
+	switch key {
+	case "enter":
+		return formSubmitted
+	case "esc", "ctrl+c":
+		return formCancelled
+	case "tab", "down":
+		f.focus = (f.focus + 1) % len(f.fields)
+	case "backtab", "up":
+		f.focus = (f.focus + len(f.fields) - 1) % len(f.fields)
+	case "left":
+		current.cursor = max(current.cursor-1, 0)
+	case "right":
+		current.cursor = min(current.cursor+1, len(current.value))
+	case "home":
+		current.cursor = 0
+	case "end":
+		current.cursor = len(current.value)
+	case "ctrl+u":
+		current.value, current.cursor = nil, 0
+	case "backspace":
+		if current.cursor > 0 {
+			current.value = append(current.value[:current.cursor-1], current.value[current.cursor:]...)
+			current.cursor--
+		}
+	case "delete":
+		if current.cursor < len(current.value) {
+			current.value = append(current.value[:current.cursor], current.value[current.cursor+1:]...)
+		}
+	default:
+		if key == "space" {
+			key = " "
+		}
+		runes := []rune(key)
+		if len(runes) != 1 {
+			return formEditing
+		}
+		value := append([]rune{}, current.value[:current.cursor]...)
+		value = append(value, runes[0])
+		current.value = append(value, current.value[current.cursor:]...)
+		current.cursor++
+	}
+	return formEditing
+}
@@ -0,0 +1,83 @@
+package tui
+
+import "unicode/utf8"
+
+// parseKeys splits terminal input into key names: printable characters as
+// themselves, and named keys such as "up", "enter", "tab", "backtab",
+// "backspace", "esc", "space" and "ctrl+c". Escape sequences the interface
+// has no use for are dropped.
+func parseKeys(data []byte) []string {
+	var keys []string
+	for len(data) > 0 {
+		b := data[0]
+		switch {
+		case b == 0x1b:
+			key, n := parseEscape(data)
+			if key != "" {
+				keys = append(keys, key)
+			}
+			data = data[n:]
+			continue
+		case b == '\r' || b == '\n':
+			keys = append(keys, "enter")
+		case b == '\t':
+			keys = append(keys, "tab")
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, "backspace")
+		case b == ' ':
+			keys = append(keys, "space")
+		case b == 0x01:
+			keys = append(keys, "home")
+		case b == 0x03:
+			keys = append(keys, "ctrl+c")
+		case b == 0x05:
+			keys = append(keys, "end")
+		case b == 0x15:
+			keys = append(keys, "ctrl+u")
+		case b < 0x20:
+		default:
+			r, n := utf8.DecodeRune(data)
+			if r != utf8.RuneError {
+				keys = append(keys, string(r))
+			}
+			data = data[n:]
+			continue
+		}
+		data = data[1:]
+	}
+	return keys
+}
+
+// csiKeys name the final bytes of the escape sequences of arrow and
+// navigation keys, and tildeKeys the parameters of those ending in '~'.
+var (
+	csiKeys = map[byte]string{
+		'A': "up", 'B': "down", 'C': "right", 'D': "left",
+		'H': "home", 'F': "end", 'Z': "backtab",
+	}
+	tildeKeys = map[string]string{
+		"1": "home", "7": "home", "4": "end", "8": "end",
+		"3": "delete", "5": "pgup", "6": "pgdown",
+	}
+)
+
+// parseEscape parses the escape sequence at the start of data, returning
+// its key name and length. A lone escape is the escape key.
+func parseEscape(data []byte) (string, int) {
+	if len(data) == 1 || (data[1] != '[' && data[1] != 'O') {
+		return "esc", 1
+	}
+
+	// Parameters are digits and ';', up to a final byte from '@' to '~'.
+	for i := 2; i < len(data); i++ {
+		b := data[i]
+		if b < 0x40 || b > 0x7e {
+			continue
+		}
+		if b == '~' {
+			return tildeKeys[string(data[2:i])], i + 1
+		}
+		return csiKeys[b], i + 1
+	}
+	return "", len(data)
+}
@@ -0,0 +1,532 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+type pane int
+
+const (
+	todayPane pane = iota
+	pastPane
+	futurePane
+	paneCount
+)
+
+var (
+	paneTitles  = [paneCount]string{"Today", "Past", "Future"}
+	paneFilters = [paneCount]storage.TimeFilter{storage.FilterCurrent, storage.FilterPast, storage.FilterFuture}
+)
+
+// minPaneWidth is the narrowest a pane gets when they are side by side; on
+// narrower terminals only the focused pane is shown.
+const minPaneWidth = 28
+
+const (
+	styleBold    = "\x1b[1m"
+	styleDim     = "\x1b[2m"
+	styleReverse = "\x1b[7m"
+	styleRed     = "\x1b[31m"
+	styleGreen   = "\x1b[32m"
+	styleReset   = "\x1b[0m"
+)
+
+const helpLine = "↑↓ move  ←→ pane  space complete  s skip  e edit  a add  r reload  q quit"
+
+// model is the state of the interface, kept apart from the terminal so that
+// it can be driven by key names and rendered to lines of text.
+type model struct {
+	config Config
+
+	tasks  [paneCount][]*todo.Task
+	cursor [paneCount]int
+	// offset is the first line of each pane on screen, when it doesn't fit.
+	offset [paneCount]int
+	focus  pane
+
+	form    *form
+	message string
+	failed  bool
+
+	// fingerprint identifies what was loaded last, so that reloading only
+	// redraws the screen when something changed.
+	fingerprint string
+}
+
+func newModel(config Config) *model {
+	return &model{config: config}
+}
+
+func (m *model) selected() *todo.Task {
+	tasks := m.tasks[m.focus]
+	if len(tasks) == 0 {
+		return nil
+	}
+	return tasks[m.cursor[m.focus]]
+}
+
+// reload reads the tasks of every pane again, keeping the cursor on the
+// task it was on. It reports whether anything changed.
+func (m *model) reload() (bool, error) {
+	var loaded [paneCount][]*todo.Task
+	var fingerprint strings.Builder
+	for p := range paneCount {
+		tasks, err := m.config.Store.List(paneFilters[p])
+		if err != nil {
+			return false, err
+		}
+		// The past is shown most recent first, closest to today at the top.
+		if p == pastPane {
+			sort.SliceStable(tasks, func(i, j int) bool {
+				return dateKey(tasks[i]) > dateKey(tasks[j])
+			})
+		}
+		loaded[p] = tasks
+
+		fingerprint.WriteString("|")
+		for _, task := range tasks {
+			fmt.Fprintf(&fingerprint, "%d:%d:%t;", task.ID, task.UpdatedAt.UnixNano(), task.Completed)
+		}
+	}
+	if fingerprint.String() == m.fingerprint {
+		return false, nil
+	}
+	m.fingerprint = fingerprint.String()
+
+	for p := range paneCount {
+		var selectedID int64
+		if tasks := m.tasks[p]; len(tasks) > 0 {
+			selectedID = tasks[m.cursor[p]].ID
+		}
+		m.tasks[p] = loaded[p]
+		m.cursor[p] = min(m.cursor[p], max(len(loaded[p])-1, 0))
+		for i, task := range loaded[p] {
+			if task.ID == selectedID {
+				m.cursor[p] = i
+			}
+		}
+	}
+	return true, nil
+}
+
+// reveal focuses the pane showing task id and moves the cursor to it.
+func (m *model) reveal(id int64) {
+	for p := range paneCount {
+		for i, task := range m.tasks[p] {
+			if task.ID == id {
+				m.focus, m.cursor[p] = p, i
+				return
+			}
+		}
+	}
+}
+
+func (m *model) setMessage(format string, args ...any) {
+	m.message, m.failed = fmt.Sprintf(format, args...), false
+}
+
+func (m *model) setError(err error) {
+	m.message, m.failed = err.Error(), true
+	if errors.Is(err, todo.ErrConflict) {
+		m.message += "; reloaded, try again"
+	}
+}
+
+// handleKey acts on a key and reports whether to quit.
+func (m *model) handleKey(key string) bool {
+	if m.form != nil {
+		switch m.form.handleKey(key) {
+		case formSubmitted:
+			if err := m.form.submit(m.form.values()); err != nil {
+				m.setError(err)
+				return false
+			}
+			m.form = nil
+		case formCancelled:
+			m.form = nil
+			m.message = ""
+		}
+		return false
+	}
+
+	tasks := m.tasks[m.focus]
+	cursor := &m.cursor[m.focus]
+	switch key {
+	case "q", "ctrl+c":
+		return true
+	case "tab", "right", "l":
+		m.focus = (m.focus + 1) % paneCount
+	case "backtab", "left", "h":
+		m.focus = (m.focus + paneCount - 1) % paneCount
+	case "1", "2", "3":
+		m.focus = pane(key[0] - '1')
+	case "down", "j":
+		*cursor = min(*cursor+1, max(len(tasks)-1, 0))
+	case "up", "k":
+		*cursor = max(*cursor-1, 0)
+	case "pgdown":
+		*cursor = min(*cursor+10, max(len(tasks)-1, 0))
+	case "pgup":
+		*cursor = max(*cursor-10, 0)
+	case "home", "g":
+		*cursor = 0
+	case "end", "G":
+		*cursor = max(len(tasks)-1, 0)
+	case "space", "c", "x":
+		m.act(m.toggle)
+	case "s":
+		m.act(m.skip)
+	case "e":
+		if task := m.selected(); task != nil {
+			m.edit(task)
+		}
+	case "a":
+		m.add()
+	case "r":
+		m.fingerprint = ""
+		m.message = ""
+		if _, err := m.reload(); err != nil {
+			m.setError(err)
+		}
+	}
+	return false
+}
+
+// act runs an action on the selected task, then reloads, since the action
+// may have created the task's next occurrence or moved it between panes.
+func (m *model) act(action func(task *todo.Task) error) {
+	task := m.selected()
+	if task == nil {
+		return
+	}
+	if err := action(task); err != nil {
+		m.setError(err)
+	}
+	m.fingerprint = ""
+	if _, err := m.reload(); err != nil {
+		m.setError(err)
+	}
+}
+
+func (m *model) toggle(task *todo.Task) error {
+	if task.Completed {
+		task.Incomplete()
+		if err := m.config.Store.Update(task); err != nil {
+			return err
+		}
+		m.setMessage("Task %d marked as incomplete", task.ID)
+		return nil
+	}
+
+	next, err := m.config.Complete(task)
+	if err != nil {
+		return err
+	}
+	m.setMessage("✓ Task %d completed%s", task.ID, describeNext(next))
+	return nil
+}
+
+func (m *model) skip(task *todo.Task) error {
+	next, err := m.config.Skip(task)
+	if err != nil {
+		return err
+	}
+	m.setMessage("⊘ Task %d skipped%s", task.ID, describeNext(next))
+	return nil
+}
+
+func describeNext(next *todo.Task) string {
+	if next == nil {
+		return ""
+	}
+	return ", next occurrence on " + next.Date.Format("Mon, Jan 2, 2006")
+}
+
+func (m *model) add() {
+	m.form = newForm("Add a task", func(values []string) error {
+		task, err := m.config.Add(values[0], values[1], values[2], values[3])
+		if err != nil {
+			return err
+		}
+		m.fingerprint = ""
+		if _, err := m.reload(); err != nil {
+			return err
+		}
+		m.reveal(task.ID)
+		m.setMessage("✓ Task added (ID: %d)%s", task.ID, describeRecurrence(task))
+		return nil
+	}, "Title", "Date", "Time", "Repeats")
+	m.form.set("Date", time.Now().Format("2006-01-02"))
+	m.message = ""
+}
+
+func describeRecurrence(task *todo.Task) string {
+	if !task.IsRecurring() {
+		return ""
+	}
+	return fmt.Sprintf(", %s, next on %s", strings.ToLower(task.RecurrencePattern.String()), task.Date.Format("Mon, Jan 2, 2006"))
+}
+
+func (m *model) edit(task *todo.Task) {
+	m.form = newForm(fmt.Sprintf("Edit task %d", task.ID), func(values []string) error {
+		if err := task.Update(values[0], values[1]); err != nil {
+			return err
+		}
+		if err := m.config.Store.Update(task); err != nil {
+			m.fingerprint = ""
+			m.reload()
+			return err
+		}
+		m.fingerprint = ""
+		if _, err := m.reload(); err != nil {
+			return err
+		}
+		m.setMessage("✓ Task %d updated", task.ID)
+		return nil
+	}, "Title", "Details")
+	m.form.set("Title", task.Title)
+	m.form.set("Details", task.Details)
+	m.message = ""
+}
+
+// cell is a piece of a screen line in one style.
+type cell struct {
+	text  string
+	style string
+}
+
+// render draws the screen as lines of exactly width columns.
+func (m *model) render(width, height int) []string {
+	if width < 10 || height < 6 {
+		lines := make([]string, height)
+		for i := range lines {
+			lines[i] = strings.Repeat(" ", max(width, 0))
+		}
+		lines[0] = pad("The terminal is too small", width)
+		return lines
+	}
+
+	var footer [][]cell
+	if m.form != nil {
+		footer = append(footer, []cell{{" " + m.form.title, styleBold}})
+		for i, field := range m.form.fields {
+			label := fmt.Sprintf("  %-8s ", field.label+":")
+			value := string(field.value)
+			if i == m.form.focus {
+				// The cursor is drawn as a reversed character.
+				before, at, after := string(field.value[:field.cursor]), " ", ""
+				if field.cursor < len(field.value) {
+					at, after = string(field.value[field.cursor]), string(field.value[field.cursor+1:])
+				}
+				footer = append(footer, []cell{{label, styleBold}, {before, ""}, {at, styleReverse}, {after, ""}})
+				continue
+			}
+			footer = append(footer, []cell{{label, styleDim}, {value, ""}})
+		}
+	}
+	if m.message != "" {
+		style := styleGreen
+		if m.failed {
+			style = styleRed
+		}
+		footer = append(footer, []cell{{" " + m.message, style}})
+	}
+	help := helpLine
+	if m.form != nil {
+		help = "enter save  tab next field  esc cancel"
+	}
+	footer = append(footer, []cell{{" " + help, styleDim}})
+
+	lines := []string{
+		renderCells([]cell{{" facienda", styleBold}, {"  " + time.Now().Format("Monday, January 2, 2006"), styleDim}}, width),
+	}
+
+	bodyHeight := max(height-len(footer)-2, 1)
+	panes := []pane{todayPane, pastPane, futurePane}
+	if width < int(paneCount)*minPaneWidth+int(paneCount)-1 {
+		panes = []pane{m.focus}
+	}
+	paneWidth := (width - (len(panes) - 1)) / len(panes)
+
+	var header []cell
+	columns := make([][][]cell, len(panes))
+	for i, p := range panes {
+		w := paneWidth
+		if i == len(panes)-1 {
+			w = width - (paneWidth+1)*(len(panes)-1)
+		}
+		if i > 0 {
+			header = append(header, cell{"│", styleDim})
+		}
+		header = append(header, m.paneHeader(p, w, len(panes) == 1))
+		columns[i] = m.paneLines(p, w, bodyHeight)
+	}
+	lines = append(lines, renderCells(header, width))
+
+	for row := range bodyHeight {
+		var cells []cell
+		for i, column := range columns {
+			if i > 0 {
+				cells = append(cells, cell{"│", styleDim})
+			}
+			cells = append(cells, column[row]...)
+		}
+		lines = append(lines, renderCells(cells, width))
+	}
+	for _, cells := range footer {
+		lines = append(lines, renderCells(cells, width))
+	}
+	return lines[:height]
+}
+
+// paneHeader is the title line of a pane. With only one pane on screen, it
+// names the others too.
+func (m *model) paneHeader(p pane, width int, alone bool) cell {
+	title := fmt.Sprintf(" %s (%d)", paneTitles[p], len(m.tasks[p]))
+	if alone {
+		var others []string
+		for other := range paneCount {
+			if other != p {
+				others = append(others, strconv.Itoa(int(other)+1)+" "+paneTitles[other])
+			}
+		}
+		title += "   " + strings.Join(others, "  ")
+	}
+	style := styleBold
+	if p == m.focus {
+		style = styleBold + styleReverse
+	}
+	return cell{pad(title, width), style}
+}
+
+// paneLines lays out the tasks of a pane as height lines of width columns,
+// scrolled so that the cursor is on screen. Past and future tasks are
+// grouped under their dates.
+func (m *model) paneLines(p pane, width, height int) [][]cell {
+	var lines [][]cell
+	selectedLine := 0
+	day := ""
+	for i, task := range m.tasks[p] {
+		if p != todayPane && dateKey(task) != day {
+			day = dateKey(task)
+			lines = append(lines, []cell{{pad(" "+formatDay(task.Date), width), styleDim + styleBold}})
+		}
+
+		mark, style := "[ ]", ""
+		if task.Completed {
+			mark, style = "[✓]", styleDim
+		}
+		text := fmt.Sprintf(" %s %s", mark, taskTitle(task))
+		if i == m.cursor[p] {
+			selectedLine = len(lines)
+			if p == m.focus {
+				style += styleReverse
+			}
+		}
+		lines = append(lines, []cell{{pad(truncate(text, width), width), style}})
+	}
+	if len(lines) == 0 {
+		lines = append(lines, []cell{{pad(" Nothing here", width), styleDim}})
+	}
+
+	offset := &m.offset[p]
+	if selectedLine < *offset {
+		*offset = selectedLine
+	}
+	if selectedLine >= *offset+height {
+		*offset = selectedLine - height + 1
+	}
+	*offset = max(min(*offset, len(lines)-height), 0)
+
+	visible := make([][]cell, height)
+	for row := range visible {
+		if *offset+row < len(lines) {
+			visible[row] = lines[*offset+row]
+		} else {
+			visible[row] = []cell{{strings.Repeat(" ", width), ""}}
+		}
+	}
+	return visible
+}
+
+// taskTitle is the task as the list command shows it: its time of day, if
+// any, the title, a marker for recurring tasks and who it is assigned to.
+func taskTitle(task *todo.Task) string {
+	title := task.Title
+	if task.HasTime() {
+		title = task.Date.Format("15:04") + " " + title
+	}
+	if task.IsRecurring() {
+		title += " ↻"
+	}
+	if task.Assignee != "" {
+		title += " → " + task.Assignee
+	}
+	return title
+}
+
+func dateKey(task *todo.Task) string {
+	return task.Date.Format("2006-01-02")
+}
+
+func formatDay(date time.Time) string {
+	if date.Year() == time.Now().Year() {
+		return date.Format("Mon, Jan 2")
+	}
+	return date.Format("Mon, Jan 2, 2006")
+}
+
+func renderCells(cells []cell, width int) string {
+	var b strings.Builder
+	used := 0
+	for _, c := range cells {
+		text := truncate(printable(c.text), width-used)
+		used += utf8.RuneCountInString(text)
+		if c.style != "" {
+			b.WriteString(c.style + text + styleReset)
+		} else {
+			b.WriteString(text)
+		}
+	}
+	b.WriteString(strings.Repeat(" ", max(width-used, 0)))
+	return b.String()
+}
+
+// printable replaces control characters, such as the newlines of an error
+// message or an escape sequence in a title, with spaces.
+func printable(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text)
+}
+
+// truncate shortens text to width columns, marking the cut with an
+// ellipsis. Every character is taken to be one column wide.
+func truncate(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:width-1]) + "…"
+}
+
+func pad(text string, width int) string {
+	text = truncate(text, width)
+	return text + strings.Repeat(" ", width-utf8.RuneCountInString(text))
+}
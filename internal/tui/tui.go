@@ -0,0 +1,133 @@
+// Package tui is a full-screen, keyboard-driven interface to the tasks, for
+// working through them without reading IDs off the output of list.
+//
+// Today's, past and future tasks are shown in three panes, side by side on
+// wide terminals and one at a time on narrow ones. The cursor picks a task
+// to complete, skip or edit, and tasks are added in a form at the bottom of
+// the screen. Completing, skipping and adding go through the same code as
+// the commands of the same name (see Config), so recurring tasks get their
+// next occurrence exactly as they would on the command line. The screen
+// follows changes made elsewhere, such as by another facienda process or a
+// sync, by reloading the tasks every second.
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+	"golang.org/x/term"
+)
+
+// reloadInterval is how often the tasks are read again to pick up changes
+// made elsewhere.
+const reloadInterval = time.Second
+
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l" // alternate screen, hidden cursor
+	exitScreen  = "\x1b[?25h\x1b[?1049l"
+	homeCursor  = "\x1b[H"
+)
+
+// Config is what the interface works on.
+type Config struct {
+	Store storage.Storage
+	// Add creates a task from the fields of the add form, which take the
+	// values of the add command's flags: a date and time of day, or a
+	// recurrence phrase.
+	Add func(title, date, at, recur string) (*todo.Task, error)
+	// Complete and Skip complete or skip a task as the commands do,
+	// returning the next occurrence they created for a recurring task.
+	Complete func(task *todo.Task) (*todo.Task, error)
+	Skip     func(task *todo.Task) (*todo.Task, error)
+}
+
+// Run shows the interface on the terminal of in and out until the user
+// quits.
+func Run(config Config, in, out *os.File) error {
+	inFd, outFd := int(in.Fd()), int(out.Fd())
+	if !term.IsTerminal(inFd) || !term.IsTerminal(outFd) {
+		return errors.New("the interactive interface needs a terminal")
+	}
+
+	m := newModel(config)
+	if _, err := m.reload(); err != nil {
+		return err
+	}
+
+	state, err := term.MakeRaw(inFd)
+	if err != nil {
+		return fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	defer term.Restore(inFd, state)
+	screen := bufio.NewWriter(out)
+	screen.WriteString(enterScreen)
+	defer func() {
+		screen.WriteString(exitScreen)
+		screen.Flush()
+	}()
+
+	keys := make(chan []string)
+	readErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			keys <- parseKeys(buf[:n])
+		}
+	}()
+
+	width, height := 0, 0
+	draw := func() error {
+		width, height, err = term.GetSize(outFd)
+		if err != nil {
+			return err
+		}
+		// Leaving the last column empty keeps the terminal from scrolling
+		// when the last line is written.
+		lines := m.render(width-1, height)
+		screen.WriteString(homeCursor + strings.Join(lines, "\r\n"))
+		return screen.Flush()
+	}
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	dirty := true
+	for {
+		if dirty {
+			if err := draw(); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case pressed := <-keys:
+			for _, key := range pressed {
+				if m.handleKey(key) {
+					return nil
+				}
+			}
+			dirty = true
+		case err := <-readErr:
+			return err
+		case <-ticker.C:
+			// Redraw only if the tasks or the size of the terminal changed.
+			changed, err := m.reload()
+			if err != nil {
+				m.setError(err)
+				changed = true
+			}
+			w, h, _ := term.GetSize(outFd)
+			dirty = changed || w != width || h != height
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package tui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+	"github.com/johnmirolha/facienda/internal/storage"
+	"github.com/johnmirolha/facienda/internal/todo"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"jk", []string{"j", "k"}},
+		{"\x1b[A\x1b[B\x1b[C\x1b[D", []string{"up", "down", "right", "left"}},
+		{"\x1bOA", []string{"up"}},
+		{"\x1b[5~\x1b[6~\x1b[3~", []string{"pgup", "pgdown", "delete"}},
+		{"\x1b[1;5C", []string{"right"}},
+		{"\x1b[Z\t", []string{"backtab", "tab"}},
+		{"\x1b", []string{"esc"}},
+		{"\r \x7f\x03", []string{"enter", "space", "backspace", "ctrl+c"}},
+		{"é✓", []string{"é", "✓"}},
+		{"\x1b[200~", nil},
+	}
+	for _, tt := range tests {
+		if got := parseKeys([]byte(tt.input)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseKeys(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestForm(t *testing.T) {
+	f := newForm("Add", nil, "Title", "Repeats")
+	for _, key := range []string{"P", "a", "y", "space", "r", "n", "t", "left", "e", "backspace", "backspace", "e", "tab", "3", "r", "d"} {
+		if result := f.handleKey(key); result != formEditing {
+			t.Fatalf("key %q ended the form", key)
+		}
+	}
+	if got := f.values(); !reflect.DeepEqual(got, []string{"Pay ret", "3rd"}) {
+		t.Errorf("unexpected values %q", got)
+	}
+	if f.handleKey("enter") != formSubmitted || f.handleKey("esc") != formCancelled {
+		t.Error("expected enter to submit and esc to cancel")
+	}
+}
+
+// newTestModel returns a model on a memory store, with actions that work
+// as the commands do.
+func newTestModel(t *testing.T) (*model, *storage.MemoryStorage) {
+	t.Helper()
+
+	store := storage.NewMemoryStorage()
+	finish := func(task *todo.Task, change func()) (*todo.Task, error) {
+		change()
+		if err := store.Update(task); err != nil {
+			return nil, err
+		}
+		next, err := task.GenerateNextInstance()
+		if next == nil || err != nil {
+			return nil, err
+		}
+		return next, store.Create(next)
+	}
+	m := newModel(Config{
+		Store: store,
+		Add: func(title, date, at, recur string) (*todo.Task, error) {
+			var task *todo.Task
+			var err error
+			if recur != "" {
+				pattern, perr := recurrence.ParsePattern(recur)
+				if perr != nil {
+					return nil, perr
+				}
+				task, err = todo.NewRecurringTask(title, "", pattern)
+			} else {
+				day, _ := time.ParseInLocation("2006-01-02", date, time.Local)
+				task, err = todo.NewTask(title, "", day)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return task, store.Create(task)
+		},
+		Complete: func(task *todo.Task) (*todo.Task, error) { return finish(task, task.Complete) },
+		Skip:     func(task *todo.Task) (*todo.Task, error) { return finish(task, task.Skip) },
+	})
+	return m, store
+}
+
+func press(m *model, keys ...string) {
+	for _, key := range keys {
+		m.handleKey(key)
+	}
+}
+
+func TestModel_Panes(t *testing.T) {
+	m, store := newTestModel(t)
+	today := storage.StartOfDay(time.Now())
+	for _, task := range []*todo.Task{
+		{Title: "Today 1", Date: today},
+		{Title: "Today 2", Date: today},
+		{Title: "Last week", Date: today.AddDate(0, 0, -7)},
+		{Title: "Yesterday", Date: today.AddDate(0, 0, -1)},
+		{Title: "Tomorrow", Date: today.AddDate(0, 0, 1)},
+	} {
+		store.Create(task)
+	}
+	if changed, err := m.reload(); err != nil || !changed {
+		t.Fatalf("reload = %v, %v", changed, err)
+	}
+
+	if got := m.selected().Title; got != "Today 1" {
+		t.Errorf("expected the cursor on the first task of today, got %q", got)
+	}
+	press(m, "j", "j", "j")
+	if got := m.selected().Title; got != "Today 2" {
+		t.Errorf("expected the cursor to stop at the last task, got %q", got)
+	}
+	press(m, "tab")
+	if got := m.selected().Title; got != "Yesterday" {
+		t.Errorf("expected the past most recent first, got %q", got)
+	}
+	press(m, "3")
+	if got := m.selected().Title; got != "Tomorrow" {
+		t.Errorf("expected the future pane, got %q", got)
+	}
+
+	// A change made elsewhere is picked up, keeping the cursor in place.
+	press(m, "1", "j")
+	store.Create(&todo.Task{Title: "Today 0", Date: today, CreatedAt: time.Now().Add(-time.Hour)})
+	if changed, _ := m.reload(); !changed {
+		t.Fatal("expected the new task to be picked up")
+	}
+	if got := m.selected().Title; got != "Today 2" || len(m.tasks[todayPane]) != 3 {
+		t.Errorf("expected the cursor to stay on Today 2, got %q", got)
+	}
+	if changed, _ := m.reload(); changed {
+		t.Error("expected no change without changes")
+	}
+}
+
+func TestModel_Actions(t *testing.T) {
+	m, store := newTestModel(t)
+	task, _ := todo.NewRecurringTask("Water plants", "", "weekly:monday")
+	task.Date = storage.StartOfDay(time.Now())
+	store.Create(task)
+	m.reload()
+
+	press(m, "space")
+	if m.failed || !strings.Contains(m.message, "next occurrence") {
+		t.Fatalf("unexpected message %q", m.message)
+	}
+	stored, _ := store.GetByID(task.ID)
+	if !stored.Completed {
+		t.Error("expected the task to be completed")
+	}
+	if len(m.tasks[futurePane]) != 1 || m.tasks[futurePane][0].Title != "Water plants" {
+		t.Errorf("expected the next occurrence in the future pane, got %v", m.tasks[futurePane])
+	}
+
+	press(m, "space")
+	if stored, _ := store.GetByID(task.ID); stored.Completed {
+		t.Error("expected space to mark a completed task incomplete")
+	}
+
+	press(m, "s")
+	all, _ := store.All()
+	if len(all) != 3 || len(m.tasks[todayPane]) != 0 {
+		t.Errorf("expected the skipped task gone and a second occurrence, got %d tasks", len(all))
+	}
+
+	press(m, "3", "e", "ctrl+u")
+	press(m, strings.Split("Water the plants", "")...)
+	press(m, "tab", "W", "enter")
+	edited, _ := store.GetByID(m.selected().ID)
+	if edited.Title != "Water the plants" || edited.Details != "W" || m.form != nil {
+		t.Errorf("expected the edit to be saved, got %+v", edited)
+	}
+}
+
+func TestModel_Add(t *testing.T) {
+	m, store := newTestModel(t)
+	m.reload()
+
+	press(m, "a", "enter")
+	if m.form == nil || !m.failed {
+		t.Fatal("expected an empty title to be refused, keeping the form open")
+	}
+	press(m, strings.Split("Pay rent", "")...)
+	press(m, "tab", "tab", "tab")
+	press(m, strings.Split("every moonday", "")...)
+	press(m, "enter")
+	if m.form == nil || !m.failed {
+		t.Fatal("expected an invalid recurrence to be refused")
+	}
+	for range len("moonday") {
+		press(m, "backspace")
+	}
+	press(m, strings.Split("monday", "")...)
+	press(m, "enter")
+	if m.form != nil || m.failed {
+		t.Fatalf("expected the task to be added, got %q", m.message)
+	}
+
+	all, _ := store.All()
+	if len(all) != 1 || all[0].RecurrencePattern != "weekly:monday" {
+		t.Fatalf("unexpected tasks %+v", all)
+	}
+	if got := m.selected(); got == nil || got.ID != all[0].ID {
+		t.Error("expected the cursor on the new task")
+	}
+}
+
+func TestModel_Render(t *testing.T) {
+	m, store := newTestModel(t)
+	today := storage.StartOfDay(time.Now())
+	store.Create(&todo.Task{Title: "A task with a rather long title\x1b[31m", Date: today})
+	store.Create(&todo.Task{Title: "Dentist", Date: today.Add(14*time.Hour + 30*time.Minute)})
+	m.reload()
+
+	for _, size := range [][2]int{{120, 30}, {50, 10}, {8, 3}} {
+		width, height := size[0], size[1]
+		lines := m.render(width, height)
+		if len(lines) != height {
+			t.Fatalf("%dx%d: expected %d lines, got %d", width, height, height, len(lines))
+		}
+		for i, line := range lines {
+			plain := stripStyles(line)
+			if n := utf8.RuneCountInString(plain); n != width {
+				t.Errorf("%dx%d: line %d is %d columns: %q", width, height, i, n, plain)
+			}
+			if strings.ContainsRune(plain, '\x1b') {
+				t.Errorf("%dx%d: line %d has a control character: %q", width, height, i, plain)
+			}
+		}
+	}
+
+	screen := stripStyles(strings.Join(m.render(120, 30), "\n"))
+	for _, want := range []string{"Today (2)", "Past (0)", "Future (0)", "14:30 Dentist", "q quit"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("expected the screen to show %q", want)
+		}
+	}
+	narrow := stripStyles(strings.Join(m.render(50, 10), "\n"))
+	if !strings.Contains(narrow, "2 Past") || strings.Contains(narrow, "Past (0)") {
+		t.Errorf("expected one pane on a narrow screen:\n%s", narrow)
+	}
+}
+
+// stripStyles removes the styles render adds around its text.
+func stripStyles(line string) string {
+	for _, style := range []string{styleBold, styleDim, styleReverse, styleRed, styleGreen, styleReset} {
+		line = strings.ReplaceAll(line, style, "")
+	}
+	return line
+}
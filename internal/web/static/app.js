@@ -0,0 +1,232 @@
+// The facienda web interface. Everything goes through the JSON API; the
+// only other endpoint is the recurrence check of the quick add form.
+"use strict";
+
+const api = document.body.dataset.api;
+const prefix = document.body.dataset.prefix;
+const views = ["today", "past", "future"];
+const weekdays = ["monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"];
+
+const form = document.getElementById("add");
+const hint = document.getElementById("recurrence-hint");
+const message = document.getElementById("message");
+const list = document.getElementById("tasks");
+const template = document.getElementById("task-template");
+
+let view = "today";
+
+// request calls the API and returns the decoded response, throwing the
+// API's error message if there is one.
+async function request(method, path, body) {
+  const options = { method, headers: {} };
+  if (body !== undefined) {
+    options.headers["Content-Type"] = "application/json";
+    options.body = JSON.stringify(body);
+  }
+  const response = await fetch(api + path, options);
+  if (response.status === 204) {
+    return null;
+  }
+  const data = await response.json().catch(() => null);
+  if (!response.ok) {
+    throw new Error((data && data.error) || response.status + " " + response.statusText);
+  }
+  return data;
+}
+
+function show(text, isError) {
+  message.textContent = text;
+  message.classList.toggle("error", Boolean(isError));
+  message.hidden = !text;
+}
+
+// parseDate reads a YYYY-MM-DD date as a local date.
+function parseDate(value) {
+  const [year, month, day] = value.split("-").map(Number);
+  return new Date(year, month - 1, day);
+}
+
+function formatDate(value) {
+  return parseDate(value).toLocaleDateString(undefined, {
+    weekday: "short", year: "numeric", month: "short", day: "numeric",
+  });
+}
+
+function ordinal(n) {
+  const rest = n % 100;
+  if (rest >= 11 && rest <= 13) return n + "th";
+  return n + ({ 1: "st", 2: "nd", 3: "rd" }[n % 10] || "th");
+}
+
+// describeRecurrence spells out a stored pattern as the list command does.
+function describeRecurrence(pattern) {
+  if (pattern === "monthly-last-weekend") return "Last weekend of each month";
+  const [kind, value] = pattern.split(":");
+  switch (kind) {
+    case "weekly":
+      return weekdays.includes(value) ? "Every " + value[0].toUpperCase() + value.slice(1) : pattern;
+    case "monthly":
+      return "Day " + value + " of each month";
+    case "monthly-nth-weekday":
+      return ordinal(Number(value)) + " weekday of each month";
+    default:
+      return pattern;
+  }
+}
+
+function renderTask(task) {
+  const node = template.content.firstElementChild.cloneNode(true);
+  node.classList.toggle("completed", task.completed);
+  node.querySelector(".title").textContent = task.title;
+  node.querySelector(".details").textContent = task.details;
+
+  const meta = [];
+  if (task.time) meta.push(task.time);
+  if (task.recurrence) meta.push("↻ " + describeRecurrence(task.recurrence));
+  if (task.assignee) meta.push("→ " + task.assignee);
+  node.querySelector(".meta").textContent = meta.join(" · ");
+
+  const toggle = node.querySelector(".toggle");
+  toggle.setAttribute("aria-label", task.completed ? "Mark as not done" : "Complete");
+  toggle.title = toggle.getAttribute("aria-label");
+  toggle.addEventListener("click", () => act(task, task.completed ? "incomplete" : "complete"));
+
+  const skip = node.querySelector(".skip");
+  if (task.completed) {
+    skip.remove();
+  } else {
+    skip.addEventListener("click", () => act(task, "skip"));
+  }
+  return node;
+}
+
+function render(tasks) {
+  list.replaceChildren();
+  if (tasks.length === 0) {
+    const empty = document.createElement("p");
+    empty.className = "empty";
+    empty.textContent = { today: "Nothing to do today.", past: "No past tasks.", future: "Nothing planned." }[view];
+    list.append(empty);
+    return;
+  }
+
+  // The past is shown most recent first, like a timeline.
+  if (view === "past") {
+    tasks.reverse();
+  }
+  let date = null;
+  for (const task of tasks) {
+    if (view !== "today" && task.date !== date) {
+      date = task.date;
+      const heading = document.createElement("h2");
+      heading.textContent = formatDate(date);
+      list.append(heading);
+    }
+    list.append(renderTask(task));
+  }
+}
+
+async function load() {
+  try {
+    render(await request("GET", "/tasks?when=" + view));
+  } catch (err) {
+    show("Couldn't load tasks: " + err.message, true);
+  }
+}
+
+async function act(task, action) {
+  try {
+    const result = await request("POST", "/tasks/" + encodeURIComponent(task.uid || task.id) + "/" + action);
+    if (result && result.next) {
+      show("Next occurrence on " + formatDate(result.next.date) + ".");
+    } else {
+      show("");
+    }
+  } catch (err) {
+    show(err.message, true);
+  }
+  load();
+}
+
+// checkRecurrence validates the Repeats field on the server, with the same
+// parser as the add command, and returns the pattern or null if the field
+// is empty. It throws if the phrase isn't understood.
+async function checkRecurrence() {
+  const text = form.elements.recurrence.value.trim();
+  form.elements.time.disabled = text !== "";
+  if (text === "") {
+    hint.textContent = "";
+    hint.classList.remove("error");
+    return null;
+  }
+
+  const response = await fetch(prefix + "recurrence?text=" + encodeURIComponent(text));
+  const check = await response.json();
+  if (text !== form.elements.recurrence.value.trim()) {
+    return check.pattern; // Typed on meanwhile; a later check shows the hint.
+  }
+  hint.classList.toggle("error", Boolean(check.error));
+  if (check.error) {
+    hint.textContent = check.error;
+    throw new Error(check.error);
+  }
+  hint.textContent = check.description + ", next on " + formatDate(check.next);
+  return check.pattern;
+}
+
+let checkTimer;
+form.elements.recurrence.addEventListener("input", () => {
+  clearTimeout(checkTimer);
+  checkTimer = setTimeout(() => checkRecurrence().catch(() => {}), 250);
+});
+
+form.addEventListener("submit", async (event) => {
+  event.preventDefault();
+  const input = { title: form.elements.title.value.trim() };
+  if (!input.title) return;
+
+  const submit = form.querySelector("button[type=submit]");
+  submit.disabled = true;
+  try {
+    const pattern = await checkRecurrence();
+    if (pattern) input.recurrence = pattern;
+    if (form.elements.date.value) input.date = form.elements.date.value;
+    if (form.elements.time.value && !pattern) input.time = form.elements.time.value;
+
+    const task = await request("POST", "/tasks", input);
+    form.reset();
+    form.elements.time.disabled = false;
+    hint.textContent = "";
+    show("Added “" + task.title + "” on " + formatDate(task.date) + ".");
+    load();
+  } catch (err) {
+    show(err.message, true);
+  } finally {
+    submit.disabled = false;
+    form.elements.title.focus();
+  }
+});
+
+function route() {
+  const name = location.hash.slice(1);
+  view = views.includes(name) ? name : "today";
+  for (const link of document.querySelectorAll("nav a")) {
+    link.classList.toggle("active", link.dataset.view === view);
+  }
+  load();
+}
+
+window.addEventListener("hashchange", () => {
+  show("");
+  route();
+});
+
+// Pick up changes made elsewhere, from the command line or another device.
+document.addEventListener("visibilitychange", () => {
+  if (document.visibilityState === "visible") load();
+});
+setInterval(() => {
+  if (document.visibilityState === "visible") load();
+}, 30000);
+
+route();
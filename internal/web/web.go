@@ -0,0 +1,108 @@
+// Package web serves a small task manager for the browser, for people who
+// would rather not use the command line. It shows today's, past and future
+// tasks, adds tasks (recurring ones too) and completes and skips them with
+// one click.
+//
+// The page is a static HTML, CSS and JavaScript app embedded in the binary,
+// so it works without a network connection. It reads and changes tasks
+// through the JSON API (see package api), which has to be served alongside
+// it. The handler adds one endpoint of its own:
+//
+//	GET /recurrence?text=every+monday   check a recurrence phrase
+//
+// which the quick add form uses to show what a phrase means as it is typed.
+package web
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/johnmirolha/facienda/internal/recurrence"
+)
+
+//go:embed static
+var static embed.FS
+
+var indexTemplate = template.Must(template.ParseFS(static, "static/index.html"))
+
+// contentSecurityPolicy keeps the page to its own assets: nothing is loaded
+// from elsewhere, and no inline script runs.
+const contentSecurityPolicy = "default-src 'self'; frame-ancestors 'none'"
+
+// Handler serves the web interface. It is safe for concurrent use.
+type Handler struct {
+	mux   *http.ServeMux
+	index []byte
+}
+
+// NewHandler serves the web interface under prefix, such as "/", talking to
+// the JSON API at apiPrefix, such as "/api/".
+func NewHandler(prefix, apiPrefix string) (*Handler, error) {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	var index bytes.Buffer
+	if err := indexTemplate.Execute(&index, struct{ Prefix, API string }{prefix, strings.TrimSuffix(apiPrefix, "/")}); err != nil {
+		return nil, err
+	}
+	assets, err := fs.Sub(static, "static")
+	if err != nil {
+		return nil, err
+	}
+
+	h := &Handler{mux: http.NewServeMux(), index: index.Bytes()}
+	h.mux.HandleFunc("GET "+prefix+"{$}", h.serveIndex)
+	h.mux.HandleFunc("GET "+prefix+"recurrence", checkRecurrence)
+	h.mux.Handle("GET "+prefix+"static/", http.StripPrefix(prefix+"static/", http.FileServerFS(assets)))
+	return h, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) serveIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(h.index)
+}
+
+// RecurrenceCheck is the response to a recurrence check: the pattern a
+// phrase stands for, or why it doesn't stand for one.
+type RecurrenceCheck struct {
+	Pattern     string `json:"pattern,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Next is the first occurrence from today, as YYYY-MM-DD.
+	Next  string `json:"next,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// checkRecurrence parses the text parameter with recurrence.ParsePattern,
+// as the add command's --recur flag does.
+func checkRecurrence(w http.ResponseWriter, r *http.Request) {
+	var check RecurrenceCheck
+	status := http.StatusOK
+
+	pattern, err := recurrence.ParsePattern(r.URL.Query().Get("text"))
+	if err == nil && pattern.IsRecurring() {
+		var next time.Time
+		next, err = pattern.NextOccurrence(time.Now().AddDate(0, 0, -1))
+		check = RecurrenceCheck{Pattern: string(pattern), Description: pattern.String(), Next: next.Format("2006-01-02")}
+	}
+	if err != nil {
+		check = RecurrenceCheck{Error: err.Error() + ` (try "every monday" or "3rd of each month")`}
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(check)
+}
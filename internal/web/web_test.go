@@ -0,0 +1,90 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, handler http.Handler, path string) (*http.Response, string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	resp := rec.Result()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestIndexAndAssets(t *testing.T) {
+	handler, err := NewHandler("/ui/", "/api/")
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+
+	resp, body := get(t, handler, "/ui/")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	for _, want := range []string{`data-api="/api"`, `src="/ui/static/app.js"`, `href="/ui/static/style.css"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected the page to contain %s", want)
+		}
+	}
+	if resp.Header.Get("Content-Security-Policy") == "" {
+		t.Error("expected a Content-Security-Policy header")
+	}
+
+	// Everything the page needs is embedded.
+	for _, asset := range []string{"app.js", "style.css"} {
+		if resp, _ := get(t, handler, "/ui/static/"+asset); resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", asset, resp.StatusCode)
+		}
+	}
+	if resp, _ := get(t, handler, "/ui/missing"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestCheckRecurrence(t *testing.T) {
+	handler, err := NewHandler("/", "/api/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text    string
+		status  int
+		pattern string
+	}{
+		{"every monday", http.StatusOK, "weekly:monday"},
+		{"3rd of each month", http.StatusOK, "monthly:3"},
+		{"", http.StatusOK, ""},
+		{"every moonday", http.StatusBadRequest, ""},
+		{"45th of each month", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			resp, body := get(t, handler, "/recurrence?text="+strings.ReplaceAll(tt.text, " ", "+"))
+			if resp.StatusCode != tt.status {
+				t.Fatalf("expected %d, got %d: %s", tt.status, resp.StatusCode, body)
+			}
+			var check RecurrenceCheck
+			if err := json.Unmarshal([]byte(body), &check); err != nil {
+				t.Fatalf("invalid JSON %q: %v", body, err)
+			}
+			if check.Pattern != tt.pattern {
+				t.Errorf("expected pattern %q, got %q", tt.pattern, check.Pattern)
+			}
+			if tt.pattern != "" && (check.Description == "" || check.Next == "") {
+				t.Errorf("expected a description and next date, got %+v", check)
+			}
+			if tt.status != http.StatusOK && check.Error == "" {
+				t.Error("expected an error message")
+			}
+		})
+	}
+}